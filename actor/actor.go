@@ -1,9 +1,16 @@
 package actor
 
 import (
+	"bytes"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"log"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"sync"
+	"sync/atomic"
 )
 
 // DefaultInCap is the default capacity of the In channel
@@ -11,15 +18,107 @@ const DefaultInCap = 100
 
 // Actor is the base type of all actors
 type Actor struct {
-	In     chan interface{}
-	StopCh chan struct{}
+	In       chan interface{}
+	StopCh   chan struct{}
+	sender   interface{}
+	goid     int64
+	loop     func()
+	init     func()
+	limiter  *limiter
+	started  int32
+	stopping int32
+	name     string
+	mailbox  func() Mailbox
+	dispatch func(interface{}) bool
+	task     *atomic.Value
+	view     *atomic.Value
+	closing  *closing
+	arena    *Arena
+	handle   func(interface{})
+	id       ID
+	seq      *sequencer
+	tenant   *tenant
+	replay   func(interface{}) error
+	replies  *replies
+	watchdog *Watchdog
 }
 
 // InCapacity returns the capacity that the In channel wil have
-func (ba Actor) InCapacity() int {
-	return DefaultInCap
+func (ba *Actor) InCapacity() int {
+	return inCap
 }
 
+// MaxAttempts returns the number of times a message that causes a panic
+// will be processed before it is quarantined. Zero means that panics are
+// not recovered
+func (ba *Actor) MaxAttempts() int {
+	return maxAttempts
+}
+
+// OnPanic is called with the dead letter of a message quarantined after
+// causing panics, by the actor's goroutine, before it processes the next
+// message. By default it does nothing
+func (ba *Actor) OnPanic(dl DeadLetter) {
+}
+
+// InlineCalls returns true if synchronous calls made from the actor's own
+// goroutine, or before the actor is started, should execute the method
+// directly instead of sending a message
+func (ba *Actor) InlineCalls() bool {
+	return false
+}
+
+// Sender returns the reference passed by the caller of the method being
+// executed, or nil if the caller didn't identify itself. It can be used to
+// send messages back to the caller
+func (ba *Actor) Sender() interface{} {
+	return ba.sender
+}
+
+// SetSender sets the sender of the message being processed. It is called by
+// the generated code before executing each method
+func (ba *Actor) SetSender(sender interface{}) {
+	ba.sender = sender
+}
+
+// Bind records the goroutine running the actor's main loop. It is called by
+// the generated code when the actor starts
+func (ba *Actor) Bind() {
+	atomic.StoreInt64(&ba.goid, goid())
+}
+
+// CheckSelfCall panics if it's called from the goroutine running the actor,
+// as a synchronous call from an actor to itself would block forever. It is
+// called by the generated code before sending synchronous requests and only
+// works when Debug is true
+func (ba *Actor) CheckSelfCall(method string) {
+	if Debug && atomic.LoadInt64(&ba.goid) == goid() {
+		panic(fmt.Sprintf("%s: synchronous call from the actor to itself would deadlock", method))
+	}
+}
+
+// CanInline returns true if a method can be executed directly from the
+// current goroutine: the actor is not started or the current goroutine is
+// the one running the actor
+func (ba *Actor) CanInline() bool {
+	id := atomic.LoadInt64(&ba.goid)
+	return id == 0 || id == goid()
+}
+
+// goid returns the id of the current goroutine
+func goid() int64 {
+	var buf [64]byte
+	n := runtime.Stack(buf[:], false)
+	// the stack trace begins with "goroutine <id> ["
+	fields := bytes.Fields(buf[:n])
+	id, _ := strconv.ParseInt(string(fields[1]), 10, 64)
+	return id
+}
+
+// Debug enables runtime checks that detect misuses of the actors, at a
+// performance cost
+var Debug = false
+
 // Log is the Logger used to write output messages
 var Log = log.New(ioutil.Discard, "goact: ", log.Ldate|log.Ltime)
 
@@ -27,3 +126,179 @@ var Log = log.New(ioutil.Discard, "goact: ", log.Ldate|log.Ltime)
 func SetLogOutput(w io.Writer) {
 	Log.SetOutput(w)
 }
+
+// DeadLetter contains a message that could not be processed by an actor
+type DeadLetter struct {
+	Actor    string
+	Msg      interface{}
+	Panic    interface{}
+	Stack    []byte
+	Attempts int
+}
+
+func (dl DeadLetter) Error() string {
+	if dl.Attempts == 0 {
+		return fmt.Sprintf("%s: message %T discarded: %v", dl.Actor, dl.Msg, dl.Panic)
+	}
+	return fmt.Sprintf("%s: message %T quarantined after %d attempts: %v", dl.Actor, dl.Msg, dl.Attempts, dl.Panic)
+}
+
+var deadLetterHandler atomic.Value
+
+// recentDeadLetters keeps the last dead letters, for monitoring tools
+var recentDeadLetters = struct {
+	sync.Mutex
+	list []DeadLetter
+}{}
+
+// maxRecentDeadLetters is the number of dead letters kept
+const maxRecentDeadLetters = 50
+
+// RecentDeadLetters returns the last quarantined messages, the most recent
+// first
+func RecentDeadLetters() []DeadLetter {
+	recentDeadLetters.Lock()
+	defer recentDeadLetters.Unlock()
+	result := make([]DeadLetter, len(recentDeadLetters.list))
+	for i, dl := range recentDeadLetters.list {
+		result[len(result)-1-i] = dl
+	}
+	return result
+}
+
+// SetDeadLetterHandler sets the function that will receive the quarantined
+// messages. By default, or with a nil handler, they are written to the
+// logger
+func SetDeadLetterHandler(h func(DeadLetter)) {
+	deadLetterHandler.Store(h)
+}
+
+// Request is implemented by the requests sent by the generated references
+type Request interface {
+	// Method returns the name of the requested method
+	Method() string
+	// Async returns true if the requested method is asynchronous
+	Async() bool
+}
+
+// Interceptor is called before an actor processes a message. It returns
+// false if the message should be dropped. It is meant for testing tools
+type Interceptor func(actor string, msg interface{}) bool
+
+var interceptor atomic.Value
+
+// SetInterceptor sets the function called before an actor processes a
+// message. A nil interceptor removes the current one
+func SetInterceptor(i Interceptor) {
+	interceptor.Store(i)
+}
+
+// ExitHook is called when the receive loop of an actor returns, after the
+// actor is stopped, with the name of the actor
+type ExitHook func(actor string)
+
+var exitHook atomic.Value
+
+// SetExitHook sets the function called when the receive loop of an actor
+// returns. A nil hook removes the current one
+func SetExitHook(h ExitHook) {
+	exitHook.Store(h)
+}
+
+// Try executes f recovering from any panic. It returns the value passed to
+// panic and the stack trace, or nil if f didn't panic
+func Try(f func()) (p interface{}, stack []byte) {
+	defer func() {
+		if p = recover(); p != nil {
+			stack = debug.Stack()
+		}
+	}()
+	f()
+	return nil, nil
+}
+
+// Process executes handle(msg) and counts it in the actor's metrics. If
+// handle panics the message is processed again right away, up to max times,
+// by the same actor: it isn't restarted, so the changes the handler made to
+// its state before panicking are kept. If it keeps failing the message is
+// quarantined: a DeadLetter is passed to the dead letter handler and
+// returned. If max is zero panics are not recovered
+func Process(name string, max int, msg interface{}, handle func(interface{})) *DeadLetter {
+	return process(name, metricsOf(name), max, msg, handle)
+}
+
+// ProcessUnmetered is Process for the actors generated without the metrics
+// feature: their messages aren't counted nor sampled
+func ProcessUnmetered(name string, max int, msg interface{}, handle func(interface{})) *DeadLetter {
+	return process(name, nil, max, msg, handle)
+}
+
+// process implements Process, counting the message in m if it isn't nil
+func process(name string, m *actorMetrics, max int, msg interface{}, handle func(interface{})) *DeadLetter {
+	if intercept, _ := interceptor.Load().(Interceptor); intercept != nil {
+		h := handle
+		handle = func(msg interface{}) {
+			if intercept(name, msg) {
+				h(msg)
+			}
+		}
+	}
+	if m != nil {
+		m.in.Add(1)
+		if rate := atomic.LoadInt64(&allocSampling); rate > 0 && m.in.Value()%rate == 0 {
+			handle = sampled(name, msg, m, rate, handle)
+		}
+	}
+	if max <= 0 {
+		handle(msg)
+		if m != nil {
+			m.out.Add(1)
+		}
+		return nil
+	}
+	for attempt := 1; ; attempt++ {
+		p, stack := Try(func() { handle(msg) })
+		if p == nil {
+			if m != nil {
+				m.out.Add(1)
+			}
+			return nil
+		}
+		if m != nil {
+			m.panics.Add(1)
+		}
+		Log.Printf("%s: message %T caused a panic (attempt %d): %v\n", name, msg, attempt, p)
+		if attempt >= max {
+			return quarantine(DeadLetter{Actor: name, Msg: msg, Panic: p, Stack: stack, Attempts: attempt})
+		}
+	}
+}
+
+// Discard turns a message that won't be processed into a dead letter, with
+// the reason as its panic and zero attempts, and passes it to the dead
+// letter handler. It is called by the generated code for the messages
+// waiting in the mailbox of a killed actor
+func Discard(name string, msg interface{}, reason error) *DeadLetter {
+	return quarantine(DeadLetter{Actor: name, Msg: msg, Panic: reason})
+}
+
+// quarantine keeps a dead letter in the recent ones and passes it to the
+// dead letter handler
+func quarantine(dl DeadLetter) *DeadLetter {
+	// requests of an arena are copied, as their slot is reused
+	if req, ok := dl.Msg.(interface{ Copy() interface{} }); ok {
+		dl.Msg = req.Copy()
+	}
+	recentDeadLetters.Lock()
+	recentDeadLetters.list = append(recentDeadLetters.list, dl)
+	if len(recentDeadLetters.list) > maxRecentDeadLetters {
+		recentDeadLetters.list = recentDeadLetters.list[1:]
+	}
+	recentDeadLetters.Unlock()
+	if h, _ := deadLetterHandler.Load().(func(DeadLetter)); h != nil {
+		h(dl)
+	} else {
+		Log.Printf("%s\n%s", dl, dl.Stack)
+	}
+	return &dl
+}
@@ -0,0 +1,51 @@
+package actor
+
+// testActor is an actor for the tests of the runtime. As the generated ones,
+// its main loop processes the requests of the runtime until it's stopped,
+// and the functions it receives stand for the requests of its methods
+type testActor struct {
+	Actor
+}
+
+// testStop is the stop request of a testActor
+type testStop struct{}
+
+func newTestActor() *testActor {
+	a := &testActor{}
+	a.In = make(chan interface{}, DefaultInCap)
+	a.StopCh = make(chan struct{})
+	a.SetLoop(a.receive)
+	return a
+}
+
+func (a *testActor) receive() {
+	a.Bind()
+	for msg := range a.In {
+		switch msg := msg.(type) {
+		case testStop:
+			close(a.StopCh)
+			RunPostStop("test", a)
+			return
+		case func():
+			msg()
+		default:
+			HandleSystem(a, msg)
+		}
+	}
+}
+
+func (a *testActor) Stop() {
+	if a.BeginStop() {
+		a.In <- testStop{}
+	}
+}
+
+// call executes f in the actor's goroutine and waits for it
+func (a *testActor) call(f func()) {
+	done := make(chan struct{})
+	a.In <- func() {
+		defer close(done)
+		f()
+	}
+	<-done
+}
@@ -0,0 +1,290 @@
+// Package admin provides a web page to monitor and operate the actors of a
+// System. It shows the actors and their dependencies, their mailbox depths,
+// the throughput of each kind of actor and the recent dead letters, and it
+// allows suspending, resuming and stopping the actors. It also serves a
+// self-test for deep health checks.
+//
+// The handler should only be exposed to operators:
+//
+//	http.Handle("/admin/", http.StripPrefix("/admin", admin.Handler(sys)))
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/carevaloc/goactors/actor"
+)
+
+// State is the state of the System returned by the api/state endpoint
+type State struct {
+	Actors      []actor.MemberInfo          `json:"actors"`
+	Metrics     map[string]map[string]int64 `json:"metrics"`
+	DeadLetters []DeadLetter                `json:"deadLetters"`
+	Tenants     []actor.TenantUsage         `json:"tenants"`
+}
+
+// DeadLetter is a quarantined message, as shown by the admin page
+type DeadLetter struct {
+	Actor    string `json:"actor"`
+	Msg      string `json:"msg"`
+	Panic    string `json:"panic"`
+	Attempts int    `json:"attempts"`
+}
+
+// Handler returns the handler of the admin page of the System. It serves:
+//
+//	GET  /             the admin page
+//	GET  /api/state    the State, encoded as JSON
+//	POST /api/suspend  suspends the actor named by the name parameter
+//	POST /api/resume   resumes the actor named by the name parameter
+//	POST /api/stop     stops the actor named by the name parameter
+//	POST /api/replay   replays a dead letter, see System.Replay, with the
+//	                   name, message, version and payload parameters
+//	GET  /selftest     the SelfTest handler
+func Handler(sys *actor.System) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, page)
+	})
+	mux.HandleFunc("/api/state", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(state(sys))
+	})
+	mux.HandleFunc("/api/suspend", operation(sys.Suspend))
+	mux.HandleFunc("/api/resume", operation(sys.Resume))
+	mux.HandleFunc("/api/stop", operation(sys.StopActor))
+	mux.HandleFunc("/api/replay", replay(sys))
+	mux.Handle("/selftest", SelfTest(sys))
+	return mux
+}
+
+// DefaultSelfTestTimeout is the time the actors have to process the canary
+// of a self-test, unless the request sets another with the timeout
+// parameter
+const DefaultSelfTestTimeout = 5 * time.Second
+
+// SelfTest returns the handler of the self-test of the System, meant for
+// deep health checks. It sends a canary message through each running actor
+// and responds with the results in the OpenMetrics text format: whether
+// each actor processed it and its round trip. The status is 503 if some
+// actor didn't process it in time. The timeout parameter, like 500ms, sets
+// how long they have
+func SelfTest(sys *actor.System) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		timeout := DefaultSelfTestTimeout
+		if t := r.FormValue("timeout"); t != "" {
+			d, err := time.ParseDuration(t)
+			if err != nil || d <= 0 {
+				http.Error(w, "invalid timeout "+t, http.StatusBadRequest)
+				return
+			}
+			timeout = d
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+		results := sys.SelfTest(ctx)
+
+		var b strings.Builder
+		status := http.StatusOK
+		b.WriteString("# TYPE goactors_selftest_up gauge\n")
+		b.WriteString("# HELP goactors_selftest_up Whether the actor processed the canary message.\n")
+		for _, res := range results {
+			up := 0
+			switch res.Status {
+			case actor.SelfTestOK:
+				up = 1
+			case actor.SelfTestFailed:
+				status = http.StatusServiceUnavailable
+			default:
+				continue
+			}
+			fmt.Fprintf(&b, "goactors_selftest_up{actor=%q} %d\n", res.Name, up)
+		}
+		b.WriteString("# TYPE goactors_selftest_latency_seconds gauge\n")
+		b.WriteString("# UNIT goactors_selftest_latency_seconds seconds\n")
+		b.WriteString("# HELP goactors_selftest_latency_seconds Round trip of the canary message.\n")
+		for _, res := range results {
+			if res.Status == actor.SelfTestOK {
+				fmt.Fprintf(&b, "goactors_selftest_latency_seconds{actor=%q} %g\n", res.Name, res.Latency.Seconds())
+			}
+		}
+		b.WriteString("# EOF\n")
+
+		w.Header().Set("Content-Type", "application/openmetrics-text; version=1.0.0; charset=utf-8")
+		w.WriteHeader(status)
+		fmt.Fprint(w, b.String())
+	})
+}
+
+// operation returns the handler of an operation on an actor. Operations
+// change the actors, so they are only accepted with POST
+func operation(op func(name string) error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if err := op(r.FormValue("name")); err != nil {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// replay returns the handler that replays a dead letter. Like the
+// operations, it's only accepted with POST
+func replay(sys *actor.System) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		version, err := strconv.Atoi(r.FormValue("version"))
+		if err != nil {
+			http.Error(w, "invalid version", http.StatusBadRequest)
+			return
+		}
+		if err := sys.Replay(r.FormValue("name"), r.FormValue("message"), version, []byte(r.FormValue("payload"))); err != nil {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// state collects the state of the System
+func state(sys *actor.System) State {
+	st := State{Actors: sys.Info(), Metrics: map[string]map[string]int64{}, DeadLetters: []DeadLetter{}, Tenants: sys.Tenants()}
+	if v := expvar.Get("goactors"); v != nil {
+		json.Unmarshal([]byte(v.String()), &st.Metrics)
+	}
+	for _, dl := range actor.RecentDeadLetters() {
+		st.DeadLetters = append(st.DeadLetters, DeadLetter{
+			Actor:    dl.Actor,
+			Msg:      fmt.Sprintf("%T", dl.Msg),
+			Panic:    fmt.Sprint(dl.Panic),
+			Attempts: dl.Attempts,
+		})
+	}
+	return st
+}
+
+// page is the admin page. It polls the state every two seconds and draws
+// the throughput of the last minutes
+const page = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>goactors admin</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { border-bottom: 1px solid #ddd; padding: 4px 12px; text-align: left; }
+.num { text-align: right; }
+#error { color: #b00; }
+</style>
+</head>
+<body>
+<h1>goactors admin</h1>
+<p id="error"></p>
+<h2>Actors</h2>
+<table id="actors"><thead><tr><th>Name</th><th>Type</th><th>Depends on</th><th>State</th><th class="num">Mailbox</th><th></th></tr></thead><tbody></tbody></table>
+<h2>Throughput</h2>
+<table id="metrics"><thead><tr><th>Actor</th><th class="num">In</th><th class="num">Out</th><th class="num">Panics</th><th class="num">Queue</th><th>Messages per second</th></tr></thead><tbody></tbody></table>
+<h2>Recent dead letters</h2>
+<table id="deadletters"><thead><tr><th>Actor</th><th>Message</th><th>Panic</th><th class="num">Attempts</th></tr></thead><tbody></tbody></table>
+<script>
+var rates = {}, last = {};
+
+function cell(row, text, cls) {
+	var td = row.insertCell();
+	td.textContent = text;
+	if (cls) td.className = cls;
+	return td;
+}
+
+function button(td, label, op, name) {
+	var b = document.createElement("button");
+	b.textContent = label;
+	b.onclick = function() {
+		fetch("api/" + op, {method: "POST", body: new URLSearchParams({name: name})})
+			.then(function(r) { return r.ok ? "" : r.text(); })
+			.then(function(err) { document.getElementById("error").textContent = err; refresh(); });
+	};
+	td.appendChild(b);
+}
+
+function graph(values) {
+	var max = Math.max.apply(null, values.concat([1])), w = 240, h = 30;
+	var points = values.map(function(v, i) { return (i * w / 59) + "," + (h - v * h / max); }).join(" ");
+	return '<svg width="' + w + '" height="' + h + '"><polyline fill="none" stroke="#36c" points="' + points + '"/></svg> ' + values[values.length - 1];
+}
+
+function refresh() {
+	fetch("api/state").then(function(r) { return r.json(); }).then(function(st) {
+		var body = document.querySelector("#actors tbody");
+		body.innerHTML = "";
+		st.actors.forEach(function(a) {
+			var row = body.insertRow();
+			cell(row, a.name);
+			cell(row, a.type);
+			cell(row, a.dependsOn.join(", "));
+			cell(row, a.suspended ? "suspended" : a.running ? "running" : "stopped");
+			cell(row, a.queue, "num");
+			var ops = cell(row, "");
+			if (a.running) {
+				button(ops, a.suspended ? "Resume" : "Suspend", a.suspended ? "resume" : "suspend", a.name);
+				button(ops, "Stop", "stop", a.name);
+			}
+		});
+
+		body = document.querySelector("#metrics tbody");
+		body.innerHTML = "";
+		Object.keys(st.metrics).sort().forEach(function(name) {
+			var m = st.metrics[name];
+			var h = rates[name] = rates[name] || [];
+			h.push(last[name] === undefined ? 0 : (m.out - last[name]) / 2);
+			if (h.length > 60) h.shift();
+			last[name] = m.out;
+			var row = body.insertRow();
+			cell(row, name);
+			cell(row, m.in, "num");
+			cell(row, m.out, "num");
+			cell(row, m.panics, "num");
+			cell(row, m.queue, "num");
+			row.insertCell().innerHTML = graph(h);
+		});
+
+		body = document.querySelector("#deadletters tbody");
+		body.innerHTML = "";
+		st.deadLetters.forEach(function(dl) {
+			var row = body.insertRow();
+			cell(row, dl.actor);
+			cell(row, dl.msg);
+			cell(row, dl.panic);
+			cell(row, dl.attempts, "num");
+		});
+	}).catch(function(err) { document.getElementById("error").textContent = err; });
+}
+
+refresh();
+setInterval(refresh, 2000);
+</script>
+</body>
+</html>
+`
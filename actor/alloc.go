@@ -0,0 +1,89 @@
+package actor
+
+import (
+	"context"
+	"fmt"
+	runtimemetrics "runtime/metrics"
+	"runtime/pprof"
+	"sync"
+	"sync/atomic"
+)
+
+// allocSampling is one in how many messages are sampled, 0 if sampling is
+// disabled
+var allocSampling int64
+
+// SetAllocSampling samples one in rate of the messages processed by the
+// actors, measuring the bytes allocated by their handlers. The estimated
+// total is added to the allocs metric of each actor. The handlers of the
+// sampled messages run with the pprof labels actor and method, so the CPU
+// profiles taken meanwhile attribute them to the actors. The allocations are
+// measured for the whole program, so they include the ones made by other
+// goroutines while the handler runs: the estimate is only accurate for the
+// actors that allocate the most. A rate of 0 disables sampling
+func SetAllocSampling(rate int) {
+	atomic.StoreInt64(&allocSampling, int64(rate))
+}
+
+// AllocReport is a sampled message whose handler allocated more than the
+// budget of its actor
+type AllocReport struct {
+	Actor  string
+	Method string
+	Bytes  uint64
+	Budget uint64
+}
+
+func (ar AllocReport) String() string {
+	return fmt.Sprintf("%s.%s: allocated %d bytes, over the budget of %d", ar.Actor, ar.Method, ar.Bytes, ar.Budget)
+}
+
+var allocBudgets sync.Map
+
+var allocBudgetHandler = func(ar AllocReport) {
+	Log.Println(ar)
+}
+
+// SetAllocBudget sets the bytes the handlers of the actors with the given
+// name can allocate per message. The sampled messages over the budget are
+// counted in the over_budget metric and passed to the budget handler. Zero
+// removes the budget
+func SetAllocBudget(actor string, bytes uint64) {
+	if bytes == 0 {
+		allocBudgets.Delete(actor)
+		return
+	}
+	allocBudgets.Store(actor, bytes)
+}
+
+// SetAllocBudgetHandler sets the function that will receive the messages
+// over the allocation budget. By default they are written to the logger
+func SetAllocBudgetHandler(h func(AllocReport)) {
+	allocBudgetHandler = h
+}
+
+// sampled returns the handler of a message that measures its allocations
+// and runs it with the pprof labels of the actor
+func sampled(name string, msg interface{}, m *actorMetrics, rate int64, handle func(interface{})) func(interface{}) {
+	method := fmt.Sprintf("%T", msg)
+	if req, ok := msg.(Request); ok {
+		method = req.Method()
+	}
+	return func(msg interface{}) {
+		sample := []runtimemetrics.Sample{{Name: "/gc/heap/allocs:bytes"}}
+		runtimemetrics.Read(sample)
+		before := sample[0].Value.Uint64()
+		defer func() {
+			runtimemetrics.Read(sample)
+			bytes := sample[0].Value.Uint64() - before
+			m.allocs.Add(int64(bytes) * rate)
+			if budget, ok := allocBudgets.Load(name); ok && bytes > budget.(uint64) {
+				m.overBudget.Add(1)
+				allocBudgetHandler(AllocReport{Actor: name, Method: method, Bytes: bytes, Budget: budget.(uint64)})
+			}
+		}()
+		pprof.Do(context.Background(), pprof.Labels("actor", name, "method", method), func(context.Context) {
+			handle(msg)
+		})
+	}
+}
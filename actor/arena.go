@@ -0,0 +1,82 @@
+package actor
+
+// Arena is a fixed number of preallocated requests of an actor, reused for
+// its messages instead of allocating one per message. A request slot is
+// acquired by the reference sending the message and released by the actor
+// once it's processed. When they are all in use the senders wait for one
+// to be released, so the arena bounds the memory used by the messages
+// waiting in the mailbox
+type Arena struct {
+	free  chan int
+	slots interface{}
+	m     *actorMetrics
+}
+
+// NewArena creates the arena of the actors with the given name. slots is
+// the slice of the requests, with capacity elements. It is called by the
+// generated New functions of the actors with an arena tag
+func NewArena(name string, slots interface{}, capacity int) *Arena {
+	a := &Arena{free: make(chan int, capacity), slots: slots, m: metricsOf(name)}
+	for i := 0; i < capacity; i++ {
+		a.free <- i
+	}
+	return a
+}
+
+// Acquire returns the index of a free slot, waiting for one to be released
+// if they are all in use
+func (a *Arena) Acquire() int {
+	var i int
+	select {
+	case i = <-a.free:
+	default:
+		a.m.arenaWaits.Add(1)
+		i = <-a.free
+	}
+	a.m.arenaUsed.Add(1)
+	return i
+}
+
+// Release returns a slot to the arena. Negative slots, used for the requests
+// allocated when the actor has no arena, are ignored
+func (a *Arena) Release(i int) {
+	if a == nil || i < 0 {
+		return
+	}
+	a.m.arenaUsed.Add(-1)
+	a.free <- i
+}
+
+// Slots returns the slice of the requests passed to NewArena
+func (a *Arena) Slots() interface{} {
+	return a.slots
+}
+
+// Cap returns the number of slots of the arena
+func (a *Arena) Cap() int {
+	return cap(a.free)
+}
+
+// Used returns the number of slots in use
+func (a *Arena) Used() int {
+	return cap(a.free) - len(a.free)
+}
+
+// SetArena sets the arena of the actor's requests and the function that
+// processes them, which is passed to Process instead of creating it for each
+// message. It is called by the generated New functions
+func (ba *Actor) SetArena(a *Arena, handle func(interface{})) {
+	ba.arena = a
+	ba.handle = handle
+}
+
+// Handler returns the function set with SetArena
+func (ba *Actor) Handler() func(interface{}) {
+	return ba.handle
+}
+
+// Arena returns the arena of the actor's requests, or nil if they are
+// allocated
+func (ba *Actor) Arena() *Arena {
+	return ba.arena
+}
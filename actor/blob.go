@@ -0,0 +1,119 @@
+package actor
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+)
+
+// BlobRef is the reference to a payload in the BlobStore. The requests carry
+// it in place of the parameters declared as blobs
+type BlobRef string
+
+// BlobStore stores the payloads of the blob parameters while their requests
+// wait in the mailboxes. A store shared by several processes keeps the
+// requests small when they are persisted or sent to other processes
+type BlobStore interface {
+	Put(data []byte) (BlobRef, error)
+	Get(ref BlobRef) ([]byte, error)
+	Delete(ref BlobRef) error
+}
+
+var (
+	blobsMu sync.RWMutex
+	blobs   BlobStore = NewMemoryBlobStore()
+)
+
+// SetBlobStore sets the store of the blob parameters. By default they are
+// kept in memory. It should be called before the actors are started
+func SetBlobStore(store BlobStore) {
+	blobsMu.Lock()
+	defer blobsMu.Unlock()
+	blobs = store
+}
+
+func blobStore() BlobStore {
+	blobsMu.RLock()
+	defer blobsMu.RUnlock()
+	return blobs
+}
+
+// PutBlob stores the payload of a blob parameter. It is called by the
+// generated references, and panics if the payload can't be stored
+func PutBlob(data []byte) BlobRef {
+	ref, err := blobStore().Put(data)
+	if err != nil {
+		panic(fmt.Errorf("storing blob: %v", err))
+	}
+	return ref
+}
+
+// GetBlob returns the payload of a blob parameter. It is called by the
+// generated actors before calling the method, and panics if the payload
+// can't be read, so the request is retried or becomes a dead letter
+func GetBlob(ref BlobRef) []byte {
+	data, err := blobStore().Get(ref)
+	if err != nil {
+		panic(fmt.Errorf("reading blob %s: %v", ref, err))
+	}
+	return data
+}
+
+// DeleteBlob deletes the payload of a blob parameter once its request is
+// processed: when the method returns, or when the request becomes a dead
+// letter or is discarded
+func DeleteBlob(ref BlobRef) {
+	if err := blobStore().Delete(ref); err != nil {
+		Log.Printf("Deleting blob %s: %v\n", ref, err)
+	}
+}
+
+// MemoryBlobStore is a BlobStore that keeps the payloads in memory
+type MemoryBlobStore struct {
+	mu   sync.Mutex
+	next uint64
+	data map[BlobRef][]byte
+}
+
+// NewMemoryBlobStore creates an empty MemoryBlobStore
+func NewMemoryBlobStore() *MemoryBlobStore {
+	return &MemoryBlobStore{data: make(map[BlobRef][]byte)}
+}
+
+// Put stores a copy of the payload and returns its reference, so the
+// caller can reuse the slice
+func (s *MemoryBlobStore) Put(data []byte) (BlobRef, error) {
+	data = append([]byte(nil), data...)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.next++
+	ref := BlobRef(strconv.FormatUint(s.next, 10))
+	s.data[ref] = data
+	return ref, nil
+}
+
+// Get returns the payload of the reference
+func (s *MemoryBlobStore) Get(ref BlobRef) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, ok := s.data[ref]
+	if !ok {
+		return nil, fmt.Errorf("unknown blob")
+	}
+	return data, nil
+}
+
+// Delete deletes the payload of the reference
+func (s *MemoryBlobStore) Delete(ref BlobRef) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.data, ref)
+	return nil
+}
+
+// Len returns the number of payloads in the store
+func (s *MemoryBlobStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.data)
+}
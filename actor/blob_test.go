@@ -0,0 +1,67 @@
+package actor
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestMemoryBlobStorePutCopies(t *testing.T) {
+	store := NewMemoryBlobStore()
+	data := []byte("payload")
+	ref, err := store.Put(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	copy(data, "changed")
+	got, err := store.Get(ref)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, []byte("payload")) {
+		t.Fatalf("got %q after changing the slice, want %q", got, "payload")
+	}
+}
+
+// blobRequest deletes its blob as the requests of the generated actors do
+type blobRequest struct {
+	content BlobRef
+}
+
+func (req blobRequest) deleteBlobs() {
+	DeleteBlob(req.content)
+}
+
+// TestDeadLetterBlobDeleted processes, as the generated code does, a request
+// with a blob that panics on every attempt, and one that is discarded. The
+// blob can be read on every attempt, and is deleted when the request
+// becomes a dead letter
+func TestDeadLetterBlobDeleted(t *testing.T) {
+	store := NewMemoryBlobStore()
+	SetBlobStore(store)
+	defer SetBlobStore(NewMemoryBlobStore())
+
+	dispatch := func(msg interface{}, dl *DeadLetter) {
+		if dl == nil {
+			t.Fatal("the request didn't become a dead letter")
+		}
+		if req, ok := msg.(interface{ deleteBlobs() }); ok {
+			req.deleteBlobs()
+		}
+	}
+	var attempts int
+	msg := blobRequest{PutBlob([]byte("payload"))}
+	dispatch(msg, ProcessUnmetered("test", 3, msg, func(msg interface{}) {
+		GetBlob(msg.(blobRequest).content)
+		attempts++
+		panic("broken")
+	}))
+	if attempts != 3 {
+		t.Fatalf("read the blob in %d attempts, want 3", attempts)
+	}
+	msg = blobRequest{PutBlob([]byte("payload"))}
+	dispatch(msg, Discard("test", msg, ErrKilled))
+
+	if n := store.Len(); n != 0 {
+		t.Fatalf("%d blobs left in the store", n)
+	}
+}
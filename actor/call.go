@@ -0,0 +1,47 @@
+package actor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrCallTimeout is wrapped by the errors of the synchronous calls made with
+// a timeout whose results don't arrive in time
+var ErrCallTimeout = errors.New("call timeout")
+
+// Await waits for the response of a synchronous call made with a context,
+// sent by the actor to out. It returns the error of ctx if it's done first,
+// or the error the actor sent instead of the response: a DeadLetter, or an
+// error wrapping ErrHandlerTimeout. out should be buffered, so the actor
+// doesn't block sending a response that nobody waits for. It is called by
+// the generated code
+func Await(ctx context.Context, out chan interface{}) (interface{}, error) {
+	select {
+	case resp := <-out:
+		if err, ok := resp.(error); ok {
+			return nil, err
+		}
+		return resp, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// StoppedCall returns the error of a synchronous call to method, made with
+// a context, when the actor is stopped. It wraps ErrStopped
+func StoppedCall(method string) error {
+	return fmt.Errorf("%s: %w", method, ErrStopped)
+}
+
+// CallTimeout returns the error of a synchronous call to method made with a
+// timeout: err, or an error wrapping ErrCallTimeout and the error of the
+// context if the results didn't arrive in time. It is called by the
+// generated code
+func CallTimeout(method string, timeout time.Duration, err error) error {
+	if !errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	return fmt.Errorf("%s: %w after %v: %w", method, ErrCallTimeout, timeout, err)
+}
@@ -0,0 +1,26 @@
+package actor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestCallTimeout(t *testing.T) {
+	for _, err := range []error{
+		context.DeadlineExceeded,
+		fmt.Errorf("waiting: %w", context.DeadlineExceeded),
+	} {
+		got := CallTimeout("test.call", time.Second, err)
+		if !errors.Is(got, ErrCallTimeout) || !errors.Is(got, context.DeadlineExceeded) {
+			t.Errorf("got %v for %v, want an error wrapping ErrCallTimeout and the deadline", got, err)
+		}
+	}
+	for _, err := range []error{nil, context.Canceled, ErrStopped} {
+		if got := CallTimeout("test.call", time.Second, err); got != err {
+			t.Errorf("got %v, want %v", got, err)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+// Package chaos injects faults in the actors to test how applications
+// handle them: delayed and dropped messages and panics. Faults are chosen
+// randomly from a seeded source, so a failing test can be repeated with
+// the same seed. The order in which the actors process their messages still
+// depends on the goroutine scheduling.
+package chaos
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/carevaloc/goactors/actor"
+)
+
+// Schedule configures the faults that will be injected. Probabilities are
+// in the range [0, 1]
+type Schedule struct {
+	// Seed of the random source
+	Seed int64
+	// Actors whose messages are affected. All the actors if empty
+	Actors []string
+	// Delay is the probability of delaying a message up to MaxDelay
+	Delay    float64
+	MaxDelay time.Duration
+	// Drop is the probability of dropping an asynchronous message
+	Drop float64
+	// Panic is the probability of panicking instead of processing a message
+	Panic float64
+}
+
+// Panic is the value passed to panic by the injected panics
+type Panic struct {
+	Actor string
+	Msg   interface{}
+}
+
+var (
+	mu   sync.Mutex
+	rnd  *rand.Rand
+	sch  Schedule
+	acts map[string]bool
+)
+
+// Start starts injecting faults in the actors according to s
+func Start(s Schedule) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	sch = s
+	rnd = rand.New(rand.NewSource(s.Seed))
+	acts = make(map[string]bool)
+	for _, name := range s.Actors {
+		acts[name] = true
+	}
+	actor.SetInterceptor(intercept)
+}
+
+// Stop stops injecting faults
+func Stop() {
+	actor.SetInterceptor(nil)
+}
+
+// intercept is the actor.Interceptor that injects the faults
+func intercept(name string, msg interface{}) bool {
+	mu.Lock()
+	if len(acts) > 0 && !acts[name] {
+		mu.Unlock()
+		return true
+	}
+	var delay time.Duration
+	if rnd.Float64() < sch.Delay && sch.MaxDelay > 0 {
+		delay = time.Duration(rnd.Int63n(int64(sch.MaxDelay)))
+	}
+	req, _ := msg.(actor.Request)
+	drop := rnd.Float64() < sch.Drop && req != nil && req.Async()
+	fail := rnd.Float64() < sch.Panic
+	mu.Unlock()
+
+	time.Sleep(delay)
+	if drop {
+		actor.Log.Printf("chaos: %s dropped %T\n", name, msg)
+		return false
+	}
+	if fail {
+		panic(Panic{Actor: name, Msg: msg})
+	}
+	return true
+}
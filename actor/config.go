@@ -0,0 +1,115 @@
+package actor
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Runtime defaults, used by actors that don't override them
+var (
+	inCap           = DefaultInCap
+	maxAttempts     = 0
+	responseTimeout time.Duration
+	gracePeriod     = DefaultGracePeriod
+)
+
+// ConfigFromEnv sets the runtime defaults from environment variables:
+//
+//	GOACTORS_IN_CAP            capacity of the In channel
+//	GOACTORS_MAX_ATTEMPTS      times a message causing a panic is processed before it is quarantined
+//	GOACTORS_RESPONSE_TIMEOUT  time the responses of the asynchronous methods wait to be polled (like 5s)
+//	GOACTORS_GRACE_PERIOD      time given to the actors to stop when a new System is shut down by a signal
+//	GOACTORS_ALLOC_SAMPLING    one in how many messages are sampled for the allocs metric, 0 disables it
+//	GOACTORS_LOG               log output: stdout, stderr or none
+//	GOACTORS_DEBUG             enables the Debug checks (true or false)
+//
+// Variables that are not set leave the corresponding default unchanged. All
+// the variables are read before any default is changed, so if one of them
+// is invalid none is changed
+func ConfigFromEnv() error {
+	var set []func()
+	for _, v := range []struct {
+		name string
+		to   *int
+	}{
+		{"GOACTORS_IN_CAP", &inCap},
+		{"GOACTORS_MAX_ATTEMPTS", &maxAttempts},
+	} {
+		n, ok, err := intFromEnv(v.name)
+		if err != nil {
+			return err
+		}
+		if ok {
+			to := v.to
+			set = append(set, func() { *to = n })
+		}
+	}
+	if rate, ok, err := intFromEnv("GOACTORS_ALLOC_SAMPLING"); err != nil {
+		return err
+	} else if ok {
+		set = append(set, func() { SetAllocSampling(rate) })
+	}
+
+	for _, v := range []struct {
+		name string
+		to   *time.Duration
+	}{
+		{"GOACTORS_RESPONSE_TIMEOUT", &responseTimeout},
+		{"GOACTORS_GRACE_PERIOD", &gracePeriod},
+	} {
+		if str, ok := os.LookupEnv(v.name); ok {
+			d, err := time.ParseDuration(str)
+			if err != nil || d < 0 {
+				return fmt.Errorf("%s: invalid value %s", v.name, str)
+			}
+			to := v.to
+			set = append(set, func() { *to = d })
+		}
+	}
+
+	if str, ok := os.LookupEnv("GOACTORS_DEBUG"); ok {
+		debug, err := strconv.ParseBool(str)
+		if err != nil {
+			return fmt.Errorf("GOACTORS_DEBUG: invalid value %s", str)
+		}
+		set = append(set, func() { Debug = debug })
+	}
+
+	if str, ok := os.LookupEnv("GOACTORS_LOG"); ok {
+		var w io.Writer
+		switch str {
+		case "stdout":
+			w = os.Stdout
+		case "stderr":
+			w = os.Stderr
+		case "none":
+			w = ioutil.Discard
+		default:
+			return fmt.Errorf("GOACTORS_LOG: unknown log output %s", str)
+		}
+		set = append(set, func() { SetLogOutput(w) })
+	}
+
+	for _, f := range set {
+		f()
+	}
+	return nil
+}
+
+// intFromEnv reads a non negative integer from the environment variable
+// name. It returns false if the variable is not set
+func intFromEnv(name string) (int, bool, error) {
+	str, ok := os.LookupEnv(name)
+	if !ok {
+		return 0, false, nil
+	}
+	n, err := strconv.Atoi(str)
+	if err != nil || n < 0 {
+		return 0, false, fmt.Errorf("%s: invalid value %s", name, str)
+	}
+	return n, true, nil
+}
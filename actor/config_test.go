@@ -0,0 +1,32 @@
+package actor
+
+import (
+	"testing"
+	"time"
+)
+
+func TestConfigFromEnv(t *testing.T) {
+	defer func() {
+		inCap, maxAttempts, gracePeriod = DefaultInCap, 0, DefaultGracePeriod
+	}()
+	t.Setenv("GOACTORS_IN_CAP", "64")
+	t.Setenv("GOACTORS_GRACE_PERIOD", "30s")
+	t.Setenv("GOACTORS_MAX_ATTEMPTS", "-1")
+	if err := ConfigFromEnv(); err == nil {
+		t.Fatal("no error for an invalid GOACTORS_MAX_ATTEMPTS")
+	}
+	if inCap != DefaultInCap || gracePeriod != DefaultGracePeriod {
+		t.Fatalf("invalid configuration applied: in cap %d, grace period %v", inCap, gracePeriod)
+	}
+
+	t.Setenv("GOACTORS_MAX_ATTEMPTS", "3")
+	if err := ConfigFromEnv(); err != nil {
+		t.Fatal(err)
+	}
+	if inCap != 64 || maxAttempts != 3 {
+		t.Fatalf("got in cap %d and max attempts %d, want 64 and 3", inCap, maxAttempts)
+	}
+	if grace := NewSystem().grace; grace != 30*time.Second {
+		t.Fatalf("got grace period %v, want 30s", grace)
+	}
+}
@@ -0,0 +1,154 @@
+package actor
+
+import (
+	"fmt"
+	"sort"
+)
+
+// MemberInfo describes an actor of a System
+type MemberInfo struct {
+	Name      string   `json:"name"`
+	Type      string   `json:"type"`
+	Tenant    string   `json:"tenant,omitempty"`
+	DependsOn []string `json:"dependsOn"`
+	Running   bool     `json:"running"`
+	Suspended bool     `json:"suspended"`
+	Queue     int      `json:"queue"`
+}
+
+// Info describes the actors of the system, in the order they were added
+func (s *System) Info() []MemberInfo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var info []MemberInfo
+	for _, m := range s.members {
+		b := m.inst.base()
+		mi := MemberInfo{
+			Name:      m.name,
+			Type:      fmt.Sprintf("%T", m.inst),
+			Tenant:    m.tenant,
+			DependsOn: []string{},
+			Running:   m.started && !stopped(b),
+			Suspended: m.resume != nil,
+			Queue:     len(b.In),
+		}
+		for _, dep := range m.deps {
+			if d, ok := s.index[dep]; ok {
+				mi.DependsOn = append(mi.DependsOn, d.name)
+			}
+		}
+		sort.Strings(mi.DependsOn)
+		info = append(info, mi)
+	}
+	return info
+}
+
+// suspendRequest blocks the actor until resume is closed
+type suspendRequest struct {
+	resume chan struct{}
+}
+
+func (req suspendRequest) execute(inst Instance) {
+	<-req.resume
+}
+
+// Suspend stops the processing of messages by the actor with the given
+// name, after the ones already received, until it's resumed. Messages sent
+// while the actor is suspended are queued
+func (s *System) Suspend(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m, err := s.running(name)
+	if err != nil {
+		return err
+	}
+	if m.resume != nil {
+		return fmt.Errorf("%s: already suspended", name)
+	}
+
+	req := suspendRequest{resume: make(chan struct{})}
+	select {
+	case m.inst.base().In <- req:
+		m.inst.base().Notify()
+	default:
+		return fmt.Errorf("%s: mailbox full", name)
+	}
+	m.resume = req.resume
+	return nil
+}
+
+// Resume resumes a suspended actor
+func (s *System) Resume(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m := s.member(name)
+	if m == nil || m.resume == nil {
+		return fmt.Errorf("%s: not suspended", name)
+	}
+	close(m.resume)
+	m.resume = nil
+	return nil
+}
+
+// StopActor stops the actor with the given name and waits until it stops.
+// It fails if other running actors depend on it
+func (s *System) StopActor(name string) error {
+	s.mu.Lock()
+	m, err := s.running(name)
+	if err == nil {
+		for _, other := range s.members {
+			for _, dep := range other.deps {
+				if dep == m.inst && other.started && !stopped(other.inst.base()) {
+					err = fmt.Errorf("%s: %s depends on it", name, other.name)
+				}
+			}
+		}
+	}
+	if err == nil && m.resume != nil {
+		close(m.resume)
+		m.resume = nil
+	}
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	m.inst.Stop()
+	<-m.inst.base().StopCh
+	return nil
+}
+
+// member returns the member with the given name, or nil if there is none
+func (s *System) member(name string) *member {
+	for _, m := range s.members {
+		if m.name == name {
+			return m
+		}
+	}
+	return nil
+}
+
+// running returns the member with the given name if it's running
+func (s *System) running(name string) (*member, error) {
+	m := s.member(name)
+	if m == nil {
+		return nil, fmt.Errorf("%s: not part of the system", name)
+	}
+	if !m.started || stopped(m.inst.base()) {
+		return nil, fmt.Errorf("%s: not running", name)
+	}
+	return m, nil
+}
+
+// resumeAll resumes the suspended actors, so they can be stopped
+func (s *System) resumeAll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, m := range s.members {
+		if m.resume != nil {
+			close(m.resume)
+			m.resume = nil
+		}
+	}
+}
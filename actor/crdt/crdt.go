@@ -0,0 +1,315 @@
+// Package crdt provides replicated data types whose copies, held by
+// different actors, converge to the same value without a coordinator: each
+// actor updates its copy, sends it to the others from time to time, and
+// merges the copies it receives. Merging is commutative, associative and
+// idempotent, so the copies can be sent in any order, more than once.
+//
+// The types are encoded as JSON, so they can be parameters of the methods
+// of the actors, snapshots of System.Dump or payloads of the outbox. The
+// copies sent to other actors must be made with Clone, as the types contain
+// maps. Each replica is identified by a node name, unique among the actors
+// sharing the value.
+package crdt
+
+import (
+	"sort"
+	"strconv"
+	"time"
+)
+
+// GCounter is a counter that only grows
+type GCounter struct {
+	Counts map[string]uint64 `json:"counts"`
+}
+
+// NewGCounter returns a counter at zero
+func NewGCounter() *GCounter {
+	return &GCounter{Counts: make(map[string]uint64)}
+}
+
+// Inc adds n to the count of node
+func (c *GCounter) Inc(node string, n uint64) {
+	if c.Counts == nil {
+		c.Counts = make(map[string]uint64)
+	}
+	c.Counts[node] += n
+}
+
+// Value returns the count of all the nodes
+func (c *GCounter) Value() uint64 {
+	var v uint64
+	for _, n := range c.Counts {
+		v += n
+	}
+	return v
+}
+
+// Merge merges other into the counter
+func (c *GCounter) Merge(other *GCounter) {
+	if c.Counts == nil {
+		c.Counts = make(map[string]uint64)
+	}
+	for node, n := range other.Counts {
+		if n > c.Counts[node] {
+			c.Counts[node] = n
+		}
+	}
+}
+
+// Clone returns a copy of the counter
+func (c *GCounter) Clone() *GCounter {
+	clone := NewGCounter()
+	clone.Merge(c)
+	return clone
+}
+
+// PNCounter is a counter that can be increased and decreased
+type PNCounter struct {
+	P *GCounter `json:"p"`
+	N *GCounter `json:"n"`
+}
+
+// NewPNCounter returns a counter at zero
+func NewPNCounter() *PNCounter {
+	return &PNCounter{P: NewGCounter(), N: NewGCounter()}
+}
+
+// init creates the counters of a counter that wasn't created with
+// NewPNCounter
+func (c *PNCounter) init() {
+	if c.P == nil {
+		c.P = NewGCounter()
+	}
+	if c.N == nil {
+		c.N = NewGCounter()
+	}
+}
+
+// Add adds delta to the count of node
+func (c *PNCounter) Add(node string, delta int64) {
+	c.init()
+	if delta >= 0 {
+		c.P.Inc(node, uint64(delta))
+	} else {
+		c.N.Inc(node, uint64(-delta))
+	}
+}
+
+// Value returns the count of all the nodes
+func (c *PNCounter) Value() int64 {
+	var p, n uint64
+	if c.P != nil {
+		p = c.P.Value()
+	}
+	if c.N != nil {
+		n = c.N.Value()
+	}
+	return int64(p) - int64(n)
+}
+
+// Merge merges other into the counter
+func (c *PNCounter) Merge(other *PNCounter) {
+	c.init()
+	if other.P != nil {
+		c.P.Merge(other.P)
+	}
+	if other.N != nil {
+		c.N.Merge(other.N)
+	}
+}
+
+// Clone returns a copy of the counter
+func (c *PNCounter) Clone() *PNCounter {
+	clone := NewPNCounter()
+	clone.Merge(c)
+	return clone
+}
+
+// ORSet is a set of strings where an element added and removed at the same
+// time by different nodes stays in the set. Each addition is tagged, and a
+// removal only removes the tags its node has seen. The tags removed are
+// kept, so the set grows with the removals
+type ORSet struct {
+	// Adds are the tags of the additions of each element
+	Adds map[string]map[string]bool `json:"adds"`
+	// Removed are the tags removed
+	Removed map[string]bool `json:"removed"`
+	// Clock is the number of additions of each node, used for the tags
+	Clock map[string]uint64 `json:"clock"`
+}
+
+// NewORSet returns an empty set
+func NewORSet() *ORSet {
+	return &ORSet{Adds: make(map[string]map[string]bool), Removed: make(map[string]bool), Clock: make(map[string]uint64)}
+}
+
+// init creates the maps of a set that wasn't created with NewORSet
+func (s *ORSet) init() {
+	if s.Adds == nil {
+		s.Adds = make(map[string]map[string]bool)
+	}
+	if s.Removed == nil {
+		s.Removed = make(map[string]bool)
+	}
+	if s.Clock == nil {
+		s.Clock = make(map[string]uint64)
+	}
+}
+
+// Add adds elem to the set from node
+func (s *ORSet) Add(node, elem string) {
+	s.init()
+	s.Clock[node]++
+	tags := s.Adds[elem]
+	if tags == nil {
+		tags = make(map[string]bool)
+		s.Adds[elem] = tags
+	}
+	tags[node+"#"+strconv.FormatUint(s.Clock[node], 10)] = true
+}
+
+// Remove removes elem from the set, if it's in it
+func (s *ORSet) Remove(elem string) {
+	s.init()
+	for tag := range s.Adds[elem] {
+		s.Removed[tag] = true
+	}
+}
+
+// Contains returns true if elem is in the set
+func (s *ORSet) Contains(elem string) bool {
+	for tag := range s.Adds[elem] {
+		if !s.Removed[tag] {
+			return true
+		}
+	}
+	return false
+}
+
+// Elements returns the elements of the set, sorted
+func (s *ORSet) Elements() []string {
+	var elems []string
+	for elem := range s.Adds {
+		if s.Contains(elem) {
+			elems = append(elems, elem)
+		}
+	}
+	sort.Strings(elems)
+	return elems
+}
+
+// Merge merges other into the set
+func (s *ORSet) Merge(other *ORSet) {
+	s.init()
+	for elem, tags := range other.Adds {
+		mine := s.Adds[elem]
+		if mine == nil {
+			mine = make(map[string]bool)
+			s.Adds[elem] = mine
+		}
+		for tag := range tags {
+			mine[tag] = true
+		}
+	}
+	for tag := range other.Removed {
+		s.Removed[tag] = true
+	}
+	for node, n := range other.Clock {
+		if n > s.Clock[node] {
+			s.Clock[node] = n
+		}
+	}
+}
+
+// Clone returns a copy of the set
+func (s *ORSet) Clone() *ORSet {
+	clone := NewORSet()
+	clone.Merge(s)
+	return clone
+}
+
+// LWWEntry is a value of a LWWMap, with the time and the node of the write
+type LWWEntry struct {
+	Value   interface{} `json:"value"`
+	Time    int64       `json:"time"`
+	Node    string      `json:"node"`
+	Deleted bool        `json:"deleted,omitempty"`
+}
+
+// after returns true if the entry was written after other. The writes at
+// the same time are ordered by node
+func (e LWWEntry) after(other LWWEntry) bool {
+	if e.Time != other.Time {
+		return e.Time > other.Time
+	}
+	return e.Node > other.Node
+}
+
+// LWWMap is a map of strings to values where the last write of a key wins.
+// The writes are ordered by the time given by the nodes, so their clocks
+// should be synchronized. The values should be immutable, as Clone doesn't
+// copy them, and encodable as JSON. The deleted keys are kept
+type LWWMap struct {
+	Entries map[string]LWWEntry `json:"entries"`
+}
+
+// NewLWWMap returns an empty map
+func NewLWWMap() *LWWMap {
+	return &LWWMap{Entries: make(map[string]LWWEntry)}
+}
+
+// Set sets the value of key from node, at the time at. It's ignored if the
+// key was written later
+func (m *LWWMap) Set(node, key string, value interface{}, at time.Time) {
+	m.write(key, LWWEntry{Value: value, Time: at.UnixNano(), Node: node})
+}
+
+// Delete deletes key from node, at the time at. It's ignored if the key
+// was written later
+func (m *LWWMap) Delete(node, key string, at time.Time) {
+	m.write(key, LWWEntry{Time: at.UnixNano(), Node: node, Deleted: true})
+}
+
+func (m *LWWMap) write(key string, e LWWEntry) {
+	if m.Entries == nil {
+		m.Entries = make(map[string]LWWEntry)
+	}
+	if old, ok := m.Entries[key]; !ok || e.after(old) {
+		m.Entries[key] = e
+	}
+}
+
+// Get returns the value of key, and false if it's not in the map
+func (m *LWWMap) Get(key string) (interface{}, bool) {
+	e, ok := m.Entries[key]
+	if !ok || e.Deleted {
+		return nil, false
+	}
+	return e.Value, true
+}
+
+// Keys returns the keys in the map, sorted
+func (m *LWWMap) Keys() []string {
+	var keys []string
+	for key, e := range m.Entries {
+		if !e.Deleted {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Merge merges other into the map
+func (m *LWWMap) Merge(other *LWWMap) {
+	for key, e := range other.Entries {
+		m.write(key, e)
+	}
+}
+
+// Clone returns a copy of the map
+func (m *LWWMap) Clone() *LWWMap {
+	clone := NewLWWMap()
+	clone.Merge(m)
+	return clone
+}
@@ -0,0 +1,40 @@
+package crdt
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestPNCounterZeroValue(t *testing.T) {
+	var c PNCounter
+	if v := c.Value(); v != 0 {
+		t.Fatalf("Value of the zero value = %d, want 0", v)
+	}
+	if v := c.Clone().Value(); v != 0 {
+		t.Fatalf("Value of the clone of the zero value = %d, want 0", v)
+	}
+	c.Add("a", 3)
+	c.Add("b", -1)
+
+	var other PNCounter
+	other.Merge(&c)
+	other.Merge(&PNCounter{})
+	if v := other.Value(); v != 2 {
+		t.Fatalf("Value after merging = %d, want 2", v)
+	}
+}
+
+func TestPNCounterMissingJSONFields(t *testing.T) {
+	for _, data := range []string{`{}`, `{"p":{"counts":{"a":5}}}`, `{"n":{"counts":{"a":1}}}`} {
+		var c PNCounter
+		if err := json.Unmarshal([]byte(data), &c); err != nil {
+			t.Fatal(err)
+		}
+		want := c.Value() + 1
+		c.Add("b", 1)
+		c.Merge(NewPNCounter())
+		if v := c.Value(); v != want {
+			t.Errorf("%s: Value = %d, want %d", data, v, want)
+		}
+	}
+}
@@ -0,0 +1,16 @@
+package actor
+
+import "testing"
+
+func TestSetDeadLetterHandler(t *testing.T) {
+	got := make(chan DeadLetter, 1)
+	SetDeadLetterHandler(func(dl DeadLetter) { got <- dl })
+	defer SetDeadLetterHandler(nil)
+
+	ProcessUnmetered("test", 2, "poison", func(interface{}) { panic("broken") })
+	if dl := <-got; dl.Msg != "poison" || dl.Attempts != 2 {
+		t.Fatalf("got dead letter %v, want poison after 2 attempts", dl)
+	}
+	SetDeadLetterHandler(nil)
+	Discard("test", "discarded", ErrKilled)
+}
@@ -0,0 +1,239 @@
+// Package deadlog keeps the dead letters of the actors in a compact binary
+// log, so they can be analyzed offline with actorc deadletters and replayed
+// into a running System.
+//
+// Each entry records the actor, the name and version of the message in the
+// registry, the panic, the attempts and the stack, and the message encoded
+// as JSON by its generated MarshalJSON method. The messages of the actors
+// without the json feature are recorded without payload, so they can't be
+// replayed.
+//
+// The log is a header followed by the entries, each one prefixed with its
+// length, with the integers encoded as varints.
+package deadlog
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/carevaloc/goactors/actor"
+)
+
+// header starts the logs, and identifies the version of the format
+const header = "GADL\x01"
+
+// maxEntry is the size of the largest entry read
+const maxEntry = 64 << 20
+
+// Entry is a dead letter in the log
+type Entry struct {
+	Time  time.Time `json:"time"`
+	Actor string    `json:"actor"`
+	// Message is the name of the message in the registry or, if it's not
+	// registered, its Go type
+	Message  string          `json:"message"`
+	Version  int             `json:"version"`
+	Attempts int             `json:"attempts"`
+	Panic    string          `json:"panic"`
+	Payload  json.RawMessage `json:"payload,omitempty"`
+	Stack    string          `json:"stack,omitempty"`
+}
+
+// EntryOf returns the entry of a dead letter, at the current time
+func EntryOf(dl actor.DeadLetter) Entry {
+	e := Entry{
+		Time:     time.Now(),
+		Actor:    dl.Actor,
+		Message:  fmt.Sprintf("%T", dl.Msg),
+		Attempts: dl.Attempts,
+		Panic:    fmt.Sprint(dl.Panic),
+		Stack:    string(dl.Stack),
+	}
+	if mt, ok := actor.MessageTypeOf(dl.Msg); ok {
+		e.Message, e.Version = mt.Name, mt.Version
+	}
+	if m, ok := dl.Msg.(json.Marshaler); ok {
+		if payload, err := m.MarshalJSON(); err == nil {
+			e.Payload = payload
+		}
+	}
+	return e
+}
+
+// Writer appends entries to a log
+type Writer struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewWriter returns a Writer of the log written to w, writing its header
+func NewWriter(w io.Writer) (*Writer, error) {
+	if _, err := io.WriteString(w, header); err != nil {
+		return nil, err
+	}
+	return &Writer{w: w}, nil
+}
+
+// OpenFile returns a Writer that appends the entries to the log in the
+// file at path, creating it if it doesn't exist
+func OpenFile(path string) (*Writer, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if info.Size() == 0 {
+		return NewWriter(f)
+	}
+	got := make([]byte, len(header))
+	if _, err := f.ReadAt(got, 0); err != nil || string(got) != header {
+		f.Close()
+		return nil, fmt.Errorf("%s: not a dead letter log", path)
+	}
+	return &Writer{w: f}, nil
+}
+
+// Write appends an entry to the log, with a single write
+func (w *Writer) Write(e Entry) error {
+	var body []byte
+	body = binary.AppendVarint(body, e.Time.UnixNano())
+	body = appendString(body, e.Actor)
+	body = appendString(body, e.Message)
+	body = binary.AppendUvarint(body, uint64(e.Version))
+	body = binary.AppendUvarint(body, uint64(e.Attempts))
+	body = appendString(body, e.Panic)
+	body = appendString(body, string(e.Payload))
+	body = appendString(body, e.Stack)
+
+	record := binary.AppendUvarint(nil, uint64(len(body)))
+	record = append(record, body...)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	_, err := w.w.Write(record)
+	return err
+}
+
+// Handler returns a dead letter handler, for actor.SetDeadLetterHandler,
+// that appends the dead letters to the log and then passes them to next,
+// if it's not nil. The errors writing the log are written to actor.Log
+func (w *Writer) Handler(next func(actor.DeadLetter)) func(actor.DeadLetter) {
+	return func(dl actor.DeadLetter) {
+		if err := w.Write(EntryOf(dl)); err != nil {
+			actor.Log.Printf("dead letter log: %v\n", err)
+		}
+		if next != nil {
+			next(dl)
+		}
+	}
+}
+
+// Close closes the underlying writer, if it's an io.Closer
+func (w *Writer) Close() error {
+	if c, ok := w.w.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+func appendString(b []byte, s string) []byte {
+	b = binary.AppendUvarint(b, uint64(len(s)))
+	return append(b, s...)
+}
+
+// Reader reads the entries of a log
+type Reader struct {
+	r *bufio.Reader
+}
+
+// NewReader returns a Reader of the log read from r, checking its header
+func NewReader(r io.Reader) (*Reader, error) {
+	br := bufio.NewReader(r)
+	got := make([]byte, len(header))
+	if _, err := io.ReadFull(br, got); err != nil || string(got) != header {
+		return nil, errors.New("not a dead letter log")
+	}
+	return &Reader{r: br}, nil
+}
+
+// errCorrupt is returned for the entries that can't be decoded
+var errCorrupt = errors.New("corrupt dead letter log")
+
+// Next returns the next entry of the log, or io.EOF at its end
+func (r *Reader) Next() (Entry, error) {
+	n, err := binary.ReadUvarint(r.r)
+	if err == io.EOF {
+		return Entry{}, io.EOF
+	}
+	if err != nil || n > maxEntry {
+		return Entry{}, errCorrupt
+	}
+	body := make([]byte, n)
+	if _, err := io.ReadFull(r.r, body); err != nil {
+		return Entry{}, errCorrupt
+	}
+
+	d := decoder{b: body}
+	nanos := d.varint()
+	e := Entry{Time: time.Unix(0, nanos)}
+	e.Actor = d.string()
+	e.Message = d.string()
+	e.Version = int(d.uvarint())
+	e.Attempts = int(d.uvarint())
+	e.Panic = d.string()
+	if payload := d.string(); payload != "" {
+		e.Payload = json.RawMessage(payload)
+	}
+	e.Stack = d.string()
+	if d.err {
+		return Entry{}, errCorrupt
+	}
+	return e, nil
+}
+
+// decoder reads the fields of an entry, remembering if some was truncated
+type decoder struct {
+	b   []byte
+	err bool
+}
+
+func (d *decoder) varint() int64 {
+	v, n := binary.Varint(d.b)
+	if n <= 0 {
+		d.err, d.b = true, nil
+		return 0
+	}
+	d.b = d.b[n:]
+	return v
+}
+
+func (d *decoder) uvarint() uint64 {
+	v, n := binary.Uvarint(d.b)
+	if n <= 0 {
+		d.err, d.b = true, nil
+		return 0
+	}
+	d.b = d.b[n:]
+	return v
+}
+
+func (d *decoder) string() string {
+	n := d.uvarint()
+	if uint64(len(d.b)) < n {
+		d.err, d.b = true, nil
+		return ""
+	}
+	s := string(d.b[:n])
+	d.b = d.b[n:]
+	return s
+}
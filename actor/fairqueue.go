@@ -0,0 +1,135 @@
+package actor
+
+// FairQueue is the mailbox of an actor whose methods have weights. It takes
+// the messages from the In channel and returns them so that, while requests
+// of several methods are waiting, each method gets a share of the processed
+// messages proportional to its weight, and a flood of requests of one method
+// doesn't starve the others. The requests of a method keep their order. The
+// messages that are not requests, as the stop request or a snapshot, keep
+// their order with the requests: they are returned after the requests
+// received before them and before the ones received after them
+type FairQueue struct {
+	in      chan interface{}
+	weights map[string]int
+	system  []queued
+	queues  map[string][]queued
+	methods []string
+	next    int
+	served  int
+	n       int
+	seq     uint64
+}
+
+// queued is a message waiting in a fair queue, with the order in which it
+// was received
+type queued struct {
+	msg interface{}
+	seq uint64
+}
+
+// NewFairQueue creates the fair queue of the messages received in the
+// channel in. Methods without a weight have weight 1
+func NewFairQueue(in chan interface{}, weights map[string]int) *FairQueue {
+	return &FairQueue{in: in, weights: weights, queues: make(map[string][]queued)}
+}
+
+// Next returns the next message to process, waiting for one if there are none
+func (q *FairQueue) Next() interface{} {
+	if q.n == 0 {
+		q.add(<-q.in)
+	}
+	q.fill()
+	return q.pop()
+}
+
+// TryNext returns the next message to process, or false if there are none
+func (q *FairQueue) TryNext() (interface{}, bool) {
+	q.fill()
+	if q.n == 0 {
+		return nil, false
+	}
+	return q.pop(), true
+}
+
+// Len returns the number of messages taken from the In channel waiting to be
+// processed
+func (q *FairQueue) Len() int {
+	return q.n
+}
+
+// fill moves the messages waiting in the In channel to the queues. The queues
+// hold no more messages than the capacity of the channel, so the senders
+// still block when the actor falls behind
+func (q *FairQueue) fill() {
+	for q.n < cap(q.in) {
+		select {
+		case msg := <-q.in:
+			q.add(msg)
+		default:
+			return
+		}
+	}
+}
+
+func (q *FairQueue) add(msg interface{}) {
+	q.n++
+	q.seq++
+	req, ok := msg.(Request)
+	if !ok {
+		q.system = append(q.system, queued{msg, q.seq})
+		return
+	}
+	method := req.Method()
+	if _, ok := q.queues[method]; !ok {
+		q.methods = append(q.methods, method)
+	}
+	q.queues[method] = append(q.queues[method], queued{msg, q.seq})
+}
+
+// pop returns the next message. The methods take turns in a round robin, and
+// each turn serves up to weight requests of the method. Only the requests
+// received before the first waiting message that is not a request take
+// part, and that message is returned when none of them are left
+func (q *FairQueue) pop() interface{} {
+	q.n--
+	barrier := ^uint64(0)
+	if len(q.system) > 0 {
+		barrier = q.system[0].seq
+		if !q.waiting(barrier) {
+			msg := q.system[0].msg
+			q.system[0] = queued{}
+			q.system = q.system[1:]
+			return msg
+		}
+	}
+	for {
+		method := q.methods[q.next]
+		if queue := q.queues[method]; len(queue) > 0 && queue[0].seq < barrier && q.served < q.weight(method) {
+			msg := queue[0].msg
+			queue[0] = queued{}
+			q.queues[method] = queue[1:]
+			q.served++
+			return msg
+		}
+		q.next = (q.next + 1) % len(q.methods)
+		q.served = 0
+	}
+}
+
+// waiting returns true if there are requests received before the message
+// with the order seq waiting in the queues
+func (q *FairQueue) waiting(seq uint64) bool {
+	for _, queue := range q.queues {
+		if len(queue) > 0 && queue[0].seq < seq {
+			return true
+		}
+	}
+	return false
+}
+
+func (q *FairQueue) weight(method string) int {
+	if w, ok := q.weights[method]; ok && w > 0 {
+		return w
+	}
+	return 1
+}
@@ -0,0 +1,45 @@
+package actor
+
+import (
+	"reflect"
+	"testing"
+)
+
+// fairRequest is a request of a method, named by the method and its order
+type fairRequest struct {
+	method, name string
+}
+
+func (req fairRequest) Method() string { return req.method }
+
+func (req fairRequest) Async() bool { return false }
+
+// TestFairQueueOrder checks that the methods take turns by their weights and
+// that the messages that are not requests keep their order with them
+func TestFairQueueOrder(t *testing.T) {
+	in := make(chan interface{}, 16)
+	for _, msg := range []interface{}{
+		fairRequest{"a", "a1"}, fairRequest{"a", "a2"}, fairRequest{"a", "a3"},
+		fairRequest{"b", "b1"}, "snapshot", fairRequest{"b", "b2"},
+		fairRequest{"a", "a4"}, "stop",
+	} {
+		in <- msg
+	}
+	q := NewFairQueue(in, map[string]int{"a": 2})
+
+	var got []string
+	for {
+		msg, ok := q.TryNext()
+		if !ok {
+			break
+		}
+		if req, ok := msg.(fairRequest); ok {
+			msg = req.name
+		}
+		got = append(got, msg.(string))
+	}
+	want := []string{"a1", "a2", "b1", "a3", "snapshot", "a4", "b2", "stop"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,156 @@
+package grafana
+
+// Dashboard is the JSON model of a Grafana dashboard
+type Dashboard struct {
+	Title         string     `json:"title"`
+	UID           string     `json:"uid,omitempty"`
+	Tags          []string   `json:"tags"`
+	Timezone      string     `json:"timezone"`
+	SchemaVersion int        `json:"schemaVersion"`
+	Refresh       string     `json:"refresh"`
+	Time          TimeRange  `json:"time"`
+	Templating    Templating `json:"templating"`
+	Panels        []Panel    `json:"panels"`
+}
+
+// TimeRange is the time range shown by a dashboard
+type TimeRange struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// Templating contains the variables of a dashboard
+type Templating struct {
+	List []Variable `json:"list"`
+}
+
+// Variable is a variable of a dashboard
+type Variable struct {
+	Name       string      `json:"name"`
+	Label      string      `json:"label"`
+	Type       string      `json:"type"`
+	Query      string      `json:"query"`
+	Datasource *Datasource `json:"datasource,omitempty"`
+	Multi      bool        `json:"multi"`
+	IncludeAll bool        `json:"includeAll"`
+	Current    *Current    `json:"current,omitempty"`
+	Refresh    int         `json:"refresh"`
+}
+
+// Current is the selected value of a variable
+type Current struct {
+	Text  []string `json:"text"`
+	Value []string `json:"value"`
+}
+
+// Datasource refers to a data source
+type Datasource struct {
+	Type string `json:"type"`
+	UID  string `json:"uid"`
+}
+
+// Panel is a graph of a dashboard
+type Panel struct {
+	ID          int         `json:"id"`
+	Title       string      `json:"title"`
+	Description string      `json:"description"`
+	Type        string      `json:"type"`
+	Datasource  *Datasource `json:"datasource"`
+	GridPos     GridPos     `json:"gridPos"`
+	Targets     []Target    `json:"targets"`
+	FieldConfig FieldConfig `json:"fieldConfig"`
+}
+
+// GridPos is the position and size of a panel
+type GridPos struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+	W int `json:"w"`
+	H int `json:"h"`
+}
+
+// Target is a query of a panel
+type Target struct {
+	RefID        string `json:"refId"`
+	Expr         string `json:"expr"`
+	LegendFormat string `json:"legendFormat"`
+}
+
+// FieldConfig sets how a panel shows its values
+type FieldConfig struct {
+	Defaults FieldDefaults `json:"defaults"`
+}
+
+// FieldDefaults are the default settings of the values of a panel
+type FieldDefaults struct {
+	Unit string `json:"unit"`
+}
+
+// graph describes a panel of the dashboard
+type graph struct {
+	title string
+	help  string
+	expr  string
+	unit  string
+}
+
+// graphs are the panels of the dashboard, in order
+var graphs = []graph{
+	{"Messages received", "Messages received per second.", "sum by (actor) (rate(goactors_messages_received_total{actor=~\"$actor\"}[$__rate_interval]))", "ops"},
+	{"Messages processed", "Messages processed per second.", "sum by (actor) (rate(goactors_messages_processed_total{actor=~\"$actor\"}[$__rate_interval]))", "ops"},
+	{"Mailbox", "Messages waiting in the In channels of the running actors.", "sum by (actor) (goactors_mailbox_messages{actor=~\"$actor\"})", "short"},
+	{"Running actors", "Actors whose receive loop is running.", "sum by (actor) (goactors_running_actors{actor=~\"$actor\"})", "short"},
+	{"Panics", "Panics caused by the messages per second.", "sum by (actor) (rate(goactors_panics_total{actor=~\"$actor\"}[$__rate_interval]))", "ops"},
+	{"Dropped responses", "Results of asynchronous methods dropped per second because they weren't polled.", "sum by (actor) (rate(goactors_dropped_responses_total{actor=~\"$actor\"}[$__rate_interval]))", "ops"},
+	{"Allocations", "Bytes allocated per second by the handlers, estimated from the sampled messages.", "sum by (actor) (rate(goactors_allocated_bytes_total{actor=~\"$actor\"}[$__rate_interval]))", "Bps"},
+	{"Arenas", "Requests of the arenas in use.", "sum by (actor) (goactors_arena_used_requests{actor=~\"$actor\"})", "short"},
+}
+
+// NewDashboard returns the model of a dashboard with the graphs of the
+// metrics of the actors, two per row. The actor variable selects the
+// actors shown, by default the given ones or all of them if there are none.
+// The data source is chosen with the datasource variable, among the
+// Prometheus ones
+func NewDashboard(title string, actors ...string) Dashboard {
+	ds := &Datasource{Type: "prometheus", UID: "${datasource}"}
+	actorVar := Variable{
+		Name:       "actor",
+		Label:      "Actor",
+		Type:       "query",
+		Query:      "label_values(goactors_messages_received_total, actor)",
+		Datasource: ds,
+		Multi:      true,
+		IncludeAll: true,
+		Current:    &Current{Text: []string{"All"}, Value: []string{"$__all"}},
+		Refresh:    2,
+	}
+	if len(actors) > 0 {
+		actorVar.Current = &Current{Text: actors, Value: actors}
+	}
+	d := Dashboard{
+		Title:         title,
+		Tags:          []string{"goactors"},
+		Timezone:      "browser",
+		SchemaVersion: 39,
+		Refresh:       "10s",
+		Time:          TimeRange{From: "now-1h", To: "now"},
+		Templating: Templating{List: []Variable{
+			{Name: "datasource", Label: "Data source", Type: "datasource", Query: "prometheus"},
+			actorVar,
+		}},
+		Panels: []Panel{},
+	}
+	for i, g := range graphs {
+		d.Panels = append(d.Panels, Panel{
+			ID:          i + 1,
+			Title:       g.title,
+			Description: g.help,
+			Type:        "timeseries",
+			Datasource:  ds,
+			GridPos:     GridPos{X: i % 2 * 12, Y: i / 2 * 8, W: 12, H: 8},
+			Targets:     []Target{{RefID: "A", Expr: g.expr, LegendFormat: "{{actor}}"}},
+			FieldConfig: FieldConfig{Defaults: FieldDefaults{Unit: g.unit}},
+		})
+	}
+	return d
+}
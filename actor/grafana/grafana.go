@@ -0,0 +1,162 @@
+// Package grafana exports the metrics of the actors to Prometheus and builds
+// a Grafana dashboard for them. The metrics published with expvar under the
+// goactors variable are served in the Prometheus text format, with a name
+// per metric and the name of the actor in the actor label:
+//
+//	goactors_messages_received_total{actor="Account"} 1520
+//
+// and NewDashboard returns the JSON model of a dashboard with their graphs,
+// ready to be imported in Grafana or provisioned through its API:
+//
+//	http.Handle("/metrics", grafana.Handler())
+package grafana
+
+import (
+	"bufio"
+	"expvar"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/carevaloc/goactors/actor"
+)
+
+// Family is an exported metric
+type Family struct {
+	// Var is the name of the metric in the expvar map of each actor
+	Var string
+	// Name is the name of the metric in Prometheus
+	Name string
+	// Type is counter or gauge
+	Type string
+	// Help describes the metric
+	Help string
+}
+
+// Families are the exported metrics. The metrics of the actors that aren't
+// in the list are exported as untyped metrics named goactors_ plus their
+// expvar name
+var Families = []Family{
+	{"in", "goactors_messages_received_total", "counter", "Messages received by the actors."},
+	{"out", "goactors_messages_processed_total", "counter", "Messages processed by the actors."},
+	{"panics", "goactors_panics_total", "counter", "Panics caused by the messages."},
+	{"queue", "goactors_mailbox_messages", "gauge", "Messages waiting in the In channels of the running actors."},
+	{"dropped", "goactors_dropped_responses_total", "counter", "Results of asynchronous methods dropped because they weren't polled."},
+	{"allocs", "goactors_allocated_bytes_total", "counter", "Bytes allocated by the handlers, estimated from the sampled messages."},
+	{"over_budget", "goactors_over_budget_messages_total", "counter", "Sampled messages whose handler allocated more than the actor's budget."},
+	{"arena_used", "goactors_arena_used_requests", "gauge", "Requests of the arenas in use."},
+	{"arena_waits", "goactors_arena_waits_total", "counter", "Calls that waited for a free request of the arena."},
+}
+
+// RunningFamily is the metric with the number of running actors
+var RunningFamily = Family{"", "goactors_running_actors", "gauge", "Actors whose receive loop is running."}
+
+// Handler returns the handler that serves the metrics of the actors in the
+// Prometheus text format
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		WriteMetrics(w)
+	})
+}
+
+// WriteMetrics writes the metrics of the actors in the Prometheus text
+// format
+func WriteMetrics(w io.Writer) error {
+	values := collect()
+	bw := bufio.NewWriter(w)
+	families := append([]Family(nil), Families...)
+	known := make(map[string]bool)
+	for _, f := range Families {
+		known[f.Var] = true
+	}
+	var others []string
+	for v := range values {
+		if !known[v] {
+			others = append(others, v)
+		}
+	}
+	sort.Strings(others)
+	for _, v := range others {
+		families = append(families, Family{v, "goactors_" + v, "untyped", "Metric " + v + " of the actors."})
+	}
+
+	for _, f := range families {
+		writeFamily(bw, f, values[f.Var])
+	}
+	running := make(map[string]string)
+	for name, n := range actor.RunningActors() {
+		running[name] = strconv.Itoa(n)
+	}
+	for _, name := range Actors() {
+		if _, ok := running[name]; !ok {
+			running[name] = "0"
+		}
+	}
+	writeFamily(bw, RunningFamily, running)
+	return bw.Flush()
+}
+
+// writeFamily writes the samples of a metric, by actor name
+func writeFamily(w *bufio.Writer, f Family, samples map[string]string) {
+	if len(samples) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", f.Name, f.Help, f.Name, f.Type)
+	var names []string
+	for name := range samples {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "%s{actor=\"%s\"} %s\n", f.Name, escapeLabel(name), samples[name])
+	}
+}
+
+// collect returns the values of the metrics of the actors, by expvar name
+// and actor name
+func collect() map[string]map[string]string {
+	values := make(map[string]map[string]string)
+	vars, ok := expvar.Get("goactors").(*expvar.Map)
+	if !ok {
+		return values
+	}
+	vars.Do(func(kv expvar.KeyValue) {
+		m, ok := kv.Value.(*expvar.Map)
+		if !ok {
+			return
+		}
+		m.Do(func(metric expvar.KeyValue) {
+			v := metric.Value.String()
+			if _, err := strconv.ParseFloat(v, 64); err != nil {
+				return
+			}
+			if values[metric.Key] == nil {
+				values[metric.Key] = make(map[string]string)
+			}
+			values[metric.Key][kv.Key] = v
+		})
+	})
+	return values
+}
+
+// Actors returns the names of the actors with metrics, sorted
+func Actors() []string {
+	var names []string
+	if vars, ok := expvar.Get("goactors").(*expvar.Map); ok {
+		vars.Do(func(kv expvar.KeyValue) {
+			names = append(names, kv.Key)
+		})
+	}
+	sort.Strings(names)
+	return names
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(s string) string {
+	return labelEscaper.Replace(s)
+}
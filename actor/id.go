@@ -0,0 +1,29 @@
+package actor
+
+import (
+	"strconv"
+	"sync/atomic"
+)
+
+// ID identifies an actor instance. IDs are unique in the process and don't
+// change while the actor lives, so they can be used as map keys to refer to
+// the actor whatever the reference used to reach it. The zero ID is the one
+// of the actors not created with their New function
+type ID uint64
+
+var lastID uint64
+
+func (id ID) String() string {
+	return "actor#" + strconv.FormatUint(uint64(id), 10)
+}
+
+// SetID gives the actor a new ID. It is called when the actor is created,
+// and by the fakes
+func (ba *Actor) SetID() {
+	ba.id = ID(atomic.AddUint64(&lastID, 1))
+}
+
+// ActorID returns the ID of the actor
+func (ba *Actor) ActorID() ID {
+	return ba.id
+}
@@ -0,0 +1,37 @@
+package actor
+
+// ParamInfo describes a parameter or result of an actor method
+type ParamInfo struct {
+	Name string
+	Type string
+}
+
+// MethodInfo describes a method of an actor: the messages it accepts
+type MethodInfo struct {
+	Name    string
+	Params  []ParamInfo
+	Results []ParamInfo
+	Async   bool
+	// Seq is true if the method, asynchronous, returns the sequence token
+	// of the write
+	Seq bool
+	// Stage is the kind of stream stage built from the method, if any:
+	// Source, Flow or Sink
+	Stage string
+}
+
+// Introspector is implemented by the generated actors and references. It
+// allows generic tools to list the methods of an actor without importing
+// its package
+type Introspector interface {
+	Methods() []MethodInfo
+}
+
+// MethodsOf returns the methods of an actor or reference, or nil if it
+// doesn't describe them
+func MethodsOf(v interface{}) []MethodInfo {
+	if i, ok := v.(Introspector); ok {
+		return i.Methods()
+	}
+	return nil
+}
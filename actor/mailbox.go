@@ -0,0 +1,42 @@
+package actor
+
+// Mailbox returns the messages received by an actor in the order it should
+// process them. It is created by the generated code when the actor starts:
+// a FairQueue for actors whose methods have weights, or a FIFO mailbox
+type Mailbox interface {
+	// Next returns the next message to process, waiting for one if there are
+	// none
+	Next() interface{}
+	// TryNext returns the next message to process, or false if there are none
+	TryNext() (interface{}, bool)
+	// Len returns the number of messages taken from the In channel waiting to
+	// be processed
+	Len() int
+}
+
+// fifo is the mailbox that returns the messages in the order they are
+// received
+type fifo chan interface{}
+
+// NewMailbox creates the FIFO mailbox of the messages received in the
+// channel in
+func NewMailbox(in chan interface{}) Mailbox {
+	return fifo(in)
+}
+
+func (f fifo) Next() interface{} {
+	return <-f
+}
+
+func (f fifo) TryNext() (interface{}, bool) {
+	select {
+	case msg := <-f:
+		return msg, true
+	default:
+		return nil, false
+	}
+}
+
+func (f fifo) Len() int {
+	return 0
+}
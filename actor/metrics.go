@@ -0,0 +1,122 @@
+package actor
+
+import (
+	"expvar"
+	"sync"
+)
+
+// metrics are published with expvar, under the goactors variable, by actor
+// name. Each actor has the following counters:
+//
+//	in          messages received
+//	out         messages processed
+//	panics      panics caused by the messages
+//	queue       messages waiting in the In channels of the running actors
+//	dropped     responses of asynchronous methods dropped because they weren't polled
+//	allocs      bytes allocated by the handlers, estimated from the sampled messages
+//	over_budget sampled messages whose handler allocated more than the actor's budget
+//	arena_used  requests of the arenas in use
+//	arena_waits calls that waited for a free request of the arena
+var metrics = expvar.NewMap("goactors")
+
+// actorMetrics contains the metrics of all the actors with the same name
+type actorMetrics struct {
+	in         expvar.Int
+	out        expvar.Int
+	panics     expvar.Int
+	dropped    expvar.Int
+	allocs     expvar.Int
+	overBudget expvar.Int
+	arenaUsed  expvar.Int
+	arenaWaits expvar.Int
+	mu         sync.Mutex
+	instances  map[*Actor]bool
+}
+
+var (
+	metricsMu     sync.Mutex
+	metricsByName sync.Map
+)
+
+// metricsOf returns the metrics of the actors with the given name, creating
+// and publishing them the first time
+func metricsOf(name string) *actorMetrics {
+	if m, ok := metricsByName.Load(name); ok {
+		return m.(*actorMetrics)
+	}
+
+	metricsMu.Lock()
+	defer metricsMu.Unlock()
+	if m, ok := metricsByName.Load(name); ok {
+		return m.(*actorMetrics)
+	}
+	m := &actorMetrics{instances: make(map[*Actor]bool)}
+	vars := new(expvar.Map).Init()
+	vars.Set("in", &m.in)
+	vars.Set("out", &m.out)
+	vars.Set("panics", &m.panics)
+	vars.Set("queue", expvar.Func(m.queue))
+	vars.Set("dropped", &m.dropped)
+	vars.Set("allocs", &m.allocs)
+	vars.Set("over_budget", &m.overBudget)
+	vars.Set("arena_used", &m.arenaUsed)
+	vars.Set("arena_waits", &m.arenaWaits)
+	metrics.Set(name, vars)
+	metricsByName.Store(name, m)
+	return m
+}
+
+// RegisterMetrics publishes the metrics of the actors with the given name,
+// which otherwise are published when the first of them starts, so the
+// dashboards show all the actors of the program from the beginning. It is
+// called by the generated code
+func RegisterMetrics(name string) {
+	metricsOf(name)
+}
+
+// queue returns the number of messages waiting to be processed
+func (m *actorMetrics) queue() interface{} {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var n int
+	for ba := range m.instances {
+		n += len(ba.In)
+	}
+	return n
+}
+
+// Track adds a running actor to the queue metric of the actors with the
+// given name. It is called by the generated code when the actor starts, and
+// it returns the function that removes it, called when the receive loop
+// returns
+func Track(name string, inst Instance) func() {
+	m := metricsOf(name)
+	ba := inst.base()
+	m.mu.Lock()
+	m.instances[ba] = true
+	m.mu.Unlock()
+	return func() {
+		m.mu.Lock()
+		delete(m.instances, ba)
+		m.mu.Unlock()
+		if hook, _ := exitHook.Load().(ExitHook); hook != nil {
+			hook(name)
+		}
+	}
+}
+
+// RunningActors returns the number of actors whose receive loop is running,
+// by actor name
+func RunningActors() map[string]int {
+	running := make(map[string]int)
+	metricsByName.Range(func(name, m interface{}) bool {
+		am := m.(*actorMetrics)
+		am.mu.Lock()
+		if n := len(am.instances); n > 0 {
+			running[name.(string)] = n
+		}
+		am.mu.Unlock()
+		return true
+	})
+	return running
+}
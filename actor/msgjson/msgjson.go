@@ -0,0 +1,538 @@
+// Package msgjson contains the encoder and the decoder used by the JSON
+// methods generated for the messages of the actors with the actorc -json
+// flag. The parameters and results of the basic types, strings and []byte
+// are encoded without reflection; other types are left to encoding/json.
+// The output is the same encoding/json produces
+package msgjson
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Encoder writes a JSON object. The first error is kept and returned by End
+type Encoder struct {
+	buf []byte
+	err error
+}
+
+// Key starts a member of the object
+func (e *Encoder) Key(key string) {
+	if len(e.buf) == 0 {
+		e.buf = append(make([]byte, 0, 64), '{')
+	} else {
+		e.buf = append(e.buf, ',')
+	}
+	e.buf = appendString(e.buf, key)
+	e.buf = append(e.buf, ':')
+}
+
+// String writes a string value
+func (e *Encoder) String(v string) {
+	e.buf = appendString(e.buf, v)
+}
+
+// Int writes a signed integer value
+func (e *Encoder) Int(v int64) {
+	e.buf = strconv.AppendInt(e.buf, v, 10)
+}
+
+// Uint writes an unsigned integer value
+func (e *Encoder) Uint(v uint64) {
+	e.buf = strconv.AppendUint(e.buf, v, 10)
+}
+
+// Float writes a floating point value of the given bit size
+func (e *Encoder) Float(v float64, bits int) {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		e.fail(fmt.Errorf("msgjson: unsupported value: %s", strconv.FormatFloat(v, 'g', -1, bits)))
+		return
+	}
+	// the same format as encoding/json
+	format := byte('f')
+	if abs := math.Abs(v); abs != 0 {
+		if bits == 64 && (abs < 1e-6 || abs >= 1e21) || bits == 32 && (float32(abs) < 1e-6 || float32(abs) >= 1e21) {
+			format = 'e'
+		}
+	}
+	start := len(e.buf)
+	e.buf = strconv.AppendFloat(e.buf, v, format, -1, bits)
+	if format == 'e' {
+		// clean up e-09 to e-9
+		n := len(e.buf) - start
+		if n >= 4 && e.buf[len(e.buf)-4] == 'e' && e.buf[len(e.buf)-3] == '-' && e.buf[len(e.buf)-2] == '0' {
+			e.buf[len(e.buf)-2] = e.buf[len(e.buf)-1]
+			e.buf = e.buf[:len(e.buf)-1]
+		}
+	}
+}
+
+// Bool writes a boolean value
+func (e *Encoder) Bool(v bool) {
+	e.buf = strconv.AppendBool(e.buf, v)
+}
+
+// Bytes writes a []byte value, encoded as base64
+func (e *Encoder) Bytes(v []byte) {
+	if v == nil {
+		e.buf = append(e.buf, "null"...)
+		return
+	}
+	e.buf = append(e.buf, '"')
+	n := len(e.buf)
+	e.buf = append(e.buf, make([]byte, base64.StdEncoding.EncodedLen(len(v)))...)
+	base64.StdEncoding.Encode(e.buf[n:], v)
+	e.buf = append(e.buf, '"')
+}
+
+// Value writes a value of any other type with encoding/json
+func (e *Encoder) Value(v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		e.fail(err)
+		data = []byte("null")
+	}
+	e.buf = append(e.buf, data...)
+}
+
+// End ends the object and returns it
+func (e *Encoder) End() ([]byte, error) {
+	if e.err != nil {
+		return nil, e.err
+	}
+	if len(e.buf) == 0 {
+		return []byte("{}"), nil
+	}
+	return append(e.buf, '}'), nil
+}
+
+func (e *Encoder) fail(err error) {
+	if e.err == nil {
+		e.err = err
+	}
+}
+
+const hex = "0123456789abcdef"
+
+// appendString appends the JSON string s, escaped as encoding/json does
+func appendString(b []byte, s string) []byte {
+	b = append(b, '"')
+	start := 0
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c < utf8.RuneSelf {
+			if c >= 0x20 && c != '"' && c != '\\' && c != '<' && c != '>' && c != '&' {
+				i++
+				continue
+			}
+			b = append(b, s[start:i]...)
+			switch c {
+			case '"', '\\':
+				b = append(b, '\\', c)
+			case '\b':
+				b = append(b, '\\', 'b')
+			case '\f':
+				b = append(b, '\\', 'f')
+			case '\n':
+				b = append(b, '\\', 'n')
+			case '\r':
+				b = append(b, '\\', 'r')
+			case '\t':
+				b = append(b, '\\', 't')
+			default:
+				b = append(b, '\\', 'u', '0', '0', hex[c>>4], hex[c&0xF])
+			}
+			i++
+			start = i
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			b = append(b, s[start:i]...)
+			b = append(b, `\ufffd`...)
+			i += size
+			start = i
+			continue
+		}
+		if r == '\u2028' || r == '\u2029' {
+			b = append(b, s[start:i]...)
+			b = append(b, '\\', 'u', '2', '0', '2', hex[r&0xF])
+			i += size
+			start = i
+			continue
+		}
+		i += size
+	}
+	b = append(b, s[start:]...)
+	return append(b, '"')
+}
+
+// Decoder reads the members of a JSON object. The first error is kept and
+// returned by Err
+type Decoder struct {
+	data    []byte
+	pos     int
+	key     string
+	started bool
+	done    bool
+	err     error
+}
+
+// NewDecoder creates a decoder of the object in data
+func NewDecoder(data []byte) *Decoder {
+	return &Decoder{data: data}
+}
+
+// Next reads the key of the next member of the object. It returns false at
+// the end of the object, or if there's an error
+func (d *Decoder) Next() bool {
+	if d.err != nil || d.done {
+		return false
+	}
+	d.space()
+	if !d.started {
+		d.started = true
+		if d.isNull() {
+			d.pos += 4
+			d.done = true
+			return false
+		}
+		if !d.expect('{') {
+			return false
+		}
+		d.space()
+		if d.peek() == '}' {
+			d.pos++
+			d.done = true
+			return false
+		}
+	} else {
+		switch d.peek() {
+		case '}':
+			d.pos++
+			d.done = true
+			return false
+		case ',':
+			d.pos++
+		default:
+			d.syntax("expected , or }")
+			return false
+		}
+	}
+	d.space()
+	d.key = d.str()
+	d.space()
+	d.expect(':')
+	d.space()
+	return d.err == nil
+}
+
+// Key returns the key of the current member
+func (d *Decoder) Key() string {
+	return d.key
+}
+
+// String reads a string value
+func (d *Decoder) String() string {
+	if d.null() {
+		return ""
+	}
+	return d.str()
+}
+
+// Int reads a signed integer value of the given bit size
+func (d *Decoder) Int(bits int) int64 {
+	if d.null() {
+		return 0
+	}
+	v, err := strconv.ParseInt(d.number(), 10, bits)
+	if err != nil {
+		d.fail(err)
+	}
+	return v
+}
+
+// Uint reads an unsigned integer value of the given bit size
+func (d *Decoder) Uint(bits int) uint64 {
+	if d.null() {
+		return 0
+	}
+	v, err := strconv.ParseUint(d.number(), 10, bits)
+	if err != nil {
+		d.fail(err)
+	}
+	return v
+}
+
+// Float reads a floating point value of the given bit size
+func (d *Decoder) Float(bits int) float64 {
+	if d.null() {
+		return 0
+	}
+	v, err := strconv.ParseFloat(d.number(), bits)
+	if err != nil {
+		d.fail(err)
+	}
+	return v
+}
+
+// Bool reads a boolean value
+func (d *Decoder) Bool() bool {
+	switch {
+	case d.null():
+		return false
+	case d.literal("true"):
+		return true
+	case d.literal("false"):
+		return false
+	}
+	d.syntax("expected a boolean")
+	return false
+}
+
+// Bytes reads a []byte value, encoded as base64
+func (d *Decoder) Bytes() []byte {
+	if d.null() {
+		return nil
+	}
+	v, err := base64.StdEncoding.DecodeString(d.str())
+	if err != nil {
+		d.fail(err)
+	}
+	return v
+}
+
+// Value reads a value of any other type with encoding/json
+func (d *Decoder) Value(v interface{}) {
+	start := d.pos
+	d.Skip()
+	if d.err == nil {
+		d.fail(json.Unmarshal(d.data[start:d.pos], v))
+	}
+}
+
+// Skip skips the value of the current member
+func (d *Decoder) Skip() {
+	depth := 0
+	for d.err == nil {
+		d.space()
+		switch c := d.peek(); {
+		case c == '"':
+			d.str()
+		case c == '{' || c == '[':
+			d.pos++
+			depth++
+		case c == '}' || c == ']':
+			if depth == 0 {
+				d.syntax("unexpected " + string(c))
+				return
+			}
+			d.pos++
+			depth--
+		case c == ',' || c == ':':
+			if depth == 0 {
+				d.syntax("unexpected " + string(c))
+				return
+			}
+			d.pos++
+			continue
+		case d.literal("null") || d.literal("true") || d.literal("false"):
+		default:
+			d.number()
+		}
+		if depth == 0 {
+			return
+		}
+	}
+}
+
+// Err returns the first error found, or an error if there's data after the
+// object
+func (d *Decoder) Err() error {
+	if d.err == nil && d.done {
+		d.space()
+		if d.pos < len(d.data) {
+			d.syntax("unexpected data after the object")
+		}
+	}
+	if d.err == nil && !d.done {
+		d.syntax("unexpected end of the object")
+	}
+	return d.err
+}
+
+func (d *Decoder) fail(err error) {
+	if d.err == nil && err != nil {
+		d.err = err
+	}
+}
+
+func (d *Decoder) syntax(msg string) {
+	d.fail(fmt.Errorf("msgjson: %s at offset %d", msg, d.pos))
+}
+
+func (d *Decoder) space() {
+	for d.pos < len(d.data) {
+		switch d.data[d.pos] {
+		case ' ', '\t', '\n', '\r':
+			d.pos++
+		default:
+			return
+		}
+	}
+}
+
+func (d *Decoder) peek() byte {
+	if d.pos < len(d.data) {
+		return d.data[d.pos]
+	}
+	return 0
+}
+
+func (d *Decoder) expect(c byte) bool {
+	if d.peek() != c {
+		d.syntax("expected " + string(c))
+		return false
+	}
+	d.pos++
+	return true
+}
+
+func (d *Decoder) isNull() bool {
+	return len(d.data)-d.pos >= 4 && string(d.data[d.pos:d.pos+4]) == "null"
+}
+
+// null skips a null value
+func (d *Decoder) null() bool {
+	return d.literal("null")
+}
+
+// literal skips the literal s if it's the next value
+func (d *Decoder) literal(s string) bool {
+	if len(d.data)-d.pos >= len(s) && string(d.data[d.pos:d.pos+len(s)]) == s {
+		d.pos += len(s)
+		return true
+	}
+	return false
+}
+
+// number reads the text of a number
+func (d *Decoder) number() string {
+	start := d.pos
+	for d.pos < len(d.data) {
+		switch c := d.data[d.pos]; {
+		case c >= '0' && c <= '9', c == '-', c == '+', c == '.', c == 'e', c == 'E':
+			d.pos++
+			continue
+		}
+		break
+	}
+	if start == d.pos {
+		d.syntax("expected a value")
+	}
+	return string(d.data[start:d.pos])
+}
+
+// str reads a string, unescaping it
+func (d *Decoder) str() string {
+	if !d.expect('"') {
+		return ""
+	}
+	start := d.pos
+	for d.pos < len(d.data) {
+		c := d.data[d.pos]
+		if c == '"' {
+			s := string(d.data[start:d.pos])
+			d.pos++
+			return s
+		}
+		if c == '\\' || c < 0x20 || c >= utf8.RuneSelf {
+			break
+		}
+		d.pos++
+	}
+
+	b := append([]byte(nil), d.data[start:d.pos]...)
+	for d.pos < len(d.data) {
+		c := d.data[d.pos]
+		switch {
+		case c == '"':
+			d.pos++
+			return string(b)
+		case c < 0x20:
+			d.syntax("invalid string")
+			return ""
+		case c == '\\':
+			d.pos++
+			if d.pos >= len(d.data) {
+				d.syntax("unexpected end of a string")
+				return ""
+			}
+			switch e := d.data[d.pos]; e {
+			case '"', '\\', '/':
+				b = append(b, e)
+			case 'b':
+				b = append(b, '\b')
+			case 'f':
+				b = append(b, '\f')
+			case 'n':
+				b = append(b, '\n')
+			case 'r':
+				b = append(b, '\r')
+			case 't':
+				b = append(b, '\t')
+			case 'u':
+				r := d.hex4()
+				if utf16.IsSurrogate(r) {
+					r2 := rune(-1)
+					if len(d.data)-d.pos > 2 && d.data[d.pos+1] == '\\' && d.data[d.pos+2] == 'u' {
+						save := d.pos
+						d.pos += 2
+						if r2 = utf16.DecodeRune(r, d.hex4()); r2 == utf8.RuneError {
+							d.pos = save
+						}
+					}
+					if r2 < 0 {
+						r2 = utf8.RuneError
+					}
+					r = r2
+				}
+				b = append(b, string(r)...)
+			default:
+				d.syntax("invalid string")
+				return ""
+			}
+			d.pos++
+		case c < utf8.RuneSelf:
+			b = append(b, c)
+			d.pos++
+		default:
+			r, size := utf8.DecodeRune(d.data[d.pos:])
+			b = append(b, string(r)...)
+			d.pos += size
+		}
+	}
+	d.syntax("unexpected end of a string")
+	return ""
+}
+
+// hex4 reads the four hex digits of a \u escape. On return pos is at the
+// last digit
+func (d *Decoder) hex4() rune {
+	if len(d.data)-d.pos < 5 {
+		d.syntax("invalid string")
+		return utf8.RuneError
+	}
+	v, err := strconv.ParseUint(string(d.data[d.pos+1:d.pos+5]), 16, 16)
+	if err != nil {
+		d.syntax("invalid string")
+		return utf8.RuneError
+	}
+	d.pos += 4
+	return rune(v)
+}
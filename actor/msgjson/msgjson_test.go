@@ -0,0 +1,191 @@
+package msgjson
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// request has the JSON methods that actorc -json generates for a request
+// with these parameters, all of them encoded without reflection
+type request struct {
+	name  string
+	seq   int32
+	ratio float64
+	data  []byte
+}
+
+func (req request) MarshalJSON() ([]byte, error) {
+	var e Encoder
+	e.Key("name")
+	e.String(req.name)
+	e.Key("seq")
+	e.Int(int64(req.seq))
+	e.Key("ratio")
+	e.Float(req.ratio, 64)
+	e.Key("data")
+	e.Bytes(req.data)
+	return e.End()
+}
+
+func (req *request) UnmarshalJSON(data []byte) error {
+	d := NewDecoder(data)
+	for d.Next() {
+		switch d.Key() {
+		case "name":
+			req.name = d.String()
+		case "seq":
+			req.seq = int32(d.Int(32))
+		case "ratio":
+			req.ratio = d.Float(64)
+		case "data":
+			req.data = d.Bytes()
+		default:
+			d.Skip()
+		}
+	}
+	return d.Err()
+}
+
+// mixedRequest has the JSON methods generated for a request with
+// parameters left to encoding/json: a time.Time, which is a
+// json.Marshaler, and a json.RawMessage
+type mixedRequest struct {
+	name  string
+	at    time.Time
+	extra json.RawMessage
+}
+
+func (req mixedRequest) MarshalJSON() ([]byte, error) {
+	var e Encoder
+	e.Key("name")
+	e.String(req.name)
+	e.Key("at")
+	e.Value(req.at)
+	e.Key("extra")
+	e.Value(req.extra)
+	return e.End()
+}
+
+func (req *mixedRequest) UnmarshalJSON(data []byte) error {
+	d := NewDecoder(data)
+	for d.Next() {
+		switch d.Key() {
+		case "name":
+			req.name = d.String()
+		case "at":
+			d.Value(&req.at)
+		case "extra":
+			d.Value(&req.extra)
+		default:
+			d.Skip()
+		}
+	}
+	return d.Err()
+}
+
+// plainRequest and plainMixedRequest are the requests encoded by
+// encoding/json with reflection
+type plainRequest struct {
+	Name  string  `json:"name"`
+	Seq   int32   `json:"seq"`
+	Ratio float64 `json:"ratio"`
+	Data  []byte  `json:"data"`
+}
+
+type plainMixedRequest struct {
+	Name  string          `json:"name"`
+	At    time.Time       `json:"at"`
+	Extra json.RawMessage `json:"extra"`
+}
+
+var (
+	req        = request{"café <\"x\">", -7, 0.25, []byte("blob")}
+	plain      = plainRequest{req.name, req.seq, req.ratio, req.data}
+	mixed      = mixedRequest{req.name, time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC), json.RawMessage(`{"k":[1,2]}`)}
+	plainMixed = plainMixedRequest{mixed.name, mixed.at, mixed.extra}
+)
+
+// codecs are the messages compared by the test and the benchmarks: each one
+// with the generated methods and encoded with reflection, and the functions
+// returning new messages to decode them
+var codecs = []struct {
+	name          string
+	msg, plain    interface{}
+	new, newPlain func() interface{}
+}{
+	{"basic", req, plain, func() interface{} { return new(request) }, func() interface{} { return new(plainRequest) }},
+	{"mixed", mixed, plainMixed, func() interface{} { return new(mixedRequest) }, func() interface{} { return new(plainMixedRequest) }},
+}
+
+func TestSameAsEncodingJSON(t *testing.T) {
+	for _, c := range codecs {
+		got, err := json.Marshal(c.msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, err := json.Marshal(c.plain)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Fatalf("%s: got %s, want %s", c.name, got, want)
+		}
+
+		decoded := c.new()
+		if err := json.Unmarshal(want, decoded); err != nil {
+			t.Fatal(err)
+		}
+		if v := reflect.ValueOf(decoded).Elem().Interface(); !reflect.DeepEqual(v, c.msg) {
+			t.Fatalf("%s: decoded %+v, want %+v", c.name, v, c.msg)
+		}
+	}
+}
+
+func BenchmarkMarshal(b *testing.B) {
+	for _, c := range codecs {
+		m := c.msg.(json.Marshaler)
+		b.Run(c.name+"/msgjson", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := m.MarshalJSON(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+		plain := c.plain
+		b.Run(c.name+"/encoding-json", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := json.Marshal(plain); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkUnmarshal(b *testing.B) {
+	for _, c := range codecs {
+		data, err := json.Marshal(c.plain)
+		if err != nil {
+			b.Fatal(err)
+		}
+		newMsg := c.new
+		b.Run(c.name+"/msgjson", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if err := newMsg().(json.Unmarshaler).UnmarshalJSON(data); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+		newPlain := c.newPlain
+		b.Run(c.name+"/encoding-json", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if err := json.Unmarshal(data, newPlain()); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
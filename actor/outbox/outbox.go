@@ -0,0 +1,255 @@
+// Package outbox solves the dual write of the actors that keep their state
+// in a database and send messages to other actors. A handler writes its
+// changes and its outgoing messages in the same transaction, the messages
+// to an outbox table, and the relay delivers them once the transaction is
+// committed. Messages are delivered at least once and in the order they
+// were written, so their receivers should be idempotent.
+//
+// The outbox table must have the columns id, an integer primary key that
+// increases with each inserted row, dest, a text, and payload, a blob
+// (bytea in Postgres). The payloads can be encrypted with WithEncryption.
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/carevaloc/goactors/actor"
+	"github.com/carevaloc/goactors/actor/seal"
+)
+
+// BatchSize is the number of messages the relay reads from the table at a
+// time
+const BatchSize = 100
+
+// DefaultInterval is the default time between two reads of the table when
+// the relay isn't woken up by a transaction
+const DefaultInterval = time.Second
+
+// Outbox is the outbox table of a database and the routes of the messages
+// written to it
+type Outbox struct {
+	db       *sql.DB
+	table    string
+	numbered bool
+	interval time.Duration
+	keys     seal.KeyProvider
+	wake     chan struct{}
+	mu       sync.Mutex
+	routes   map[string]func([]byte) error
+}
+
+// Option configures an Outbox
+type Option func(*Outbox)
+
+// WithNumberedParams makes the queries use the parameters $1, $2... of
+// Postgres instead of ?
+func WithNumberedParams() Option {
+	return func(o *Outbox) {
+		o.numbered = true
+	}
+}
+
+// WithInterval sets the time between two reads of the table when the relay
+// isn't woken up by a transaction. It's also the time the relay waits
+// before retrying a message that couldn't be delivered
+func WithInterval(d time.Duration) Option {
+	return func(o *Outbox) {
+		o.interval = d
+	}
+}
+
+// WithEncryption seals the payloads of the messages with the keys of kp
+// before they are written to the table, so they are encrypted at rest, and
+// opens them before they are delivered. The destination is authenticated
+// with the payload, so a message can't be moved to another destination
+func WithEncryption(kp seal.KeyProvider) Option {
+	return func(o *Outbox) {
+		o.keys = kp
+	}
+}
+
+// New returns the outbox stored in table
+func New(db *sql.DB, table string, opts ...Option) *Outbox {
+	o := &Outbox{
+		db:       db,
+		table:    table,
+		interval: DefaultInterval,
+		wake:     make(chan struct{}, 1),
+		routes:   make(map[string]func([]byte) error),
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// param returns the query parameter number n, starting at 1
+func (o *Outbox) param(n int) string {
+	if o.numbered {
+		return "$" + strconv.Itoa(n)
+	}
+	return "?"
+}
+
+// Tx is a transaction of an outbox. The state changes are written with the
+// methods of the embedded sql.Tx, and the outgoing messages with Send
+type Tx struct {
+	*sql.Tx
+	ctx  context.Context
+	ob   *Outbox
+	sent bool
+}
+
+// Send writes a message for dest to the outbox table. msg is encoded in
+// JSON, and it's delivered only if the transaction is committed
+func (tx *Tx) Send(dest string, msg interface{}) error {
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("outbox: message for %s: %w", dest, err)
+	}
+	if tx.ob.keys != nil {
+		if payload, err = seal.Seal(tx.ctx, tx.ob.keys, payload, []byte(dest)); err != nil {
+			return fmt.Errorf("outbox: message for %s: %w", dest, err)
+		}
+	}
+	query := "INSERT INTO " + tx.ob.table + " (dest, payload) VALUES (" + tx.ob.param(1) + ", " + tx.ob.param(2) + ")"
+	if _, err := tx.ExecContext(tx.ctx, query, dest, payload); err != nil {
+		return fmt.Errorf("outbox: message for %s: %w", dest, err)
+	}
+	tx.sent = true
+	return nil
+}
+
+// Update runs fn in a transaction, which is committed if fn returns nil and
+// rolled back otherwise. The relay is woken up when the transaction sent
+// messages. It is meant to be called by the handlers of the actors
+func (o *Outbox) Update(ctx context.Context, fn func(tx *Tx) error) error {
+	sqlTx, err := o.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	tx := &Tx{Tx: sqlTx, ctx: ctx, ob: o}
+	if err := fn(tx); err != nil {
+		sqlTx.Rollback()
+		return err
+	}
+	if err := sqlTx.Commit(); err != nil {
+		return err
+	}
+	if tx.sent {
+		select {
+		case o.wake <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// Route sets the function that delivers the messages for dest, which
+// receives their JSON payload. It usually decodes it and calls a method of
+// the reference of an actor. It must be set before the relay is run
+func (o *Outbox) Route(dest string, deliver func(payload []byte) error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.routes[dest] = deliver
+}
+
+// Run runs the relay until ctx is done, and returns its error. The relay
+// delivers the messages of the table in order and deletes each one once
+// it's delivered. A message that can't be delivered, because its route
+// returns an error, panics or doesn't exist, is logged and retried, and the
+// ones after it wait. Only a relay should be run for a table
+func (o *Outbox) Run(ctx context.Context) error {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-o.wake:
+		case <-timer.C:
+		}
+		for {
+			n, err := o.relay(ctx)
+			if err != nil {
+				actor.Log.Printf("outbox %s: %v\n", o.table, err)
+				break
+			}
+			if n < BatchSize {
+				break
+			}
+		}
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(o.interval)
+	}
+}
+
+// message is a row of the outbox table
+type message struct {
+	id      int64
+	dest    string
+	payload []byte
+}
+
+// relay delivers a batch of messages and returns how many it delivered
+func (o *Outbox) relay(ctx context.Context) (int, error) {
+	rows, err := o.db.QueryContext(ctx, "SELECT id, dest, payload FROM "+o.table+" ORDER BY id LIMIT "+strconv.Itoa(BatchSize))
+	if err != nil {
+		return 0, err
+	}
+	var batch []message
+	for rows.Next() {
+		var m message
+		if err := rows.Scan(&m.id, &m.dest, &m.payload); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		batch = append(batch, m)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for i, m := range batch {
+		if err := o.deliver(ctx, m); err != nil {
+			return i, fmt.Errorf("message %d for %s: %w", m.id, m.dest, err)
+		}
+		if _, err := o.db.ExecContext(ctx, "DELETE FROM "+o.table+" WHERE id = "+o.param(1), m.id); err != nil {
+			return i, err
+		}
+	}
+	return len(batch), nil
+}
+
+// deliver passes a message to its route, turning its panics into errors.
+// The payloads sealed with WithEncryption are opened first
+func (o *Outbox) deliver(ctx context.Context, m message) (err error) {
+	o.mu.Lock()
+	route := o.routes[m.dest]
+	o.mu.Unlock()
+	if route == nil {
+		return errors.New("no route")
+	}
+	if o.keys != nil {
+		if m.payload, err = seal.Open(ctx, o.keys, m.payload, []byte(m.dest)); err != nil {
+			return err
+		}
+	}
+	if p, _ := actor.Try(func() { err = route(m.payload) }); p != nil {
+		return fmt.Errorf("panic: %v", p)
+	}
+	return err
+}
@@ -0,0 +1,30 @@
+package actor
+
+import "sync/atomic"
+
+// projection holds the view published by an actor, so nil views can be
+// stored
+type projection struct {
+	v interface{}
+}
+
+// Publish publishes v as the projection of the actor's state, which the
+// readers get without sending a message to the actor. v must not be
+// modified afterwards: the actor publishes a new copy when its state
+// changes. It is called by the generated code after each message
+func (ba *Actor) Publish(v interface{}) {
+	if ba.view == nil {
+		ba.view = new(atomic.Value)
+	}
+	ba.view.Store(projection{v})
+}
+
+// Projection returns the last projection published by the actor, or nil if
+// it didn't publish any
+func (ba *Actor) Projection() interface{} {
+	if ba.view == nil {
+		return nil
+	}
+	p, _ := ba.view.Load().(projection)
+	return p.v
+}
@@ -0,0 +1,81 @@
+package actor
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// MessageType describes a message type in the registry
+type MessageType struct {
+	// Name is the stable name of the message: package.Actor.MethodRequest,
+	// package.Actor.MethodResponse or package.Actor.Stop
+	Name string
+	// Version is the message version declared by the actor
+	Version int
+	// New returns a pointer to a new zero message, ready to be decoded into
+	New func() interface{}
+}
+
+// registry contains the messages registered by the generated code
+var registry = struct {
+	sync.RWMutex
+	names map[string]MessageType
+	types map[reflect.Type]MessageType
+}{
+	names: make(map[string]MessageType),
+	types: make(map[reflect.Type]MessageType),
+}
+
+// RegisterMessage adds a message type to the registry. It is called by the
+// generated code for every request and response. It panics if the name is
+// already registered for a different type
+func RegisterMessage(name string, version int, new func() interface{}) {
+	t := reflect.TypeOf(new()).Elem()
+
+	registry.Lock()
+	defer registry.Unlock()
+	if mt, ok := registry.names[name]; ok && reflect.TypeOf(mt.New()).Elem() != t {
+		panic(fmt.Sprintf("actor: message %s registered for types %s and %s", name, reflect.TypeOf(mt.New()).Elem(), t))
+	}
+	mt := MessageType{Name: name, Version: version, New: new}
+	registry.names[name] = mt
+	registry.types[t] = mt
+}
+
+// LookupMessage returns the message type registered with the given name
+func LookupMessage(name string) (MessageType, bool) {
+	registry.RLock()
+	defer registry.RUnlock()
+	mt, ok := registry.names[name]
+	return mt, ok
+}
+
+// MessageTypeOf returns the registered type of msg, which can be a message
+// or a pointer to a message
+func MessageTypeOf(msg interface{}) (MessageType, bool) {
+	t := reflect.TypeOf(msg)
+	if t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	registry.RLock()
+	defer registry.RUnlock()
+	mt, ok := registry.types[t]
+	return mt, ok
+}
+
+// Messages returns the registered message types sorted by name
+func Messages() []MessageType {
+	registry.RLock()
+	defer registry.RUnlock()
+	var result []MessageType
+	for _, mt := range registry.names {
+		result = append(result, mt)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
@@ -0,0 +1,85 @@
+package actor
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrNotReplayable is returned when a message can't be sent again to an
+// actor: it's not one of its requests, or one of its parameters can't be
+// decoded as it was, like the blob parameters
+var ErrNotReplayable = errors.New("message can't be replayed")
+
+// Replay sends again to an actor of the system a message of the registry,
+// decoded from its JSON payload, like the ones of a dead letter log. name
+// is the name of the actor in the system or, if there is only one running,
+// its type, the actor of the dead letters. The request is sent through a
+// new reference, and its results are discarded. A version other than zero
+// must match the one of the registered message. Only the actors with the
+// json feature can replay their messages
+func (s *System) Replay(name, message string, version int, payload []byte) error {
+	b, err := s.replayTarget(name)
+	if err != nil {
+		return err
+	}
+	if b.replay == nil {
+		return fmt.Errorf("%s: %w: the actor doesn't have the json feature", name, ErrNotReplayable)
+	}
+	mt, ok := LookupMessage(message)
+	if !ok {
+		return fmt.Errorf("%s: unknown message %s", name, message)
+	}
+	if version != 0 && version != mt.Version {
+		return fmt.Errorf("%s: message %s version %d, expected %d", name, message, version, mt.Version)
+	}
+	msg := mt.New()
+	if err := json.Unmarshal(payload, msg); err != nil {
+		return fmt.Errorf("%s: message %s: %v", name, message, err)
+	}
+
+	if p, _ := Try(func() { err = b.replay(msg) }); p != nil {
+		return fmt.Errorf("%s: message %s: panic: %v", name, message, p)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: message %s: %w", name, message, err)
+	}
+	return nil
+}
+
+// replayTarget returns the running actor with the given name or, if there
+// is only one, of the given type
+func (s *System) replayTarget(name string) (*Actor, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if m := s.member(name); m != nil {
+		m, err := s.running(name)
+		if err != nil {
+			return nil, err
+		}
+		return m.inst.base(), nil
+	}
+	var found []*member
+	for _, m := range s.members {
+		if b := m.inst.base(); b.name == name && m.started && !stopped(b) {
+			found = append(found, m)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return nil, fmt.Errorf("%s: not running in the system", name)
+	case 1:
+		return found[0].inst.base(), nil
+	}
+	return nil, fmt.Errorf("%s: %d actors running, give the name of one of them", name, len(found))
+}
+
+// AwaitPoll calls poll every millisecond until it returns true. It is
+// called by the generated code to wait for the results of the asynchronous
+// methods it replays
+func AwaitPoll(poll func() bool) {
+	for !poll() {
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,116 @@
+package actor
+
+import (
+	"fmt"
+	"runtime"
+	"sync/atomic"
+	"time"
+)
+
+// ResponseTimeout returns how long the response of an asynchronous method
+// waits to be polled. The actor processes other messages meanwhile, and if
+// the response isn't polled in time it is dropped. Zero means that it waits
+// until it's polled
+func (ba *Actor) ResponseTimeout() time.Duration {
+	return responseTimeout
+}
+
+// DroppedResponse contains the response of an asynchronous method that was
+// dropped because it wasn't polled in time
+type DroppedResponse struct {
+	Actor    string
+	Method   string
+	Response interface{}
+	Timeout  time.Duration
+}
+
+func (dr DroppedResponse) String() string {
+	return fmt.Sprintf("%s.%s: response %T dropped, not polled in %v", dr.Actor, dr.Method, dr.Response, dr.Timeout)
+}
+
+var droppedResponseHandler atomic.Value
+
+// SetDroppedResponseHandler sets the function that will receive the dropped
+// responses. By default, or with a nil handler, they are written to the
+// logger
+func SetDroppedResponseHandler(h func(DroppedResponse)) {
+	droppedResponseHandler.Store(h)
+}
+
+// Deliver sends the response of an asynchronous method to the reference
+// that called it. out has room for it, so the actor doesn't wait for it to
+// be polled. If it isn't polled before the timeout, when there is one, it's
+// dropped: it's counted in the actor's metrics and passed to the dropped
+// response handler. It is called by the generated code
+func Deliver(name, method string, out chan interface{}, resp interface{}, timeout time.Duration) {
+	out <- resp
+	if timeout <= 0 {
+		return
+	}
+	time.AfterFunc(timeout, func() {
+		select {
+		case resp := <-out:
+			metricsOf(name).dropped.Add(1)
+			dr := DroppedResponse{Actor: name, Method: method, Response: resp, Timeout: timeout}
+			if h, _ := droppedResponseHandler.Load().(func(DroppedResponse)); h != nil {
+				h(dr)
+			} else {
+				Log.Println(dr)
+			}
+		default:
+		}
+	})
+}
+
+// replies are the responses of the synchronous methods of an actor waiting
+// to be sent, when it batches them
+type replies struct {
+	max     int
+	pending []reply
+}
+
+type reply struct {
+	out  chan interface{}
+	resp interface{}
+}
+
+// SetReplies makes the actor batch the responses of its synchronous methods:
+// they are sent when max of them are waiting, or when the actor has no more
+// messages to process, so the callers are woken together instead of one by
+// one, between the messages. Zero max means GOMAXPROCS, as no more callers
+// can run at the same time. It is called by the generated New functions
+func (ba *Actor) SetReplies(max int) {
+	if max <= 0 {
+		max = runtime.GOMAXPROCS(0)
+	}
+	ba.replies = &replies{max: max, pending: make([]reply, 0, max)}
+}
+
+// Reply sends the response of a synchronous method to the reference that
+// called it, batching it if the actor batches its responses. It is called by
+// the generated code
+func (ba *Actor) Reply(out chan interface{}, resp interface{}) {
+	r := ba.replies
+	if r == nil {
+		out <- resp
+		return
+	}
+	r.pending = append(r.pending, reply{out: out, resp: resp})
+	if len(r.pending) >= r.max {
+		ba.FlushReplies()
+	}
+}
+
+// FlushReplies sends the batched responses. It is called by the generated
+// code and the Scheduler when the actor has no more messages to process
+func (ba *Actor) FlushReplies() {
+	r := ba.replies
+	if r == nil {
+		return
+	}
+	for i, p := range r.pending {
+		p.out <- p.resp
+		r.pending[i] = reply{}
+	}
+	r.pending = r.pending[:0]
+}
@@ -0,0 +1,55 @@
+package actor
+
+import (
+	"testing"
+	"time"
+)
+
+// TestDeliverNotPolled checks that the actor doesn't wait for a response
+// that is never polled, and that it's dropped after the response timeout
+func TestDeliverNotPolled(t *testing.T) {
+	dropped := make(chan DroppedResponse, 1)
+	SetDroppedResponseHandler(func(dr DroppedResponse) { dropped <- dr })
+	defer SetDroppedResponseHandler(nil)
+
+	out := make(chan interface{}, 1)
+	done := make(chan struct{})
+	go func() {
+		Deliver("test", "Slow", out, 42, 10*time.Millisecond)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Deliver waited for the response to be polled")
+	}
+	select {
+	case dr := <-dropped:
+		if dr.Method != "Slow" || dr.Response != 42 {
+			t.Fatalf("got dropped response %v", dr)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the response wasn't dropped")
+	}
+	if len(out) != 0 {
+		t.Fatal("the dropped response can still be polled")
+	}
+}
+
+// TestDeliverPolled checks that a response polled in time isn't dropped
+func TestDeliverPolled(t *testing.T) {
+	dropped := make(chan DroppedResponse, 1)
+	SetDroppedResponseHandler(func(dr DroppedResponse) { dropped <- dr })
+	defer SetDroppedResponseHandler(nil)
+
+	out := make(chan interface{}, 1)
+	Deliver("test", "Fast", out, 42, 10*time.Millisecond)
+	if resp := <-out; resp != 42 {
+		t.Fatalf("got response %v", resp)
+	}
+	select {
+	case dr := <-dropped:
+		t.Fatalf("got dropped response %v", dr)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
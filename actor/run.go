@@ -0,0 +1,68 @@
+package actor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Go starts the System and binds it to ctx, so it can be part of a tree of
+// goroutines managed with contexts or errgroups. When ctx is done, or the
+// main loop of an actor panics and can't be restarted, the System is shut
+// down, giving the actors the grace period to stop. The panics are returned
+// by Wait instead of crashing the program. Go returns the error of Start
+func (s *System) Go(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	s.done = make(chan struct{})
+	for _, m := range s.members {
+		m.fail = func(err error) {
+			s.mu.Lock()
+			s.errs = append(s.errs, err)
+			s.mu.Unlock()
+			cancel()
+		}
+	}
+	if err := s.Start(); err != nil {
+		cancel()
+		close(s.done)
+		return err
+	}
+
+	go func() {
+		defer cancel()
+		<-ctx.Done()
+		shutdown, cancelShutdown := context.WithTimeout(context.Background(), s.grace)
+		defer cancelShutdown()
+		if err := s.Shutdown(shutdown); err != nil {
+			s.mu.Lock()
+			s.errs = append(s.errs, err)
+			s.mu.Unlock()
+		}
+		close(s.done)
+	}()
+	return nil
+}
+
+// Wait waits until the System started with Go is shut down. It returns the
+// panics of the actors, the errors of their post-stop hooks and the error of
+// the shutdown, joined, or nil if there were none
+func (s *System) Wait() error {
+	if s.done == nil {
+		return fmt.Errorf("system not started with Go")
+	}
+	<-s.done
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return errors.Join(s.errs...)
+}
+
+// Run starts the System with Go and waits until it's shut down. It is meant
+// to be run by an errgroup:
+//
+//	g.Go(func() error { return system.Run(ctx) })
+func (s *System) Run(ctx context.Context) error {
+	if err := s.Go(ctx); err != nil {
+		return err
+	}
+	return s.Wait()
+}
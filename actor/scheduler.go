@@ -0,0 +1,345 @@
+package actor
+
+import (
+	"fmt"
+	"hash/fnv"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// SchedulerBatch is the number of messages a scheduled actor processes
+// before the worker moves on to the next actor
+const SchedulerBatch = 64
+
+// Scheduler runs actors as tasks multiplexed onto a fixed number of worker
+// goroutines, instead of a goroutine per actor. An actor is queued in a
+// worker when it receives a message and processes the messages in its
+// mailbox, one at a time, until it's empty. Idle workers steal the actors
+// queued in the busy ones.
+//
+// A worker is blocked while an actor waits: for a synchronous call to other
+// actor, for the response of an asynchronous method to be polled, or while
+// it's suspended. Scheduled actors should not wait for other actors of the
+// same scheduler if all the workers can be waiting at the same time
+type Scheduler struct {
+	workers  []*worker
+	next     uint32
+	idle     int32
+	quit     chan struct{}
+	wg       sync.WaitGroup
+	mu       sync.Mutex
+	affinity map[*Actor]string
+}
+
+// worker is a goroutine of the scheduler with its queue of actors
+type worker struct {
+	mu        sync.Mutex
+	tasks     []*task
+	wake      chan struct{}
+	parked    int32
+	actors    int64
+	pinned    int64
+	processed int64
+	stolen    int64
+}
+
+// task is an actor run by a scheduler. It's queued in its home worker,
+// from which other workers can steal it
+type task struct {
+	inst     Instance
+	queue    Mailbox
+	dispatch func(interface{}) bool
+	home     *worker
+	pinned   bool
+	state    int32
+	stopped  bool
+	untrack  func()
+}
+
+// Task states
+const (
+	taskIdle int32 = iota
+	taskQueued
+	taskDone
+)
+
+// NewScheduler creates a scheduler with the given number of workers and
+// launches them. Zero workers means GOMAXPROCS
+func NewScheduler(workers int) *Scheduler {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	s := &Scheduler{quit: make(chan struct{}), affinity: make(map[*Actor]string)}
+	for i := 0; i < workers; i++ {
+		s.workers = append(s.workers, &worker{wake: make(chan struct{}, 1)})
+	}
+	s.wg.Add(workers)
+	for _, w := range s.workers {
+		go s.work(w)
+	}
+	return s
+}
+
+// Close stops the workers once they finish the actors they are running. The
+// actors should be stopped before: the ones left in the queues aren't run
+func (s *Scheduler) Close() {
+	close(s.quit)
+	s.wg.Wait()
+}
+
+// Affinity declares a group of actors that always run in the same worker,
+// the one of the shard key, so they share its CPU caches when they work
+// together. Pinned actors aren't stolen by other workers. It must be called
+// before the actors are started, and actors can only be in one group
+func (s *Scheduler) Affinity(key string, insts ...Instance) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, inst := range insts {
+		s.affinity[inst.base()] = key
+	}
+}
+
+// shard returns the worker of a shard key
+func (s *Scheduler) shard(key string) *worker {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return s.workers[h.Sum32()%uint32(len(s.workers))]
+}
+
+// Schedule makes the scheduler run the actor. It is called by the generated
+// StartOn methods and by the System, after Prepare
+func (s *Scheduler) Schedule(inst Instance) {
+	ba := inst.base()
+	if ba.dispatch == nil {
+		panic(fmt.Sprintf("%T: generated without support for schedulers", inst))
+	}
+	t := &task{
+		inst:     inst,
+		queue:    ba.mailbox(),
+		dispatch: ba.dispatch,
+		untrack:  Track(ba.name, inst),
+	}
+	s.mu.Lock()
+	key, ok := s.affinity[ba]
+	delete(s.affinity, ba)
+	s.mu.Unlock()
+	if ok {
+		t.home = s.shard(key)
+		t.pinned = true
+		atomic.AddInt64(&t.home.pinned, 1)
+	} else {
+		t.home = s.workers[atomic.AddUint32(&s.next, 1)%uint32(len(s.workers))]
+	}
+	atomic.AddInt64(&t.home.actors, 1)
+	atomic.StoreInt64(&ba.goid, -1)
+	ba.task.Store(&scheduled{s, t})
+	if len(ba.In) > 0 && atomic.CompareAndSwapInt32(&t.state, taskIdle, taskQueued) {
+		s.push(t)
+	}
+}
+
+// scheduled is stored in the actor when it's run by a scheduler
+type scheduled struct {
+	s *Scheduler
+	t *task
+}
+
+// Notify queues the actor in its scheduler, if it's run by one and it isn't
+// queued already. It must be called after sending a message to the In
+// channel. The generated code and the runtime call it after every message
+// they send
+func (ba *Actor) Notify() {
+	if ba.task == nil {
+		return
+	}
+	sc, _ := ba.task.Load().(*scheduled)
+	if sc != nil && atomic.CompareAndSwapInt32(&sc.t.state, taskIdle, taskQueued) {
+		sc.s.push(sc.t)
+	}
+}
+
+// push queues a task in its home worker and wakes it, and an idle worker
+// that can steal it if the home worker is busy
+func (s *Scheduler) push(t *task) {
+	w := t.home
+	w.mu.Lock()
+	w.tasks = append(w.tasks, t)
+	w.mu.Unlock()
+	w.signal()
+	if atomic.LoadInt32(&s.idle) > 0 {
+		for _, other := range s.workers {
+			if other != w && atomic.LoadInt32(&other.parked) == 1 {
+				other.signal()
+				break
+			}
+		}
+	}
+}
+
+func (w *worker) signal() {
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+}
+
+// pop takes the first task of the worker's queue
+func (w *worker) pop() *task {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.tasks) == 0 {
+		return nil
+	}
+	t := w.tasks[0]
+	w.tasks[0] = nil
+	w.tasks = w.tasks[1:]
+	return t
+}
+
+// steal takes half of the tasks that aren't pinned queued in other worker,
+// starting at the end of its queue, and returns one of them
+func (s *Scheduler) steal(thief *worker) *task {
+	for _, w := range s.workers {
+		if w == thief {
+			continue
+		}
+		w.mu.Lock()
+		n := (len(w.tasks) + 1) / 2
+		var stolen []*task
+		kept := w.tasks[:0]
+		for i := len(w.tasks) - 1; i >= 0; i-- {
+			if t := w.tasks[i]; len(stolen) < n && !t.pinned {
+				stolen = append(stolen, t)
+				w.tasks[i] = nil
+			}
+		}
+		if len(stolen) == 0 {
+			w.mu.Unlock()
+			continue
+		}
+		for _, t := range w.tasks {
+			if t != nil {
+				kept = append(kept, t)
+			}
+		}
+		for i := len(kept); i < len(w.tasks); i++ {
+			w.tasks[i] = nil
+		}
+		w.tasks = kept
+		w.mu.Unlock()
+
+		atomic.AddInt64(&thief.stolen, int64(len(stolen)))
+		thief.mu.Lock()
+		thief.tasks = append(thief.tasks, stolen[1:]...)
+		thief.mu.Unlock()
+		return stolen[0]
+	}
+	return nil
+}
+
+// work runs the tasks of a worker, stealing tasks when its queue is empty
+// and waiting when there are none to steal
+func (s *Scheduler) work(w *worker) {
+	defer s.wg.Done()
+	id := goid()
+	for {
+		t := w.pop()
+		if t == nil {
+			t = s.steal(w)
+		}
+		if t == nil {
+			// a task pushed after the worker is marked as parked wakes it
+			atomic.StoreInt32(&w.parked, 1)
+			atomic.AddInt32(&s.idle, 1)
+			if t = s.steal(w); t == nil {
+				select {
+				case <-w.wake:
+				case <-s.quit:
+					return
+				}
+			}
+			atomic.AddInt32(&s.idle, -1)
+			atomic.StoreInt32(&w.parked, 0)
+			if t == nil {
+				continue
+			}
+		}
+		atomic.AddInt64(&w.processed, int64(s.run(t, id)))
+		select {
+		case <-s.quit:
+			return
+		default:
+		}
+	}
+}
+
+// run processes up to SchedulerBatch messages of the task and returns how
+// many it processed. Tasks that have more messages are queued again, and
+// stopped tasks without messages are removed
+func (s *Scheduler) run(t *task, id int64) int {
+	ba := t.inst.base()
+	atomic.StoreInt64(&ba.goid, id)
+	for i := 0; i < SchedulerBatch; i++ {
+		msg, ok := t.queue.TryNext()
+		if !ok {
+			ba.FlushReplies()
+			atomic.StoreInt64(&ba.goid, -1)
+			if t.stopped {
+				Log.Println("No more messages. Exiting")
+				atomic.StoreInt32(&t.state, taskDone)
+				ba.task.Store((*scheduled)(nil))
+				atomic.AddInt64(&t.home.actors, -1)
+				if t.pinned {
+					atomic.AddInt64(&t.home.pinned, -1)
+				}
+				RunPostStop(ba.name, t.inst)
+				t.untrack()
+				return i
+			}
+			// the messages sent before the task is idle are not notified
+			atomic.StoreInt32(&t.state, taskIdle)
+			if len(ba.In) > 0 && atomic.CompareAndSwapInt32(&t.state, taskIdle, taskQueued) {
+				s.push(t)
+			}
+			return i
+		}
+		if t.dispatch(msg) {
+			t.stopped = true
+		}
+	}
+	ba.FlushReplies()
+	atomic.StoreInt64(&ba.goid, -1)
+	s.push(t)
+	return SchedulerBatch
+}
+
+// WorkerStats are the metrics of a worker of a Scheduler, used to observe
+// how balanced the shards are
+type WorkerStats struct {
+	// Actors is the number of running actors whose home is the worker
+	Actors int64
+	// Pinned is the number of them pinned by an affinity group
+	Pinned int64
+	// Queued is the number of actors waiting in the worker's queue
+	Queued int
+	// Processed is the number of messages processed by the worker
+	Processed int64
+	// Stolen is the number of actors the worker stole from other workers
+	Stolen int64
+}
+
+// Stats returns the metrics of the scheduler's workers
+func (s *Scheduler) Stats() []WorkerStats {
+	stats := make([]WorkerStats, len(s.workers))
+	for i, w := range s.workers {
+		w.mu.Lock()
+		stats[i].Queued = len(w.tasks)
+		w.mu.Unlock()
+		stats[i].Actors = atomic.LoadInt64(&w.actors)
+		stats[i].Pinned = atomic.LoadInt64(&w.pinned)
+		stats[i].Processed = atomic.LoadInt64(&w.processed)
+		stats[i].Stolen = atomic.LoadInt64(&w.stolen)
+	}
+	return stats
+}
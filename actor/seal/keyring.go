@@ -0,0 +1,85 @@
+package seal
+
+import (
+	"context"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// Keyring is a KeyProvider that keeps the master keys in the process. The
+// current key encrypts the new data keys, and all of them decrypt the data
+// keys encrypted before
+type Keyring struct {
+	mu      sync.RWMutex
+	current string
+	keys    map[string]cipher.AEAD
+}
+
+// NewKeyring returns a Keyring with the master key current, of 256 bits
+func NewKeyring(current string, key []byte) (*Keyring, error) {
+	kr := &Keyring{keys: make(map[string]cipher.AEAD)}
+	if err := kr.Rotate(current, key); err != nil {
+		return nil, err
+	}
+	return kr, nil
+}
+
+// Rotate adds a master key of 256 bits and makes it the current one. The
+// previous keys still decrypt the data keys encrypted with them
+func (kr *Keyring) Rotate(id string, key []byte) error {
+	if len(key) != 32 {
+		return fmt.Errorf("seal: master key %s of %d bytes, expected 32", id, len(key))
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return err
+	}
+	kr.mu.Lock()
+	defer kr.mu.Unlock()
+	if _, ok := kr.keys[id]; ok {
+		return fmt.Errorf("seal: master key %s already exists", id)
+	}
+	kr.keys[id] = gcm
+	kr.current = id
+	return nil
+}
+
+// DataKey returns a new random data key, encrypted with the current master
+// key
+func (kr *Keyring) DataKey(ctx context.Context) ([]byte, string, []byte, error) {
+	kr.mu.RLock()
+	id, gcm := kr.current, kr.keys[kr.current]
+	kr.mu.RUnlock()
+
+	key := make([]byte, 32)
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(key); err != nil {
+		return nil, "", nil, err
+	}
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, "", nil, err
+	}
+	return key, id, gcm.Seal(nonce, nonce, key, []byte(id)), nil
+}
+
+// Unwrap decrypts a data key encrypted with the master key id
+func (kr *Keyring) Unwrap(ctx context.Context, id string, wrapped []byte) ([]byte, error) {
+	kr.mu.RLock()
+	gcm, ok := kr.keys[id]
+	kr.mu.RUnlock()
+	if !ok {
+		return nil, errors.New("unknown master key")
+	}
+	if len(wrapped) < gcm.NonceSize() {
+		return nil, errors.New("invalid data key")
+	}
+	nonce := wrapped[:gcm.NonceSize()]
+	key, err := gcm.Open(nil, nonce, wrapped[len(nonce):], []byte(id))
+	if err != nil {
+		return nil, errors.New("invalid data key")
+	}
+	return key, nil
+}
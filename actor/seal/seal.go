@@ -0,0 +1,181 @@
+// Package seal encrypts the data the actors persist, like the archives of
+// System.Dump and the payloads of the outbox, so the state of the actors
+// that handle personal data is encrypted at rest.
+//
+// It uses envelope encryption: each sealed value is encrypted with AES-GCM
+// under a new data key, which is stored with it encrypted by a master key.
+// The master keys are kept by a KeyProvider: a Keyring in the process, or
+// an adapter of a key management service, whose generate data key and
+// decrypt operations map to DataKey and Unwrap. Rotating the master key
+// only needs a new key in the provider: the values sealed before keep the
+// ID of the key that can open them.
+package seal
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// KeyProvider gives the data keys and keeps the master keys that encrypt
+// them
+type KeyProvider interface {
+	// DataKey returns a new 256 bits data key, the ID of the master key
+	// and the data key encrypted with it
+	DataKey(ctx context.Context) (key []byte, keyID string, wrapped []byte, err error)
+	// Unwrap decrypts a data key encrypted with the master key keyID
+	Unwrap(ctx context.Context, keyID string, wrapped []byte) ([]byte, error)
+}
+
+// ErrNotSealed is returned by Open for the data that wasn't sealed
+var ErrNotSealed = errors.New("seal: data not sealed")
+
+// magic starts the sealed values, and identifies the version of the format
+const magic = "GAS1"
+
+// Seal encrypts plaintext with a new data key of kp. aad is authenticated
+// but not encrypted: Open fails if it's not given the same. The result
+// contains the ID of the master key, the encrypted data key, the nonce and
+// the ciphertext
+func Seal(ctx context.Context, kp KeyProvider, plaintext, aad []byte) ([]byte, error) {
+	key, keyID, wrapped, err := kp.DataKey(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("seal: data key: %w", err)
+	}
+	if len(keyID) > 0xffff || len(wrapped) > 0xffff {
+		return nil, errors.New("seal: key ID or encrypted data key too long")
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	var header bytes.Buffer
+	header.WriteString(magic)
+	binary.Write(&header, binary.BigEndian, uint16(len(keyID)))
+	header.WriteString(keyID)
+	binary.Write(&header, binary.BigEndian, uint16(len(wrapped)))
+	header.Write(wrapped)
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("seal: nonce: %w", err)
+	}
+	header.Write(nonce)
+
+	sealed := header.Bytes()
+	return gcm.Seal(sealed, nonce, plaintext, additional(sealed, aad)), nil
+}
+
+// Open decrypts a value sealed by Seal with the same aad, unwrapping its
+// data key with kp. It returns ErrNotSealed if data wasn't sealed
+func Open(ctx context.Context, kp KeyProvider, data, aad []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, []byte(magic)) {
+		return nil, ErrNotSealed
+	}
+	r := bytes.NewReader(data[len(magic):])
+	keyID, err := readField(r)
+	if err != nil {
+		return nil, err
+	}
+	wrapped, err := readField(r)
+	if err != nil {
+		return nil, err
+	}
+	key, err := kp.Unwrap(ctx, string(keyID), wrapped)
+	if err != nil {
+		return nil, fmt.Errorf("seal: data key of master key %s: %w", keyID, err)
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(r, nonce); err != nil {
+		return nil, errors.New("seal: truncated data")
+	}
+	header := data[:len(data)-r.Len()]
+	plaintext, err := gcm.Open(nil, nonce, data[len(header):], additional(header, aad))
+	if err != nil {
+		return nil, errors.New("seal: data corrupted or sealed with other additional data")
+	}
+	return plaintext, nil
+}
+
+// readField reads a field of the header prefixed with its length
+func readField(r *bytes.Reader) ([]byte, error) {
+	var n uint16
+	if err := binary.Read(r, binary.BigEndian, &n); err != nil {
+		return nil, errors.New("seal: truncated data")
+	}
+	field := make([]byte, n)
+	if _, err := io.ReadFull(r, field); err != nil {
+		return nil, errors.New("seal: truncated data")
+	}
+	return field, nil
+}
+
+// additional returns the data authenticated by AES-GCM: the header, so the
+// key ID and the data key can't be swapped, and the aad of the caller
+func additional(header, aad []byte) []byte {
+	return append(append([]byte(nil), header...), aad...)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("seal: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// Writer seals the data written to it, and writes it to the underlying
+// writer when it's closed. It is meant to seal the archives of System.Dump
+type Writer struct {
+	ctx context.Context
+	kp  KeyProvider
+	w   io.Writer
+	buf bytes.Buffer
+}
+
+// NewWriter returns a Writer that seals the data written to it with kp and
+// writes it to w
+func NewWriter(ctx context.Context, kp KeyProvider, w io.Writer) *Writer {
+	return &Writer{ctx: ctx, kp: kp, w: w}
+}
+
+func (w *Writer) Write(p []byte) (int, error) {
+	return w.buf.Write(p)
+}
+
+// Close seals the data written and writes it. It doesn't close the
+// underlying writer
+func (w *Writer) Close() error {
+	sealed, err := Seal(w.ctx, w.kp, w.buf.Bytes(), nil)
+	if err != nil {
+		return err
+	}
+	w.buf.Reset()
+	_, err = w.w.Write(sealed)
+	return err
+}
+
+// NewReader reads the data sealed by a Writer from r and returns a reader of
+// the data opened with kp. It is meant to pass the sealed archives to
+// System.Restore
+func NewReader(ctx context.Context, kp KeyProvider, r io.Reader) (io.Reader, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := Open(ctx, kp, data, nil)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(plaintext), nil
+}
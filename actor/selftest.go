@@ -0,0 +1,81 @@
+package actor
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Statuses of the actors in a self-test
+const (
+	SelfTestOK        = "ok"
+	SelfTestFailed    = "failed"
+	SelfTestSuspended = "suspended"
+	SelfTestStopped   = "stopped"
+)
+
+// SelfTestResult is the result of the canary message sent to an actor by
+// SelfTest. The suspended and stopped actors aren't sent one
+type SelfTestResult struct {
+	Name    string        `json:"name"`
+	Status  string        `json:"status"`
+	Latency time.Duration `json:"latency"`
+	Error   string        `json:"error,omitempty"`
+}
+
+// canaryRequest is the canary message of a self-test. It only proves that
+// the actor processes its mailbox
+type canaryRequest struct {
+	reply chan struct{}
+}
+
+func (req canaryRequest) execute(inst Instance) {
+	close(req.reply)
+}
+
+// SelfTest sends a canary message to each running actor of the system, at
+// the same time, and waits until they process it, after the messages they
+// received before, or until ctx is done. It returns the round trip of each
+// actor, in the order they were added. The actors whose canary isn't
+// processed in time fail
+func (s *System) SelfTest(ctx context.Context) []SelfTestResult {
+	s.mu.Lock()
+	results := make([]SelfTestResult, len(s.members))
+	var tested []int
+	for i, m := range s.members {
+		results[i] = SelfTestResult{Name: m.name, Status: SelfTestStopped}
+		switch {
+		case !m.started || stopped(m.inst.base()):
+		case m.resume != nil:
+			results[i].Status = SelfTestSuspended
+		default:
+			tested = append(tested, i)
+		}
+	}
+	s.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for _, i := range tested {
+		wg.Add(1)
+		go func(r *SelfTestResult, b *Actor) {
+			defer wg.Done()
+			start := time.Now()
+			req := canaryRequest{reply: make(chan struct{})}
+			select {
+			case b.In <- req:
+				b.Notify()
+			case <-ctx.Done():
+				r.Status, r.Error = SelfTestFailed, "canary not sent: "+ctx.Err().Error()
+				return
+			}
+			select {
+			case <-req.reply:
+				r.Status, r.Latency = SelfTestOK, time.Since(start)
+			case <-ctx.Done():
+				r.Status, r.Error = SelfTestFailed, "canary not processed: "+ctx.Err().Error()
+			}
+		}(&results[i], s.members[i].inst.base())
+	}
+	wg.Wait()
+	return results
+}
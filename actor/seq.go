@@ -0,0 +1,127 @@
+package actor
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// Seq is the sequence token of an asynchronous write, returned by the
+// methods listed in the seq tag. The tokens of an actor increase
+// monotonically, and WaitFor on its references waits until the write of a
+// token was processed, so a later read reflects it. The zero Seq is always
+// reached
+type Seq uint64
+
+// sequencer keeps the tokens of an actor: the last one given and the
+// highest one that, with all the ones before it, was processed
+type sequencer struct {
+	next uint64
+	done uint64
+	// ahead are the tokens processed before some lower one, as the writes
+	// of different goroutines can reach the mailbox out of order. It's only
+	// used by the goroutine running the actor
+	ahead map[Seq]bool
+	// waiting is the number of goroutines in WaitFor. The actor only takes
+	// the lock to wake them when there are some
+	waiting int32
+	mu      sync.Mutex
+	changed chan struct{}
+	stopped bool
+}
+
+// EnableSeq gives the actor its sequence tokens. It is called by SetHandler,
+// and by the generated fakes of the actors with a seq tag
+func (ba *Actor) EnableSeq() {
+	ba.seq = &sequencer{ahead: make(map[Seq]bool)}
+}
+
+// NextSeq returns the token of a write that is about to be sent to the
+// actor. It is called by the generated references, and returns zero if the
+// actor has no tokens
+func (ba *Actor) NextSeq() Seq {
+	if ba.seq == nil {
+		return 0
+	}
+	return Seq(atomic.AddUint64(&ba.seq.next, 1))
+}
+
+// SeqDone records that the write of a token was processed, or discarded as
+// a dead letter, and wakes the callers waiting for it. It is called by the
+// generated code from the goroutine running the actor
+func (ba *Actor) SeqDone(seq Seq) {
+	s := ba.seq
+	if s == nil || seq == 0 {
+		return
+	}
+	done := Seq(atomic.LoadUint64(&s.done))
+	if seq != done+1 {
+		s.ahead[seq] = true
+		return
+	}
+	for done++; s.ahead[done+1]; done++ {
+		delete(s.ahead, done+1)
+	}
+	atomic.StoreUint64(&s.done, uint64(done))
+	if atomic.LoadInt32(&s.waiting) > 0 {
+		s.mu.Lock()
+		if s.changed != nil {
+			close(s.changed)
+			s.changed = nil
+		}
+		s.mu.Unlock()
+	}
+}
+
+// CloseSeq wakes the callers waiting for tokens that won't be processed,
+// once the actor stopped. It is called by RunPostStop, and by the generated
+// fakes of the actors with a seq tag
+func (ba *Actor) CloseSeq() {
+	s := ba.seq
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.stopped = true
+	if s.changed != nil {
+		close(s.changed)
+		s.changed = nil
+	}
+	s.mu.Unlock()
+}
+
+// WaitFor waits until the actor processed the write of the token seq and
+// the ones before it. It returns an error wrapping ErrStopped if the actor
+// stopped before, or the error of ctx if it's done first. It is called by
+// the generated references
+func (ba *Actor) WaitFor(ctx context.Context, seq Seq) error {
+	s := ba.seq
+	if s == nil || seq == 0 {
+		return nil
+	}
+	atomic.AddInt32(&s.waiting, 1)
+	defer atomic.AddInt32(&s.waiting, -1)
+	for {
+		s.mu.Lock()
+		if Seq(atomic.LoadUint64(&s.done)) >= seq {
+			s.mu.Unlock()
+			return nil
+		}
+		if s.stopped {
+			s.mu.Unlock()
+			return fmt.Errorf("%s: token %d: %w", ba.name, seq, ErrStopped)
+		}
+		if s.changed == nil {
+			s.changed = make(chan struct{})
+		}
+		changed := s.changed
+		s.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
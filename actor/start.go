@@ -0,0 +1,128 @@
+package actor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+)
+
+// Errors returned when an actor can't be started
+var (
+	ErrNotCreated = errors.New("actor not created with its New function")
+	ErrStarted    = errors.New("actor already started")
+	ErrStopped    = errors.New("actor stopped")
+)
+
+// ErrKilled is the panic of the dead letters of the messages discarded
+// because the actor was killed
+var ErrKilled = errors.New("actor killed")
+
+// PreStart is the pre-start hook. It is executed before the actor's main
+// loop is launched, in the goroutine starting the actor. Actors that need to
+// verify their state, or acquire resources, before processing messages
+// should override it and return an error if they can't be started
+func (ba *Actor) PreStart() error {
+	return nil
+}
+
+// Prepare verifies that the actor can be started and runs its pre-start
+// hook. It returns an error if the actor wasn't created with its New
+// function, if it's already started or stopped, or if the hook fails. It is
+// called by the generated Start methods and by the System before launching
+// the main loop, which should only be launched if it returns nil
+func Prepare(name string, inst Instance) error {
+	ba := inst.base()
+	if ba.In == nil || ba.StopCh == nil || ba.loop == nil {
+		return fmt.Errorf("%s: %w", name, ErrNotCreated)
+	}
+	if atomic.LoadInt32(&ba.stopping) == 1 {
+		return fmt.Errorf("%s: %w", name, ErrStopped)
+	}
+	if !atomic.CompareAndSwapInt32(&ba.started, 0, 1) {
+		return fmt.Errorf("%s: %w", name, ErrStarted)
+	}
+	if err := inst.(interface{ PreStart() error }).PreStart(); err != nil {
+		atomic.StoreInt32(&ba.started, 0)
+		return fmt.Errorf("%s: pre-start hook: %w", name, err)
+	}
+	return nil
+}
+
+// Started returns true if the actor's main loop was launched
+func (ba *Actor) Started() bool {
+	return atomic.LoadInt32(&ba.started) == 1
+}
+
+// BeginStop records that the actor is being stopped. It returns true only
+// the first time it's called, so the generated Stop methods send a single
+// stop request however many times, and from however many goroutines, they
+// are called
+func (ba *Actor) BeginStop() bool {
+	return atomic.CompareAndSwapInt32(&ba.stopping, 0, 1)
+}
+
+// PostStop is the post-stop hook. It is executed by the actor when it exits,
+// after it processes the messages received before it was stopped. Actors
+// holding connections, files or other resources should override it to
+// release them. ctx is the one passed to Close, or a background context when
+// the actor is stopped with Stop
+func (ba *Actor) PostStop(ctx context.Context) error {
+	return nil
+}
+
+// closing is how the actor was stopped: the context passed to the post-stop
+// hook, the channel that receives its result if the actor was stopped with
+// Close, and whether it was killed
+type closing struct {
+	ctx    context.Context
+	done   chan error
+	killed int32
+}
+
+// SetClosing records the context and the result channel of the stop request
+// being processed. It is called by the generated code
+func (ba *Actor) SetClosing(ctx context.Context, done chan error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if ba.closing == nil {
+		ba.closing = new(closing)
+	}
+	ba.closing.ctx = ctx
+	ba.closing.done = done
+}
+
+// RunPostStop wakes the callers waiting for sequence tokens that won't be
+// processed, runs the post-stop hook of the actor and sends its result to
+// the caller of Close, if it was stopped with it. Errors are also written to
+// the logger. It is called by the generated code when the receive loop exits
+func RunPostStop(name string, inst Instance) {
+	inst.base().CloseSeq()
+	c := inst.base().closing
+	if c == nil {
+		c = &closing{ctx: context.Background()}
+	}
+	err := inst.(interface{ PostStop(context.Context) error }).PostStop(c.ctx)
+	if err != nil {
+		err = fmt.Errorf("%s: post-stop hook: %w", name, err)
+		Log.Println(err)
+	}
+	if c.done != nil {
+		c.done <- err
+	}
+}
+
+// SetKilled records that the actor was killed, so it discards the messages
+// waiting in its mailbox. It is called by the generated Kill methods
+func (ba *Actor) SetKilled() {
+	if ba.closing == nil {
+		ba.closing = &closing{ctx: context.Background()}
+	}
+	atomic.StoreInt32(&ba.closing.killed, 1)
+}
+
+// Killed returns true if the actor was killed
+func (ba *Actor) Killed() bool {
+	return ba.closing != nil && atomic.LoadInt32(&ba.closing.killed) == 1
+}
@@ -0,0 +1,147 @@
+package stream
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// ErrEndpointStopped is the error of a Link whose producer or consumer
+// actor was stopped before the end of the stream
+var ErrEndpointStopped = errors.New("stream endpoint stopped")
+
+// Producer is the producing end of a credit-based stream between two
+// actors. The references of the actors return it for the methods listed in
+// the producer tag, which are called by the actor to get the elements
+type Producer struct {
+	elem  reflect.Type
+	grant func(l *Link, n int) bool
+}
+
+// NewProducer creates the Producer of the elements of type elem of an
+// actor. grant sends to the actor the credits of l, returning false if the
+// actor is stopped. It is called by the generated code
+func NewProducer(elem reflect.Type, grant func(l *Link, n int) bool) *Producer {
+	return &Producer{elem: elem, grant: grant}
+}
+
+// Elem returns the type of the elements of the producer
+func (p *Producer) Elem() reflect.Type {
+	return p.elem
+}
+
+// Consumer is the consuming end of a credit-based stream between two
+// actors. The references of the actors return it for the methods listed in
+// the consumer tag, which are called by the actor with each element
+type Consumer struct {
+	elem     reflect.Type
+	capacity int
+	deliver  func(l *Link, v interface{}, end bool) bool
+}
+
+// NewConsumer creates the Consumer of the elements of type elem of an actor
+// with a mailbox of the given capacity. deliver sends to the actor an
+// element of l, or the end of the stream, returning false if the actor is
+// stopped. It is called by the generated code
+func NewConsumer(elem reflect.Type, capacity int, deliver func(l *Link, v interface{}, end bool) bool) *Consumer {
+	return &Consumer{elem: elem, capacity: capacity, deliver: deliver}
+}
+
+// Elem returns the type of the elements of the consumer
+func (c *Consumer) Elem() reflect.Type {
+	return c.elem
+}
+
+// Link is a stream between a producer and a consumer actor with flow
+// control: the producer sends elements only while it has credits, and the
+// consumer grants them back as it processes the elements. There are never
+// more than window elements in the mailbox of the consumer, so it can't
+// overflow with large transfers, and the producer doesn't produce more
+// elements than the consumer can take
+type Link struct {
+	producer *Producer
+	consumer *Consumer
+	window   int
+	// closed is set by the producer's goroutine after sending the end
+	closed bool
+	// consumed is the number of elements processed by the consumer's
+	// goroutine since its last grant
+	consumed int
+
+	once sync.Once
+	done chan struct{}
+	err  error
+}
+
+// Connect starts a stream from p to c, granting window credits to the
+// producer. It returns an error if the elements of p and c have different
+// types, or if the window doesn't fit in the mailbox of the consumer
+func Connect(p *Producer, c *Consumer, window int) (*Link, error) {
+	if p.elem != c.elem {
+		return nil, fmt.Errorf("stream: producer of %v connected to a consumer of %v", p.elem, c.elem)
+	}
+	if window < 1 || window > c.capacity {
+		return nil, fmt.Errorf("stream: window %d out of the range 1..%d, the capacity of the mailbox of the consumer", window, c.capacity)
+	}
+	l := &Link{producer: p, consumer: c, window: window, done: make(chan struct{})}
+	if !p.grant(l, window) {
+		return nil, fmt.Errorf("stream: producer: %w", ErrEndpointStopped)
+	}
+	return l, nil
+}
+
+// Done returns a channel closed when the consumer has processed the last
+// element of the stream, or when one of the actors is stopped
+func (l *Link) Done() <-chan struct{} {
+	return l.done
+}
+
+// Err returns an error wrapping ErrEndpointStopped if the stream was
+// interrupted because one of the actors was stopped, once Done is closed
+func (l *Link) Err() error {
+	<-l.done
+	return l.err
+}
+
+// Produce sends to the consumer up to n elements returned by next, and the
+// end of the stream when next returns false. It is called by the generated
+// code of the producer, in the goroutine of the actor, when it receives
+// credits
+func (l *Link) Produce(n int, next func() (interface{}, bool)) {
+	for i := 0; i < n && !l.closed; i++ {
+		v, ok := next()
+		l.closed = !ok
+		if !l.consumer.deliver(l, v, !ok) {
+			l.closed = true
+			l.finish(fmt.Errorf("stream: consumer: %w", ErrEndpointStopped))
+		}
+	}
+}
+
+// Consumed records that the consumer processed an element, or the end of
+// the stream. Every half window it grants the credits of the elements
+// processed to the producer. It is called by the generated code of the
+// consumer, in the goroutine of the actor
+func (l *Link) Consumed(end bool) {
+	if end {
+		l.finish(nil)
+		return
+	}
+	l.consumed++
+	if l.consumed < (l.window+1)/2 {
+		return
+	}
+	n := l.consumed
+	l.consumed = 0
+	if !l.producer.grant(l, n) {
+		l.finish(fmt.Errorf("stream: producer: %w", ErrEndpointStopped))
+	}
+}
+
+func (l *Link) finish(err error) {
+	l.once.Do(func() {
+		l.err = err
+		close(l.done)
+	})
+}
@@ -0,0 +1,93 @@
+// Package stream builds processing pipelines out of actors. A pipeline is a
+// Source followed by any number of Flows and a Sink. Every stage runs in its
+// own goroutine, processing one element at a time, and stages are connected
+// by bounded buffers: when a stage can't keep up the previous one blocks
+// (backpressure).
+package stream
+
+// DefaultBufferCap is the default capacity of the buffers between stages
+const DefaultBufferCap = 16
+
+// Source produces the elements of a stream. It returns false when there are
+// no more elements
+type Source func() (interface{}, bool)
+
+// Flow transforms the elements of a stream
+type Flow func(interface{}) interface{}
+
+// Sink consumes the elements of a stream
+type Sink func(interface{})
+
+// Stream is a pipeline under construction
+type Stream struct {
+	src   Source
+	flows []Flow
+	cap   int
+}
+
+// From creates a stream whose elements are produced by src
+func From(src Source) *Stream {
+	return &Stream{src: src, cap: DefaultBufferCap}
+}
+
+// Slice returns a Source that produces the given elements
+func Slice(elems ...interface{}) Source {
+	var i int
+	return func() (interface{}, bool) {
+		if i == len(elems) {
+			return nil, false
+		}
+		i++
+		return elems[i-1], true
+	}
+}
+
+// Buffer sets the capacity of the buffers between stages
+func (s *Stream) Buffer(n int) *Stream {
+	s.cap = n
+	return s
+}
+
+// Via adds a Flow stage to the stream
+func (s *Stream) Via(f Flow) *Stream {
+	s.flows = append(s.flows, f)
+	return s
+}
+
+// To sends the elements of the stream to sink. It returns when the source
+// is exhausted and all the elements have been consumed
+func (s *Stream) To(sink Sink) {
+	src := make(chan interface{}, s.cap)
+	go func() {
+		defer close(src)
+		for {
+			v, ok := s.src()
+			if !ok {
+				return
+			}
+			src <- v
+		}
+	}()
+
+	ch := src
+	for _, f := range s.flows {
+		ch = via(ch, f, s.cap)
+	}
+
+	for v := range ch {
+		sink(v)
+	}
+}
+
+// via starts a goroutine that applies f to the elements read from in
+// and returns the channel where the results are written
+func via(in chan interface{}, f Flow, cap int) chan interface{} {
+	out := make(chan interface{}, cap)
+	go func() {
+		defer close(out)
+		for v := range in {
+			out <- f(v)
+		}
+	}()
+	return out
+}
@@ -0,0 +1,228 @@
+package actor
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Strategy is how a Supervisor restarts its children when one of them fails
+type Strategy int
+
+const (
+	// OneForOne restarts only the child that failed
+	OneForOne Strategy = iota
+)
+
+func (st Strategy) String() string {
+	switch st {
+	case OneForOne:
+		return "one-for-one"
+	}
+	return fmt.Sprintf("Strategy(%d)", int(st))
+}
+
+// Supervisor runs a group of actors, its children, and restarts the ones
+// whose main loop panics. It allows up to maxRestarts restarts of its
+// children in any period of length window: one more and it gives up. A
+// Supervisor can be the child of another one, forming a tree: when it gives
+// up the failure is passed to its parent, which restarts the failed actor
+// if its own restarts allow it, or gives up in turn. When the root gives up
+// all the actors of the tree are stopped, and Err returns the failure.
+//
+// As with WithAutoRestart, a restart relaunches the main loop of the same
+// actor after executing its init method again, so the references to it
+// stay valid. The message that caused the panic is lost
+type Supervisor struct {
+	name        string
+	strategy    Strategy
+	maxRestarts int
+	window      time.Duration
+	backoff     BackoffPolicy
+	parent      *Supervisor
+
+	mu       sync.Mutex
+	children []supervised
+	restarts []time.Time
+	started  bool
+	stopping bool
+	done     chan struct{}
+	err      error
+}
+
+// supervised is a child of a Supervisor: an actor or another Supervisor
+type supervised struct {
+	name string
+	inst Instance
+	sup  *Supervisor
+}
+
+// NewSupervisor creates a Supervisor without children
+func NewSupervisor(name string, strategy Strategy, maxRestarts int, window time.Duration) *Supervisor {
+	return &Supervisor{name: name, strategy: strategy, maxRestarts: maxRestarts, window: window, done: make(chan struct{})}
+}
+
+// SetBackoff sets how long the Supervisor waits before each restart of a
+// child, by the number of restarts in the window. By default it doesn't wait
+func (s *Supervisor) SetBackoff(backoff BackoffPolicy) {
+	s.backoff = backoff
+}
+
+// Add adds an actor to the children of the Supervisor, before it's
+// started. The actor should not be started: the Supervisor will start it
+func (s *Supervisor) Add(name string, inst Instance) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.children = append(s.children, supervised{name: s.name + "/" + name, inst: inst})
+}
+
+// Supervise adds a Supervisor to the children of s, before s is started.
+// Its failures are handled by s
+func (s *Supervisor) Supervise(child *Supervisor) {
+	child.mu.Lock()
+	child.parent = s
+	child.mu.Unlock()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.children = append(s.children, supervised{name: child.name, sup: child})
+}
+
+// Start starts the children in the order they were added. If a child can't
+// be started the ones already started are stopped. It fails if the strategy
+// of the Supervisor is unknown
+func (s *Supervisor) Start() error {
+	s.mu.Lock()
+	if s.started {
+		s.mu.Unlock()
+		return fmt.Errorf("%s: %w", s.name, ErrStarted)
+	}
+	if s.strategy != OneForOne {
+		s.mu.Unlock()
+		return fmt.Errorf("%s: unknown strategy %v", s.name, s.strategy)
+	}
+	s.started = true
+	children := s.children
+	s.mu.Unlock()
+
+	for i, c := range children {
+		var err error
+		if c.sup != nil {
+			err = c.sup.Start()
+		} else if err = Prepare(c.name, c.inst); err == nil {
+			go s.run(c)
+		}
+		if err != nil {
+			s.stop(children[:i])
+			return err
+		}
+	}
+	return nil
+}
+
+// Stop stops the children in reverse order, waiting for each one to stop
+// before stopping the next
+func (s *Supervisor) Stop() {
+	s.mu.Lock()
+	if s.stopping {
+		s.mu.Unlock()
+		return
+	}
+	s.stopping = true
+	children := s.children
+	s.mu.Unlock()
+	s.stop(children)
+}
+
+func (s *Supervisor) stop(children []supervised) {
+	for i := len(children) - 1; i >= 0; i-- {
+		if c := children[i]; c.sup != nil {
+			c.sup.Stop()
+		} else if c.inst.base().Started() {
+			closeInstance(context.Background(), c.inst)
+		}
+	}
+}
+
+// Done returns a channel closed when the Supervisor gives up
+func (s *Supervisor) Done() <-chan struct{} {
+	return s.done
+}
+
+// Err returns the failure that made the Supervisor give up, or nil
+func (s *Supervisor) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// run runs the main loop of a child, restarting it after a panic while the
+// supervisors allow it
+func (s *Supervisor) run(c supervised) {
+	restartLoop(c.name, c.inst.base(), s.backoff, func(failure error) (int, bool) {
+		n, ok := s.restart(failure)
+		if ok {
+			// with OneForOne only the failed child is restarted
+			Log.Printf("%s: restart %d allowed by %s (%s)\n", c.name, n, s.name, s.strategy)
+		}
+		return n, ok
+	})
+}
+
+// restart records a failure of a child and returns the number of restarts
+// in the window, and true if the child can be restarted. When there are
+// too many the failure is passed to the parent, and if it allows the
+// restart the window starts again. Otherwise the Supervisor gives up
+func (s *Supervisor) restart(failure error) (int, bool) {
+	s.mu.Lock()
+	parent := s.parent
+	if s.stopping || s.err != nil {
+		s.mu.Unlock()
+		return 0, false
+	}
+	now := time.Now()
+	recent := s.restarts[:0]
+	for _, t := range s.restarts {
+		if now.Sub(t) < s.window {
+			recent = append(recent, t)
+		}
+	}
+	s.restarts = recent
+	if len(s.restarts) < s.maxRestarts {
+		s.restarts = append(s.restarts, now)
+		n := len(s.restarts)
+		s.mu.Unlock()
+		return n, true
+	}
+	s.mu.Unlock()
+
+	failure = fmt.Errorf("%s: more than %d restarts in %v: %w", s.name, s.maxRestarts, s.window, failure)
+	if parent != nil {
+		if n, ok := parent.restart(failure); ok {
+			s.mu.Lock()
+			s.restarts = s.restarts[:0]
+			s.mu.Unlock()
+			return n, true
+		}
+	}
+	s.giveUp(failure)
+	return 0, false
+}
+
+// giveUp records the failure and, at the root of the tree, stops all the
+// actors
+func (s *Supervisor) giveUp(failure error) {
+	s.mu.Lock()
+	if s.err != nil {
+		s.mu.Unlock()
+		return
+	}
+	s.err = failure
+	close(s.done)
+	root := s.parent == nil
+	s.mu.Unlock()
+	Log.Printf("%v, giving up\n", failure)
+	if root {
+		go s.Stop()
+	}
+}
@@ -0,0 +1,144 @@
+package actor
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fail makes the main loop of the actor panic
+func (a *testActor) fail() {
+	a.In <- func() { panic("failure") }
+}
+
+// waitDone waits until the Supervisor gives up
+func waitDone(t *testing.T, s *Supervisor) {
+	t.Helper()
+	select {
+	case <-s.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatalf("%s didn't give up", s.name)
+	}
+}
+
+func TestSupervisorMaxRestarts(t *testing.T) {
+	a := newTestActor()
+	s := NewSupervisor("sup", OneForOne, 2, time.Minute)
+	s.Add("a", a)
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Stop()
+
+	for i := 0; i < 2; i++ {
+		a.fail()
+		// processed by the restarted main loop
+		a.call(func() {})
+	}
+	if s.Err() != nil {
+		t.Fatalf("gave up after 2 restarts: %v", s.Err())
+	}
+	a.fail()
+	waitDone(t, s)
+	if err := s.Err(); err == nil || !strings.Contains(err.Error(), "more than 2 restarts") {
+		t.Fatalf("got error %v", err)
+	}
+	if !stopped(&a.Actor) {
+		t.Fatal("the child wasn't stopped when the root gave up")
+	}
+}
+
+func TestSupervisorWindow(t *testing.T) {
+	a := newTestActor()
+	s := NewSupervisor("sup", OneForOne, 1, 50*time.Millisecond)
+	s.Add("a", a)
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Stop()
+
+	a.fail()
+	a.call(func() {})
+	time.Sleep(100 * time.Millisecond)
+	// the first restart left the window
+	a.fail()
+	a.call(func() {})
+	if s.Err() != nil {
+		t.Fatalf("gave up: %v", s.Err())
+	}
+}
+
+func TestSupervisorEscalation(t *testing.T) {
+	a := newTestActor()
+	b := newTestActor()
+	child := NewSupervisor("child", OneForOne, 0, time.Minute)
+	child.Add("a", a)
+	root := NewSupervisor("root", OneForOne, 1, time.Minute)
+	root.Supervise(child)
+	root.Add("b", b)
+	if err := root.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer root.Stop()
+
+	// the child can't restart a, its parent can once
+	a.fail()
+	a.call(func() {})
+	if child.Err() != nil || root.Err() != nil {
+		t.Fatalf("gave up: %v, %v", child.Err(), root.Err())
+	}
+	a.fail()
+	waitDone(t, root)
+	waitDone(t, child)
+	if err := root.Err(); err == nil || !strings.Contains(err.Error(), "child: more than 0 restarts") {
+		t.Fatalf("got error %v", err)
+	}
+	// the root stops the whole tree
+	select {
+	case <-b.StopCh:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the root didn't stop its other children")
+	}
+}
+
+func TestSupervisorBackoff(t *testing.T) {
+	a := newTestActor()
+	s := NewSupervisor("sup", OneForOne, 3, time.Minute)
+	var mu sync.Mutex
+	var restarts []int
+	s.SetBackoff(func(n int) time.Duration {
+		mu.Lock()
+		defer mu.Unlock()
+		restarts = append(restarts, n)
+		return time.Millisecond
+	})
+	s.Add("a", a)
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Stop()
+
+	for i := 0; i < 3; i++ {
+		a.fail()
+		a.call(func() {})
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(restarts) != 3 || restarts[0] != 1 || restarts[1] != 2 || restarts[2] != 3 {
+		t.Fatalf("backoff called with %v, want [1 2 3]", restarts)
+	}
+}
+
+func TestSupervisorUnknownStrategy(t *testing.T) {
+	a := newTestActor()
+	s := NewSupervisor("sup", Strategy(7), 1, time.Minute)
+	s.Add("a", a)
+	if err := s.Start(); err == nil || errors.Is(err, ErrStarted) {
+		t.Fatalf("got error %v, want an unknown strategy", err)
+	}
+	if a.Started() {
+		t.Fatal("the child was started")
+	}
+}
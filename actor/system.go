@@ -0,0 +1,588 @@
+package actor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Instance is implemented by all the generated actors. It allows the System
+// to handle actors of different types
+type Instance interface {
+	base() *Actor
+	Stop()
+}
+
+func (ba *Actor) base() *Actor {
+	return ba
+}
+
+// SetLoop sets the function running the actor's main loop. It is called
+// by the generated code when the actor is created
+func (ba *Actor) SetLoop(loop func()) {
+	ba.loop = loop
+}
+
+// SetHandler sets the name of the actor, the function that creates its
+// mailbox and the one that processes a message, returning true when the
+// actor is stopped. They are used to run the actor in a Scheduler. It also
+// gives the actor its ID. It is called by the generated code when the actor
+// is created
+func (ba *Actor) SetHandler(name string, mailbox func() Mailbox, dispatch func(interface{}) bool) {
+	ba.SetID()
+	ba.name = name
+	ba.mailbox = mailbox
+	ba.dispatch = dispatch
+	ba.task = new(atomic.Value)
+	ba.view = new(atomic.Value)
+	ba.closing = &closing{ctx: context.Background()}
+	ba.EnableSeq()
+}
+
+// SetInit sets the function that initializes the actor again when its main
+// loop is relaunched after a panic. It is called by the generated code for
+// actors with an init method
+func (ba *Actor) SetInit(init func()) {
+	ba.init = init
+}
+
+// SetReplay sets the function that sends again a message decoded from a
+// dead letter log, through a reference of the actor. It is called by the
+// generated code for the actors with the json feature
+func (ba *Actor) SetReplay(replay func(interface{}) error) {
+	ba.replay = replay
+}
+
+// Ready is the readiness hook. It is executed by the actor, in mailbox order,
+// when it's started by a System. Actors that need to finish some work before
+// other actors can use them should override it and return when they are
+// ready, or an error if they will never be
+func (ba *Actor) Ready() error {
+	return nil
+}
+
+// Snapshot is the snapshot hook. It is executed by the actor, in mailbox
+// order, when the System is dumped. Actors whose state should be part of the
+// dump override it to return a value that can be encoded as JSON. A nil
+// state is left out of the dump
+func (ba *Actor) Snapshot() (interface{}, error) {
+	return nil, nil
+}
+
+// StateVersion returns the version of the state returned by the snapshot
+// hook. Actors should increase it when the state changes in a way that
+// old snapshots can't be restored
+func (ba *Actor) StateVersion() int {
+	return 0
+}
+
+// Restore is the restore hook. It is executed before the actor is started
+// when the System is restored from a dump and receives the JSON encoded
+// state returned by the snapshot hook. Actors that provide snapshots should
+// override it
+func (ba *Actor) Restore(state []byte) error {
+	return fmt.Errorf("restore hook not implemented")
+}
+
+// systemRequest is a request sent by the runtime to an actor. It is executed
+// by the actor in mailbox order, usually calling one of its hooks
+type systemRequest interface {
+	execute(inst Instance)
+}
+
+// HandleSystem executes msg if it's a request sent by the runtime and
+// returns true, or returns false otherwise. It is called by the generated
+// code for every message
+func HandleSystem(inst Instance, msg interface{}) bool {
+	if req, ok := msg.(systemRequest); ok {
+		req.execute(inst)
+		return true
+	}
+	return false
+}
+
+type readyRequest struct {
+	reply chan error
+}
+
+func (req readyRequest) execute(inst Instance) {
+	req.reply <- inst.(interface{ Ready() error }).Ready()
+}
+
+type snapshotRequest struct {
+	reply chan snapshotReply
+}
+
+type snapshotReply struct {
+	state   json.RawMessage
+	version int
+	err     error
+}
+
+// execute calls the snapshot hook and encodes the state in the actor's
+// goroutine, so it can't change while it's encoded
+func (req snapshotRequest) execute(inst Instance) {
+	state, err := inst.(interface{ Snapshot() (interface{}, error) }).Snapshot()
+	if err != nil || state == nil {
+		req.reply <- snapshotReply{err: err}
+		return
+	}
+	data, err := json.Marshal(state)
+	version := inst.(interface{ StateVersion() int }).StateVersion()
+	req.reply <- snapshotReply{state: data, version: version, err: err}
+}
+
+// Option configures how an actor is managed by a System
+type Option func(*member)
+
+// WithDependsOn declares that an actor uses the given actors, which will be
+// started and ready before it
+func WithDependsOn(deps ...Instance) Option {
+	return func(m *member) {
+		m.deps = append(m.deps, deps...)
+	}
+}
+
+// WithName sets the name that identifies an actor in the System's dumps.
+// By default actors are named after their type and the order in which they
+// were added
+func WithName(name string) Option {
+	return func(m *member) {
+		m.name = name
+	}
+}
+
+// BackoffPolicy returns how long to wait before the given restart attempt,
+// starting at 1
+type BackoffPolicy func(attempt int) time.Duration
+
+// ConstantBackoff waits the same time before every restart
+func ConstantBackoff(d time.Duration) BackoffPolicy {
+	return func(int) time.Duration {
+		return d
+	}
+}
+
+// ExponentialBackoff doubles the wait before each restart, starting at min,
+// up to max
+func ExponentialBackoff(min, max time.Duration) BackoffPolicy {
+	return func(attempt int) time.Duration {
+		d := min
+		for i := 1; i < attempt && d < max; i++ {
+			d *= 2
+		}
+		if d > max {
+			d = max
+		}
+		return d
+	}
+}
+
+// WithAutoRestart relaunches the actor's main loop when a message causes a
+// panic that is not recovered, up to max times, waiting as the backoff
+// policy says before each restart. The actor's init method is executed
+// again before relaunching it. The message that caused the panic is lost
+func WithAutoRestart(max int, backoff BackoffPolicy) Option {
+	return func(m *member) {
+		m.restarts = max
+		m.backoff = backoff
+	}
+}
+
+// WithScheduler runs the actor as a task of the scheduler instead of in its
+// own goroutine. It can't be combined with WithAutoRestart
+func WithScheduler(sched *Scheduler) Option {
+	return func(m *member) {
+		m.sched = sched
+	}
+}
+
+// WithAffinity adds the actor to the affinity group of the shard key in its
+// scheduler, set with WithScheduler
+func WithAffinity(key string) Option {
+	return func(m *member) {
+		m.affinity = key
+	}
+}
+
+// member is an actor managed by a System
+type member struct {
+	inst     Instance
+	name     string
+	deps     []Instance
+	restarts int
+	backoff  BackoffPolicy
+	sched    *Scheduler
+	affinity string
+	tenant   string
+	started  bool
+	resume   chan struct{}
+	// fail receives the panics of the main loop instead of propagating them,
+	// when the System is run with Go
+	fail func(error)
+}
+
+// run runs the actor's main loop, relaunching it after a panic as allowed
+// by the restart policy. When there are no restarts left the panic is
+// propagated, or passed to fail
+func (m *member) run() {
+	attempts := 0
+	p := restartLoop(m.name, m.inst.base(), m.backoff, func(error) (int, bool) {
+		attempts++
+		return attempts, attempts <= m.restarts
+	})
+	if p == nil {
+		return
+	}
+	if m.fail == nil {
+		panic(p)
+	}
+	m.fail(fmt.Errorf("%s: main loop panic: %v", m.name, p))
+}
+
+// restartLoop runs the main loop of an actor and relaunches it after each
+// panic, executing its init method again, while restart allows it. restart
+// receives the failure and returns the number of the restart, passed to
+// the backoff policy. When the actor was stopped, or can't be restarted, it
+// returns the panic, leaving the actor stopped. It is shared by the Systems
+// and the Supervisors
+func restartLoop(name string, b *Actor, backoff BackoffPolicy, restart func(failure error) (int, bool)) interface{} {
+	for {
+		p, stack := Try(b.loop)
+		if p == nil {
+			return nil
+		}
+		Log.Printf("%s: main loop panic: %v\n%s", name, p, stack)
+		if stopped(b) {
+			return p
+		}
+		n, ok := restart(fmt.Errorf("%s: main loop panic: %v", name, p))
+		if !ok {
+			// the actor is dead: it can't be stopped anymore
+			b.BeginStop()
+			if !stopped(b) {
+				close(b.StopCh)
+			}
+			return p
+		}
+		if backoff != nil {
+			time.Sleep(backoff(n))
+		}
+		if b.init != nil {
+			b.init()
+		}
+		Log.Printf("%s: main loop restarted (attempt %d)\n", name, n)
+	}
+}
+
+// stopped returns true if the actor has processed its stop request
+func stopped(b *Actor) bool {
+	select {
+	case <-b.StopCh:
+		return true
+	default:
+		return false
+	}
+}
+
+// DefaultGracePeriod is the default time given to the actors to stop when
+// the System is shut down by a signal. GOACTORS_GRACE_PERIOD changes it, see
+// ConfigFromEnv
+const DefaultGracePeriod = 10 * time.Second
+
+// System manages the lifecycle of a group of actors
+type System struct {
+	members []*member
+	index   map[Instance]*member
+	started []Instance
+	grace   time.Duration
+	tenants map[string]*tenant
+	// mu protects the state of the members changed while the actors run
+	mu sync.Mutex
+	// done is closed when the System run with Go is shut down, and errs are
+	// the errors returned by Wait
+	done chan struct{}
+	errs []error
+}
+
+// NewSystem creates an empty actor system
+func NewSystem() *System {
+	return &System{index: make(map[Instance]*member), grace: gracePeriod}
+}
+
+// SetGracePeriod sets the time given to the actors to stop when the System
+// is shut down by a signal
+func (s *System) SetGracePeriod(d time.Duration) {
+	s.grace = d
+}
+
+// Add adds an actor to the system. It should not be started: the system
+// will start it
+func (s *System) Add(inst Instance, opts ...Option) {
+	m := &member{inst: inst, name: fmt.Sprintf("%T#%d", inst, len(s.members))}
+	for _, opt := range opts {
+		opt(m)
+	}
+	s.members = append(s.members, m)
+	s.index[inst] = m
+}
+
+// Start starts the actors in dependency order. Each actor is started
+// after all the actors it depends on are ready. If an actor can't be
+// started the ones already started are stopped. No actor is started if the
+// actors of a tenant exceed its quota
+func (s *System) Start() error {
+	order, err := s.order()
+	if err != nil {
+		return err
+	}
+	if err := s.checkQuotas(); err != nil {
+		return err
+	}
+
+	for _, inst := range order {
+		b := inst.base()
+		m := s.index[inst]
+		if m.sched != nil && m.restarts > 0 {
+			s.Stop()
+			return fmt.Errorf("%s: scheduled actors can't be restarted", m.name)
+		}
+		if err := Prepare(m.name, inst); err != nil {
+			s.Stop()
+			return err
+		}
+		if m.sched != nil {
+			if m.affinity != "" {
+				m.sched.Affinity(m.affinity, inst)
+			}
+			m.sched.Schedule(inst)
+		} else {
+			go m.run()
+		}
+		s.started = append(s.started, inst)
+		s.mu.Lock()
+		m.started = true
+		s.mu.Unlock()
+
+		reply := make(chan error)
+		b.In <- readyRequest{reply: reply}
+		b.Notify()
+		if err := <-reply; err != nil {
+			s.Stop()
+			return fmt.Errorf("%s not ready: %w", m.name, err)
+		}
+	}
+	return nil
+}
+
+// Stop stops the started actors in reverse order, waiting for each one
+// to stop, and its post-stop hook to return, before stopping the next
+func (s *System) Stop() {
+	s.resumeAll()
+	for i := len(s.started) - 1; i >= 0; i-- {
+		closeInstance(context.Background(), s.started[i])
+	}
+	s.started = nil
+}
+
+// closeInstance stops an actor with Close, if it was generated with it, or
+// with Stop, and waits for it to stop
+func closeInstance(ctx context.Context, inst Instance) error {
+	if c, ok := inst.(interface{ Close(context.Context) error }); ok {
+		if err := c.Close(ctx); !errors.Is(err, ErrStopped) {
+			return err
+		}
+	} else {
+		inst.Stop()
+	}
+	<-inst.base().StopCh
+	return nil
+}
+
+// Shutdown stops the started actors in reverse order, like Stop, but gives
+// up when ctx is done. ctx is passed to their post-stop hooks. It returns an
+// error if some actors didn't stop in time, or the errors of the post-stop
+// hooks
+func (s *System) Shutdown(ctx context.Context) error {
+	s.resumeAll()
+	var errs []error
+	total := len(s.started)
+	for i := total - 1; i >= 0; i-- {
+		inst := s.started[i]
+		name := s.index[inst].name
+		// Stop blocks while the In channel is full
+		done := make(chan error, 1)
+		go func() {
+			done <- closeInstance(ctx, inst)
+		}()
+		select {
+		case err := <-done:
+			if err != nil && ctx.Err() == nil {
+				errs = append(errs, err)
+			}
+		case <-ctx.Done():
+		}
+		if !stopped(inst.base()) {
+			s.started = s.started[:i+1]
+			errs = append(errs, fmt.Errorf("%d actors not stopped, the last one %s: %v", i+1, name, ctx.Err()))
+			return errors.Join(errs...)
+		}
+		Log.Printf("%s stopped (%d/%d)\n", name, total-i, total)
+	}
+	s.started = nil
+	return errors.Join(errs...)
+}
+
+// HandleSignals waits until one of the signals is received or ctx is done,
+// and then shuts the System down, giving the actors the grace period to
+// stop. It returns the error returned by Shutdown
+func (s *System) HandleSignals(ctx context.Context, sigs ...os.Signal) error {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, sigs...)
+	defer signal.Stop(ch)
+
+	select {
+	case sig := <-ch:
+		Log.Printf("%v received, stopping %d actors\n", sig, len(s.started))
+	case <-ctx.Done():
+		Log.Printf("%v, stopping %d actors\n", ctx.Err(), len(s.started))
+	}
+
+	shutdown, cancel := context.WithTimeout(context.Background(), s.grace)
+	defer cancel()
+	return s.Shutdown(shutdown)
+}
+
+// Archive is the content of a System dump
+type Archive struct {
+	Actors []ActorSnapshot `json:"actors"`
+}
+
+// ActorSnapshot is the state of an actor in a System dump
+type ActorSnapshot struct {
+	Name    string          `json:"name"`
+	Type    string          `json:"type"`
+	Version int             `json:"version"`
+	State   json.RawMessage `json:"state"`
+}
+
+// Dump writes to w a JSON archive with the snapshots of the started
+// actors. Each actor takes its snapshot when it processes the snapshot
+// request, after the messages it received before
+func (s *System) Dump(ctx context.Context, w io.Writer) error {
+	var archive Archive
+	for _, inst := range s.started {
+		reply := make(chan snapshotReply, 1)
+		select {
+		case inst.base().In <- snapshotRequest{reply: reply}:
+			inst.base().Notify()
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		var r snapshotReply
+		select {
+		case r = <-reply:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		if r.err != nil {
+			return fmt.Errorf("%s: snapshot failed: %v", s.index[inst].name, r.err)
+		}
+		if r.state == nil {
+			continue
+		}
+		archive.Actors = append(archive.Actors, ActorSnapshot{
+			Name:    s.index[inst].name,
+			Type:    fmt.Sprintf("%T", inst),
+			Version: r.version,
+			State:   r.state,
+		})
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(archive)
+}
+
+// Restore reads an archive written by Dump and passes each snapshot to the
+// restore hook of the actor with the same name. It should be called before
+// Start, after adding the actors. It fails if the archive contains
+// snapshots of actors that are not part of the system, or whose type or
+// state version don't match
+func (s *System) Restore(r io.Reader) error {
+	var archive Archive
+	if err := json.NewDecoder(r).Decode(&archive); err != nil {
+		return fmt.Errorf("invalid archive: %v", err)
+	}
+
+	names := make(map[string]Instance)
+	for _, m := range s.members {
+		names[m.name] = m.inst
+	}
+
+	for _, snap := range archive.Actors {
+		inst, ok := names[snap.Name]
+		if !ok {
+			return fmt.Errorf("%s: not part of the system", snap.Name)
+		}
+		if typ := fmt.Sprintf("%T", inst); typ != snap.Type {
+			return fmt.Errorf("%s: snapshot of a %s can't be restored into a %s", snap.Name, snap.Type, typ)
+		}
+		version := inst.(interface{ StateVersion() int }).StateVersion()
+		if version != snap.Version {
+			return fmt.Errorf("%s: snapshot version %d, expected %d", snap.Name, snap.Version, version)
+		}
+		if err := inst.(interface{ Restore([]byte) error }).Restore(snap.State); err != nil {
+			return fmt.Errorf("%s: %v", snap.Name, err)
+		}
+	}
+	return nil
+}
+
+// order sorts the actors so that every actor comes after its dependencies
+func (s *System) order() ([]Instance, error) {
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	var order []Instance
+	state := make(map[*member]int)
+
+	var visit func(m *member) error
+	visit = func(m *member) error {
+		switch state[m] {
+		case visiting:
+			return fmt.Errorf("dependency cycle involving %T", m.inst)
+		case visited:
+			return nil
+		}
+		state[m] = visiting
+		for _, dep := range m.deps {
+			d, ok := s.index[dep]
+			if !ok {
+				return fmt.Errorf("%T depends on %T, which is not part of the system", m.inst, dep)
+			}
+			if err := visit(d); err != nil {
+				return err
+			}
+		}
+		state[m] = visited
+		order = append(order, m.inst)
+		return nil
+	}
+
+	for _, m := range s.members {
+		if err := visit(m); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
@@ -0,0 +1,46 @@
+package actor
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+var errNotReady = errors.New("no connection")
+
+// notReadyActor is a testActor whose readiness hook fails
+type notReadyActor struct {
+	testActor
+}
+
+func (a *notReadyActor) Ready() error {
+	return errNotReady
+}
+
+func (a *notReadyActor) receive() {
+	a.Bind()
+	for msg := range a.In {
+		if _, ok := msg.(testStop); ok {
+			close(a.StopCh)
+			return
+		}
+		HandleSystem(a, msg)
+	}
+}
+
+func TestStartNotReady(t *testing.T) {
+	a := &notReadyActor{}
+	a.In = make(chan interface{}, DefaultInCap)
+	a.StopCh = make(chan struct{})
+	a.SetLoop(a.receive)
+
+	s := NewSystem()
+	s.Add(a, WithName("db"))
+	err := s.Start()
+	if !errors.Is(err, errNotReady) {
+		t.Fatalf("got %v, want an error wrapping the error of Ready", err)
+	}
+	if !strings.HasPrefix(err.Error(), "db not ready") {
+		t.Fatalf("got %v, want it to name the actor db", err)
+	}
+}
@@ -0,0 +1,231 @@
+package actor
+
+import (
+	"expvar"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+	"unsafe"
+)
+
+// Quota limits the resources of the actors of a tenant of a System. The
+// zero values disable each limit
+type Quota struct {
+	// MaxActors is the number of actors the tenant can have
+	MaxActors int
+	// MaxMailboxBytes is the memory the mailboxes of the actors of the
+	// tenant can reserve: the capacity of their In channels times the size
+	// of a message in them
+	MaxMailboxBytes int64
+	// Rate is the number of messages per second the actors of the tenant
+	// can receive, in total. Senders over it wait until they can send, or
+	// if Reject is true, their calls panic with a QuotaExceeded error
+	Rate   int
+	Reject bool
+}
+
+// QuotaExceeded is the value passed to panic when a call is rejected
+// because the tenant of the actor exceeded its message rate
+type QuotaExceeded struct {
+	Tenant string
+	Method string
+}
+
+func (q QuotaExceeded) Error() string {
+	return fmt.Sprintf("%s: tenant %s exceeded its message rate", q.Method, q.Tenant)
+}
+
+// TenantUsage is the quota of a tenant and the resources used by its actors
+type TenantUsage struct {
+	Name  string `json:"name"`
+	Quota Quota  `json:"quota"`
+	// Actors are the running actors of the tenant
+	Actors       int   `json:"actors"`
+	MailboxBytes int64 `json:"mailboxBytes"`
+	Queue        int   `json:"queue"`
+	// Admitted are the messages sent to the actors of the tenant, and
+	// Waited and Rejected the ones whose senders waited or were rejected
+	// because of the message rate
+	Admitted int64 `json:"admitted"`
+	Waited   int64 `json:"waited"`
+	Rejected int64 `json:"rejected"`
+}
+
+// mailboxSlot is the memory of a message in an In channel
+const mailboxSlot = int64(unsafe.Sizeof(interface{}(nil)))
+
+// tenantMetrics are published with expvar, under the goactors_tenants
+// variable, by tenant name. Each tenant has the following counters:
+//
+//	admitted  messages sent to the actors of the tenant
+//	waited    messages whose senders waited because of the message rate
+//	rejected  messages rejected because of the message rate
+var tenantMetrics = expvar.NewMap("goactors_tenants")
+
+// tenant is a namespace of the actors of a System, with its quota. Its rate
+// is enforced with a token bucket that holds a second of messages
+type tenant struct {
+	name     string
+	quota    Quota
+	admitted expvar.Int
+	waited   expvar.Int
+	rejected expvar.Int
+	mu       sync.Mutex
+	tokens   float64
+	last     time.Time
+}
+
+// AddTenant adds a tenant to the system, with its quota. Its actors are
+// added with the WithTenant option, before or after the tenant. It fails if
+// the tenant already exists
+func (s *System) AddTenant(name string, q Quota) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.tenants[name]; ok {
+		return fmt.Errorf("tenant %s already exists", name)
+	}
+	t := &tenant{name: name, quota: q, tokens: float64(q.Rate)}
+	if s.tenants == nil {
+		s.tenants = make(map[string]*tenant)
+	}
+	s.tenants[name] = t
+
+	vars := new(expvar.Map).Init()
+	vars.Set("admitted", &t.admitted)
+	vars.Set("waited", &t.waited)
+	vars.Set("rejected", &t.rejected)
+	tenantMetrics.Set(name, vars)
+	return nil
+}
+
+// WithTenant adds the actor to a tenant of the System, created with
+// AddTenant, whose quota limits it along with the other actors of the
+// tenant
+func WithTenant(name string) Option {
+	return func(m *member) {
+		m.tenant = name
+	}
+}
+
+// checkQuotas returns an error if the actors of a tenant exceed its quota
+// of actors or mailbox memory, or if they are added to an unknown tenant.
+// Otherwise it links the actors to their tenants, which limit their message
+// rate. It is called by Start, once all the tenants and actors are added
+func (s *System) checkQuotas() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	actors := make(map[string]int)
+	bytes := make(map[string]int64)
+	for _, m := range s.members {
+		if m.tenant == "" {
+			continue
+		}
+		if _, ok := s.tenants[m.tenant]; !ok {
+			return fmt.Errorf("%s: unknown tenant %s", m.name, m.tenant)
+		}
+		actors[m.tenant]++
+		bytes[m.tenant] += int64(cap(m.inst.base().In)) * mailboxSlot
+	}
+	for name, t := range s.tenants {
+		if max := t.quota.MaxActors; max > 0 && actors[name] > max {
+			return fmt.Errorf("tenant %s: %d actors, over its quota of %d", name, actors[name], max)
+		}
+		if max := t.quota.MaxMailboxBytes; max > 0 && bytes[name] > max {
+			return fmt.Errorf("tenant %s: mailboxes of %d bytes, over its quota of %d", name, bytes[name], max)
+		}
+	}
+	for _, m := range s.members {
+		if m.tenant != "" {
+			m.inst.base().tenant = s.tenants[m.tenant]
+		}
+	}
+	return nil
+}
+
+// Tenants returns the quotas of the tenants of the system, and the
+// resources used by their actors, sorted by name
+func (s *System) Tenants() []TenantUsage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	usage := make(map[string]*TenantUsage)
+	for name, t := range s.tenants {
+		usage[name] = &TenantUsage{
+			Name:     name,
+			Quota:    t.quota,
+			Admitted: t.admitted.Value(),
+			Waited:   t.waited.Value(),
+			Rejected: t.rejected.Value(),
+		}
+	}
+	for _, m := range s.members {
+		u, ok := usage[m.tenant]
+		b := m.inst.base()
+		if !ok || !m.started || stopped(b) {
+			continue
+		}
+		u.Actors++
+		u.MailboxBytes += int64(cap(b.In)) * mailboxSlot
+		u.Queue += len(b.In)
+	}
+	tenants := []TenantUsage{}
+	for _, u := range usage {
+		tenants = append(tenants, *u)
+	}
+	sort.Slice(tenants, func(i, j int) bool {
+		return tenants[i].Name < tenants[j].Name
+	})
+	return tenants
+}
+
+// admitTenant waits until the tenant of the actor can receive a message,
+// according to its rate, or panics with a QuotaExceeded error if it should
+// be rejected
+func (ba *Actor) admitTenant(method string) {
+	t := ba.tenant
+	if t == nil {
+		return
+	}
+	if t.quota.Rate <= 0 {
+		t.admitted.Add(1)
+		return
+	}
+	for waited := false; ; waited = true {
+		wait := t.take()
+		if wait == 0 {
+			t.admitted.Add(1)
+			return
+		}
+		if t.quota.Reject {
+			t.rejected.Add(1)
+			panic(QuotaExceeded{Tenant: t.name, Method: method})
+		}
+		if !waited {
+			t.waited.Add(1)
+		}
+		time.Sleep(wait)
+	}
+}
+
+// take takes a token from the bucket and returns zero, or returns how long
+// to wait until there is one
+func (t *tenant) take() time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	rate := float64(t.quota.Rate)
+	if !t.last.IsZero() {
+		t.tokens += now.Sub(t.last).Seconds() * rate
+		if t.tokens > rate {
+			t.tokens = rate
+		}
+	}
+	t.last = now
+	if t.tokens >= 1 {
+		t.tokens--
+		return 0
+	}
+	return time.Duration((1 - t.tokens) / rate * float64(time.Second))
+}
@@ -0,0 +1,27 @@
+package actor
+
+import (
+	"errors"
+	"testing"
+)
+
+// TestTenantAddedAfterActor checks that the message rate of a tenant limits
+// the actors added to the System before the tenant
+func TestTenantAddedAfterActor(t *testing.T) {
+	a := newTestActor()
+	s := NewSystem()
+	s.Add(a, WithTenant("acme"))
+	if err := s.AddTenant("acme", Quota{Rate: 1, Reject: true}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Stop()
+
+	a.Admit("test.call", nil, Throttle{})
+	p, _ := Try(func() { a.Admit("test.call", nil, Throttle{}) })
+	if err, _ := p.(error); !errors.As(err, new(QuotaExceeded)) {
+		t.Fatalf("got %v over the message rate, want a QuotaExceeded panic", p)
+	}
+}
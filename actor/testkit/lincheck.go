@@ -0,0 +1,241 @@
+package testkit
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// Model is the sequential specification an actor is checked against by
+// CheckLinearizable
+type Model struct {
+	// Init returns the initial state
+	Init func() interface{}
+	// Step applies a call to the state. It returns false if the results
+	// aren't the ones of the model for the state, and the next state
+	Step func(state interface{}, method string, params, results []interface{}) (bool, interface{})
+}
+
+// LinOptions configures CheckLinearizable. The zero values are replaced by
+// the defaults
+type LinOptions struct {
+	// Clients is the number of goroutines calling the actor at the same
+	// time, each with its own reference. 4 by default
+	Clients int
+	// Calls is the number of calls of each client. 20 by default
+	Calls int
+	// Runs is the number of histories checked, each with a new actor. 20
+	// by default
+	Runs int
+	// Seed is the seed of the random calls. By default it's random, and
+	// it's logged when the test fails
+	Seed int64
+}
+
+// LinClient calls a random method of the actor, with random parameters,
+// through its own reference, and returns the method, the parameters and the
+// results of the call
+type LinClient func(rnd *rand.Rand) Operation
+
+// Operation is a call of a history
+type Operation struct {
+	Client  int
+	Method  string
+	Params  []interface{}
+	Results []interface{}
+	// Pending is true for the asynchronous calls without results, which
+	// return before the actor processes them, so they can take effect at
+	// any point after the call
+	Pending bool
+	// Call and Return order the start and the end of the calls of the
+	// history
+	Call, Return int64
+}
+
+func (op Operation) String() string {
+	if op.Pending {
+		return fmt.Sprintf("client %d: %s%v (async)", op.Client, op.Method, op.Params)
+	}
+	return fmt.Sprintf("client %d: %s%v -> %v", op.Client, op.Method, op.Params, op.Results)
+}
+
+// CheckLinearizable fires random concurrent calls at new actors and fails
+// the test if their results can't be explained by calling the methods one
+// at a time, in an order that respects the calls that returned before
+// others started, on the model. start creates and starts an actor, and
+// returns the function that creates its clients and the one that stops it.
+// It is called by the generated CheckXxxLinearizable functions
+func CheckLinearizable(t testing.TB, model Model, opts LinOptions, start func() (newClient func() LinClient, stop func())) {
+	t.Helper()
+	if opts.Clients <= 0 {
+		opts.Clients = 4
+	}
+	if opts.Calls <= 0 {
+		opts.Calls = 20
+	}
+	if opts.Runs <= 0 {
+		opts.Runs = 20
+	}
+	if opts.Seed == 0 {
+		opts.Seed = rand.Int63()
+	}
+
+	for run := 0; run < opts.Runs; run++ {
+		newClient, stop := start()
+		history := runHistory(newClient, opts, opts.Seed+int64(run))
+		stop()
+		if !Linearizable(model, history) {
+			sort.Slice(history, func(i, j int) bool {
+				return history[i].Call < history[j].Call
+			})
+			var lines []string
+			for _, op := range history {
+				lines = append(lines, "\t"+op.String())
+			}
+			t.Fatalf("history not linearizable (seed %d, run %d):\n%s", opts.Seed, run, strings.Join(lines, "\n"))
+		}
+	}
+}
+
+// runHistory runs the calls of the clients at the same time and returns
+// them
+func runHistory(newClient func() LinClient, opts LinOptions, seed int64) []Operation {
+	var clock int64
+	var mu sync.Mutex
+	var history []Operation
+	var wg sync.WaitGroup
+	for c := 0; c < opts.Clients; c++ {
+		client := newClient()
+		rnd := rand.New(rand.NewSource(seed*int64(opts.Clients) + int64(c)))
+		wg.Add(1)
+		go func(c int) {
+			defer wg.Done()
+			for i := 0; i < opts.Calls; i++ {
+				call := atomic.AddInt64(&clock, 1)
+				op := client(rnd)
+				op.Client, op.Call, op.Return = c, call, atomic.AddInt64(&clock, 1)
+				if op.Pending {
+					op.Return = math.MaxInt64
+				}
+				mu.Lock()
+				history = append(history, op)
+				mu.Unlock()
+			}
+		}(c)
+	}
+	wg.Wait()
+	return history
+}
+
+// Linearizable returns true if the operations of the history can be
+// ordered, respecting the ones that returned before others were called, so
+// that applying them to the model one at a time gives their results
+func Linearizable(model Model, history []Operation) bool {
+	ops := append([]Operation(nil), history...)
+	sort.Slice(ops, func(i, j int) bool {
+		return ops[i].Call < ops[j].Call
+	})
+	done := make([]bool, len(ops))
+	failed := make(map[string]bool)
+
+	var search func(state interface{}, left int) bool
+	search = func(state interface{}, left int) bool {
+		if left == 0 {
+			return true
+		}
+		key := linKey(done, state)
+		if failed[key] {
+			return false
+		}
+		// the candidates are the pending operations called before any
+		// pending one returned
+		minReturn := int64(-1)
+		for i, op := range ops {
+			if !done[i] && (minReturn < 0 || op.Return < minReturn) {
+				minReturn = op.Return
+			}
+		}
+		for i, op := range ops {
+			if done[i] {
+				continue
+			}
+			if op.Call > minReturn {
+				break
+			}
+			ok, next := model.Step(state, op.Method, op.Params, op.Results)
+			if !ok {
+				continue
+			}
+			done[i] = true
+			if search(next, left-1) {
+				return true
+			}
+			done[i] = false
+		}
+		failed[key] = true
+		return false
+	}
+	return search(model.Init(), len(ops))
+}
+
+// linKey identifies the operations done and the state reached, to avoid
+// searching again from the same point. States are compared by their Go
+// syntax representation
+func linKey(done []bool, state interface{}) string {
+	var b strings.Builder
+	for _, d := range done {
+		if d {
+			b.WriteByte('1')
+		} else {
+			b.WriteByte('0')
+		}
+	}
+	fmt.Fprintf(&b, "|%#v", state)
+	return b.String()
+}
+
+// RandomValue returns a random value of a basic type, from a small set so
+// the calls of a history often use the same values. It is used by the
+// generated CheckXxxLinearizable functions for the parameters of the
+// methods
+func RandomValue(rnd *rand.Rand, typeName string) interface{} {
+	n := rnd.Intn(8)
+	switch typeName {
+	case "string":
+		return string(rune('a' + n))
+	case "[]byte":
+		return []byte{byte('a' + n)}
+	case "bool":
+		return n%2 == 0
+	case "byte", "uint8":
+		return uint8(n)
+	case "rune", "int32":
+		return int32(n)
+	case "int":
+		return n
+	case "int8":
+		return int8(n)
+	case "int16":
+		return int16(n)
+	case "int64":
+		return int64(n)
+	case "uint":
+		return uint(n)
+	case "uint16":
+		return uint16(n)
+	case "uint32":
+		return uint32(n)
+	case "uint64":
+		return uint64(n)
+	case "float32":
+		return float32(n)
+	case "float64":
+		return float64(n)
+	}
+	panic("testkit: no random values of type " + typeName)
+}
@@ -0,0 +1,42 @@
+// Package testkit contains assertions about the actors for tests
+package testkit
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/carevaloc/goactors/actor"
+)
+
+// StopTimeout is how long AssertNoRunningActors waits for the actors to
+// finish the messages in their mailboxes after they are stopped
+var StopTimeout = time.Second
+
+// AssertNoRunningActors fails the test if there are actors whose receive
+// loop is still running, usually because Stop wasn't called and their
+// goroutines leak. Stopped actors have StopTimeout to exit. It is meant to
+// be called at the end of a test, or deferred:
+//
+//	defer testkit.AssertNoRunningActors(t)
+func AssertNoRunningActors(t testing.TB) {
+	t.Helper()
+	deadline := time.Now().Add(StopTimeout)
+	running := actor.RunningActors()
+	for len(running) > 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		running = actor.RunningActors()
+	}
+	if len(running) == 0 {
+		return
+	}
+
+	var names []string
+	for name, n := range running {
+		names = append(names, fmt.Sprintf("%s (%d)", name, n))
+	}
+	sort.Strings(names)
+	t.Errorf("actors still running: %s", strings.Join(names, ", "))
+}
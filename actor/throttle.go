@@ -0,0 +1,111 @@
+package actor
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Throttle limits the messages each sender can send to an actor: no more
+// than Limit in any period of length Window. Senders over their quota wait
+// until they can send, or if Reject is true, their calls panic with a
+// Throttled error. A zero Limit disables throttling
+type Throttle struct {
+	Limit  int
+	Window time.Duration
+	Reject bool
+}
+
+// Throttle returns the limit of the messages each sender can send to the
+// actor. By default there is no limit
+func (ba *Actor) Throttle() Throttle {
+	return Throttle{}
+}
+
+// Throttled is the value passed to panic when a call is rejected because the
+// sender exceeded its quota
+type Throttled struct {
+	Method string
+	Sender interface{}
+}
+
+func (t Throttled) Error() string {
+	return fmt.Sprintf("%s: sender %v exceeded its quota", t.Method, t.Sender)
+}
+
+// limiter keeps the times of the last messages of each sender, in a sliding
+// window
+type limiter struct {
+	mu      sync.Mutex
+	senders map[interface{}][]time.Time
+	sweep   int
+}
+
+// limitersMu protects the limiter field of the actors, which is set by the
+// first sender admitted to a throttled actor. A single lock is shared by all
+// the actors so the Actor, embedded by value in the actors, doesn't contain
+// one
+var limitersMu sync.Mutex
+
+// Admit waits until the sender can send a message to the actor, according
+// to the throttle and the message rate of its tenant, or panics with a
+// Throttled or QuotaExceeded error if it should be rejected. It is called by
+// the generated references before sending the requests
+func (ba *Actor) Admit(method string, sender interface{}, t Throttle) {
+	ba.admitTenant(method)
+	if t.Limit <= 0 {
+		return
+	}
+	limitersMu.Lock()
+	if ba.limiter == nil {
+		ba.limiter = &limiter{senders: make(map[interface{}][]time.Time), sweep: 1024}
+	}
+	l := ba.limiter
+	limitersMu.Unlock()
+
+	for {
+		wait := l.admit(sender, t)
+		if wait == 0 {
+			return
+		}
+		if t.Reject {
+			panic(Throttled{Method: method, Sender: sender})
+		}
+		time.Sleep(wait)
+	}
+}
+
+// admit records a message of the sender if it's within its quota and returns
+// zero, or returns how long the sender should wait
+func (l *limiter) admit(sender interface{}, t Throttle) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	times := expire(l.senders[sender], now.Add(-t.Window))
+	if len(times) >= t.Limit {
+		l.senders[sender] = times
+		return times[0].Add(t.Window).Sub(now)
+	}
+	l.senders[sender] = append(times, now)
+
+	// forget the senders that are not sending anymore
+	if len(l.senders) > l.sweep {
+		for s, times := range l.senders {
+			if len(expire(times, now.Add(-t.Window))) == 0 {
+				delete(l.senders, s)
+			}
+		}
+		l.sweep = 2 * len(l.senders)
+	}
+	return 0
+}
+
+// expire removes the times before start
+func expire(times []time.Time, start time.Time) []time.Time {
+	i := 0
+	for i < len(times) && !times[i].After(start) {
+		i++
+	}
+	return times[i:]
+}
@@ -0,0 +1,205 @@
+package actor
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	wheelBits   = 6
+	wheelSlots  = 1 << wheelBits
+	wheelMask   = wheelSlots - 1
+	wheelLevels = 4
+	// sweepMin is the number of cancelled timers that the wheel keeps
+	// before it removes them from its slots, if they are more than the
+	// timers that are still scheduled
+	sweepMin = 1024
+)
+
+// Timer states
+const (
+	timerScheduled int32 = iota
+	timerCancelled
+	timerFired
+)
+
+// Timer is a function scheduled on a TimerWheel
+type Timer struct {
+	f       func()
+	w       *TimerWheel
+	ticks   uint64
+	expires uint64
+	state   int32
+}
+
+// Cancel prevents the timer from firing. It has no effect if the timer
+// has already fired
+func (t *Timer) Cancel() {
+	if atomic.CompareAndSwapInt32(&t.state, timerScheduled, timerCancelled) {
+		atomic.AddInt64(&t.w.cancelled, 1)
+	}
+}
+
+// TimerWheel schedules very large numbers of delayed functions using a
+// single goroutine instead of one per timer. Timers are kept in a hierarchy
+// of wheels: the first one has a slot per tick and each of the following
+// covers the whole previous wheel with every slot, so that timers move down
+// to finer wheels as they approach their expiration time. Timers fire
+// up to one tick after their delay
+type TimerWheel struct {
+	In     chan *Timer
+	StopCh chan struct{}
+	stop   sync.Once
+	tick   time.Duration
+	now    uint64
+	wheels [wheelLevels][wheelSlots][]*Timer
+	// size is the number of timers in the wheels, and cancelled the number
+	// of them that are cancelled
+	size      int
+	cancelled int64
+}
+
+// NewTimerWheel creates a timer wheel with the given resolution
+func NewTimerWheel(tick time.Duration) *TimerWheel {
+	return &TimerWheel{
+		In:     make(chan *Timer, DefaultInCap),
+		StopCh: make(chan struct{}),
+		tick:   tick,
+	}
+}
+
+// Start starts the wheel's main loop on a new goroutine
+func (w *TimerWheel) Start() *TimerWheel {
+	go w.receive()
+	return w
+}
+
+// Stop stops the wheel. Pending timers will not fire. Only the first call
+// has effect
+func (w *TimerWheel) Stop() {
+	w.stop.Do(func() {
+		close(w.StopCh)
+	})
+}
+
+// After schedules f to be executed after d. f is executed on the wheel's
+// goroutine, so it should not block: sending a message to an asynchronous
+// method is the intended use
+func (w *TimerWheel) After(d time.Duration, f func()) *Timer {
+	t := &Timer{f: f, w: w, ticks: uint64((d + w.tick - 1) / w.tick)}
+	w.In <- t
+	return t
+}
+
+func (w *TimerWheel) receive() {
+	start := time.Now()
+	ticker := time.NewTicker(w.tick)
+	defer ticker.Stop()
+	for {
+		select {
+		case t := <-w.In:
+			w.catchUp(start)
+			// the current tick has already started
+			t.expires = w.now + t.ticks + 1
+			w.add(t)
+		case <-ticker.C:
+			w.catchUp(start)
+			if c := int(atomic.LoadInt64(&w.cancelled)); c > sweepMin && c > w.size-c {
+				w.sweep()
+			}
+		case <-w.StopCh:
+			Log.Println("Timer wheel stopped")
+			return
+		}
+	}
+}
+
+// catchUp advances the wheels to the current time. The ticker drops ticks
+// when the wheel falls behind
+func (w *TimerWheel) catchUp(start time.Time) {
+	now := uint64(time.Since(start) / w.tick)
+	for w.now < now {
+		w.advance()
+	}
+}
+
+// add places a timer in the slot of the finest wheel that covers its
+// expiration time
+func (w *TimerWheel) add(t *Timer) {
+	if t.expires <= w.now {
+		w.fire(t)
+		return
+	}
+	w.size++
+	delta := t.expires - w.now
+	for level := uint(0); level < wheelLevels; level++ {
+		if delta < 1<<(wheelBits*(level+1)) {
+			slot := (t.expires >> (wheelBits * level)) & wheelMask
+			w.wheels[level][slot] = append(w.wheels[level][slot], t)
+			return
+		}
+	}
+	// beyond the range of the wheels: park the timer in the last slot of
+	// the coarsest wheel, it will be placed again when the slot is reached
+	top := uint(wheelLevels - 1)
+	slot := ((w.now >> (wheelBits * top)) - 1) & wheelMask
+	w.wheels[top][slot] = append(w.wheels[top][slot], t)
+}
+
+// advance moves the wheels one tick, moving the timers of the coarser wheels
+// that are now within range to finer wheels and firing the expired timers
+func (w *TimerWheel) advance() {
+	w.now++
+	for level := uint(1); level < wheelLevels; level++ {
+		if w.now&(1<<(wheelBits*level)-1) != 0 {
+			break
+		}
+		slot := (w.now >> (wheelBits * level)) & wheelMask
+		timers := w.wheels[level][slot]
+		w.wheels[level][slot] = nil
+		w.size -= len(timers)
+		for _, t := range timers {
+			w.add(t)
+		}
+	}
+
+	slot := w.now & wheelMask
+	timers := w.wheels[0][slot]
+	w.wheels[0][slot] = nil
+	w.size -= len(timers)
+	for _, t := range timers {
+		w.fire(t)
+	}
+}
+
+// fire executes the function of the timer, unless it was cancelled
+func (w *TimerWheel) fire(t *Timer) {
+	if atomic.CompareAndSwapInt32(&t.state, timerScheduled, timerFired) {
+		t.f()
+		return
+	}
+	atomic.AddInt64(&w.cancelled, -1)
+}
+
+// sweep removes the cancelled timers from the wheels. They would be removed
+// when their slots are reached, but meanwhile they hold their functions
+func (w *TimerWheel) sweep() {
+	for level := range w.wheels {
+		for slot, timers := range w.wheels[level] {
+			kept := timers[:0]
+			for _, t := range timers {
+				if atomic.LoadInt32(&t.state) == timerScheduled {
+					kept = append(kept, t)
+					continue
+				}
+				atomic.AddInt64(&w.cancelled, -1)
+				w.size--
+			}
+			for i := len(kept); i < len(timers); i++ {
+				timers[i] = nil
+			}
+			w.wheels[level][slot] = kept
+		}
+	}
+}
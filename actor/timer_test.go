@@ -0,0 +1,45 @@
+package actor
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestTimerWheel(t *testing.T) {
+	w := NewTimerWheel(time.Millisecond).Start()
+	defer w.Stop()
+
+	var fired int32
+	for i := 0; i < 10; i++ {
+		w.After(5*time.Millisecond, func() { atomic.AddInt32(&fired, 1) })
+	}
+	w.After(5*time.Millisecond, func() { atomic.AddInt32(&fired, 100) }).Cancel()
+	time.Sleep(50 * time.Millisecond)
+	if n := atomic.LoadInt32(&fired); n != 10 {
+		t.Fatalf("%d timers fired, want 10", n)
+	}
+}
+
+func TestTimerWheelSweep(t *testing.T) {
+	w := NewTimerWheel(time.Millisecond).Start()
+	defer w.Stop()
+
+	for i := 0; i < 2*sweepMin; i++ {
+		w.After(time.Hour, func() {}).Cancel()
+	}
+	deadline := time.Now().Add(time.Second)
+	// the wheel keeps up to sweepMin cancelled timers
+	for atomic.LoadInt64(&w.cancelled) > sweepMin {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d cancelled timers not swept", atomic.LoadInt64(&w.cancelled))
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestTimerWheelStopTwice(t *testing.T) {
+	w := NewTimerWheel(time.Millisecond).Start()
+	w.Stop()
+	w.Stop()
+}
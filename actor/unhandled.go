@@ -0,0 +1,75 @@
+package actor
+
+import "fmt"
+
+// Unhandled is what an actor does with the messages of unknown types, that
+// none of its methods handle. They can be sent through the In channel by a
+// bus or a bridge that doesn't know the actor
+type Unhandled int
+
+const (
+	// UnhandledPanic panics with an UnknownMessage. The panic is recovered
+	// as the panics of the methods, so with MaxAttempts the message ends
+	// quarantined
+	UnhandledPanic Unhandled = iota
+	// UnhandledLog writes the message to Log and drops it
+	UnhandledLog
+	// UnhandledDeadLetter discards the message, passing it to the dead
+	// letter handler with an UnknownMessage as its panic
+	UnhandledDeadLetter
+	// UnhandledHook passes the message to the OnUnhandled method of the
+	// actor
+	UnhandledHook
+)
+
+func (u Unhandled) String() string {
+	switch u {
+	case UnhandledPanic:
+		return "panic"
+	case UnhandledLog:
+		return "log"
+	case UnhandledDeadLetter:
+		return "deadletter"
+	case UnhandledHook:
+		return "hook"
+	}
+	return fmt.Sprintf("Unhandled(%d)", int(u))
+}
+
+// Unhandled returns what the actor does with the messages of unknown types.
+// By default it panics
+func (ba *Actor) Unhandled() Unhandled {
+	return UnhandledPanic
+}
+
+// OnUnhandled is called with the messages of unknown types when Unhandled
+// returns UnhandledHook. It's executed by the actor's goroutine, as its
+// methods. By default it does nothing
+func (ba *Actor) OnUnhandled(msg interface{}) {
+}
+
+// UnknownMessage is the value passed to panic, or the reason of the dead
+// letter, of a message of an unknown type
+type UnknownMessage struct {
+	Actor string
+	Msg   interface{}
+}
+
+func (u UnknownMessage) Error() string {
+	return fmt.Sprintf("%s: unknown message %T", u.Actor, u.Msg)
+}
+
+// HandleUnknown applies the policy u to a message of an unknown type
+// received by the actor name. It is called by the generated code
+func HandleUnknown(name string, u Unhandled, msg interface{}, hook func(interface{})) {
+	switch u {
+	case UnhandledLog:
+		Log.Printf("%s: unknown message %T dropped\n", name, msg)
+	case UnhandledDeadLetter:
+		Discard(name, msg, UnknownMessage{Actor: name, Msg: msg})
+	case UnhandledHook:
+		hook(msg)
+	default:
+		panic(UnknownMessage{Actor: name, Msg: msg})
+	}
+}
@@ -0,0 +1,105 @@
+package actor
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrHandlerTimeout is the error returned to the callers of the methods
+// with a timeout that don't finish in time
+var ErrHandlerTimeout = errors.New("handler timeout")
+
+// Watchdog watches the execution of a method with a timeout. If the method
+// doesn't finish in time the caller receives an error wrapping
+// ErrHandlerTimeout, and the response of the method is discarded. Go can't
+// interrupt the method, so the actor processes the next message when it
+// returns
+type Watchdog struct {
+	state int32
+	timer *Timer
+}
+
+// watchdogTick is the resolution of the timer wheel of the watchdogs: a
+// watchdog expires up to watchdogTick after its timeout
+const watchdogTick = 10 * time.Millisecond
+
+var (
+	watchdogsOnce sync.Once
+	watchdogs     *TimerWheel
+)
+
+// watchdogWheel returns the timer wheel shared by the watchdogs, started
+// the first time a method with a timeout is executed
+func watchdogWheel() *TimerWheel {
+	watchdogsOnce.Do(func() {
+		watchdogs = NewTimerWheel(watchdogTick).Start()
+	})
+	return watchdogs
+}
+
+// Watchdog states
+const (
+	watching int32 = iota
+	finished
+	expired
+)
+
+// Watch starts the watchdog of the method. When the timeout expires the
+// error is sent to out, if it's not nil and has room for it. It is called by the generated code
+// before executing the methods with a timeout. The watchdogs are timers of
+// a TimerWheel, so the methods don't start a goroutine each
+func Watch(method string, timeout time.Duration, out chan interface{}) *Watchdog {
+	w := &Watchdog{}
+	w.timer = watchdogWheel().After(timeout, func() {
+		if !atomic.CompareAndSwapInt32(&w.state, watching, expired) {
+			return
+		}
+		Log.Printf("%s: handler timeout after %v\n", method, timeout)
+		if out != nil {
+			// out has room for the error, as the response won't be sent,
+			// and the wheel must not block
+			select {
+			case out <- fmt.Errorf("%s: %w after %v", method, ErrHandlerTimeout, timeout):
+			default:
+			}
+		}
+	})
+	return w
+}
+
+// StartWatchdog starts the watchdog of the method being executed by the
+// actor, as Watch. If the message is being processed again after a panic
+// and the watchdog of the previous attempt expired, it returns that one
+// instead, as the caller already received the error. It is called by the
+// generated code before executing the methods with a timeout
+func (ba *Actor) StartWatchdog(method string, timeout time.Duration, out chan interface{}) *Watchdog {
+	if w := ba.watchdog; w != nil && atomic.LoadInt32(&w.state) == expired {
+		return w
+	}
+	ba.watchdog = Watch(method, timeout, out)
+	return ba.watchdog
+}
+
+// TimedOut returns true if the watchdog of the message just processed
+// expired, so its caller received the timeout error and nothing else
+// should be sent to it, and forgets the watchdog. It is called by the
+// generated code after processing each message of the actors with timeouts
+func (ba *Actor) TimedOut() bool {
+	w := ba.watchdog
+	ba.watchdog = nil
+	return w != nil && atomic.LoadInt32(&w.state) == expired
+}
+
+// Stop stops the watchdog. It returns true if the method finished in time,
+// so its response should be sent, or false if the timeout expired or the
+// watchdog was already stopped
+func (w *Watchdog) Stop() bool {
+	if !atomic.CompareAndSwapInt32(&w.state, watching, finished) {
+		return false
+	}
+	w.timer.Cancel()
+	return true
+}
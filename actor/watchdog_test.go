@@ -0,0 +1,55 @@
+package actor
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestTimeoutThenPanic processes, as the generated code does, a request of
+// a method with a timeout that expires and then panics on every attempt.
+// The caller receives the timeout error, and neither the retry nor the
+// dead letter block the actor sending more to it
+func TestTimeoutThenPanic(t *testing.T) {
+	var ba Actor
+	out := make(chan interface{}, 1)
+	handle := func(msg interface{}) {
+		watchdog := ba.StartWatchdog("test.slow", time.Millisecond, out)
+		defer watchdog.Stop()
+		for len(out) == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		panic("broken")
+	}
+
+	done := make(chan *DeadLetter)
+	go func() {
+		done <- ProcessUnmetered("test", 3, "request", handle)
+	}()
+	var dl *DeadLetter
+	select {
+	case dl = <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the actor blocked processing the request")
+	}
+	if dl == nil || dl.Attempts != 3 {
+		t.Fatalf("got dead letter %v, want one after 3 attempts", dl)
+	}
+	if !ba.TimedOut() {
+		t.Fatal("TimedOut returned false for an expired watchdog")
+	}
+	if ba.TimedOut() {
+		t.Fatal("TimedOut didn't forget the watchdog")
+	}
+
+	err, _ := (<-out).(error)
+	if !errors.Is(err, ErrHandlerTimeout) {
+		t.Fatalf("got %v, want an error wrapping ErrHandlerTimeout", err)
+	}
+	time.Sleep(20 * time.Millisecond)
+	select {
+	case v := <-out:
+		t.Fatalf("got %v after the timeout error", v)
+	default:
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"encoding/json"
 	"flag"
 	"fmt"
 	"go/format"
@@ -16,9 +17,46 @@ import (
 var act actor.Actor
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "doctor" {
+		doctor(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "attach" {
+		attach(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "docs" {
+		docs(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "compat" {
+		compat(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "serve" {
+		serve(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "deadletters" {
+		deadLetters(os.Args[2:])
+		return
+	}
+
 	input := flag.String("i", "", "input file")
 	output := flag.String("o", "", "output file")
 	verbose := flag.Bool("v", false, "verbose console output (for debbuging)")
+	loose := flag.Bool("loose", false, "generate code even if the input file doesn't type check")
+	fakes := flag.String("fakes", "", "test helpers output file (_test.go)")
+	fuzz := flag.String("fuzz", "", "fuzz targets output file (_test.go)")
+	linCheck := flag.String("lincheck", "", "linearizability tests output file (_test.go)")
+	hooks := flag.String("hooks", "", "skeleton of the hooks of the actors, written only if the file doesn't exist")
+	report := flag.String("report", "", "generation report file (JSON)")
+	auditFile := flag.String("audit", "", "hot path audit file (JSON)")
+	suffix := flag.String("suffix", "", "output file suffix, used when there is no output file")
+	tags := flag.String("tags", "", "build constraint of the output file")
+	header := flag.String("header", "", "file with the header comment of the output file")
+	jsonMethods := flag.Bool("json", false, "generate JSON methods of the messages that don't use reflection")
+	diagFormat := flag.String("diag-format", "text", "format of the errors and warnings of the input file: text or json")
 
 	flag.Parse()
 
@@ -26,6 +64,10 @@ func main() {
 		log.SetOutput(ioutil.Discard)
 	}
 
+	if *output == "" && *suffix != "" {
+		*output = strings.TrimSuffix(*input, ".go") + *suffix + ".go"
+	}
+
 	log.Printf("input file: %s\n", *input)
 	log.Printf("output file: %s\n", *output)
 
@@ -34,15 +76,52 @@ func main() {
 		os.Exit(1)
 	}
 
+	if *diagFormat != "text" && *diagFormat != "json" {
+		fmt.Printf("Unknown diagnostics format %s\n", *diagFormat)
+		os.Exit(1)
+	}
+
 	if *output == *input {
 		fmt.Println("Input file and output file are the same")
 		os.Exit(2)
 	}
 
-	actors, err := compiler.ParseFile(*input)
-	if err != nil {
+	parse := compiler.ParseFile
+	if *loose {
+		parse = compiler.ParseFileLoose
+	}
+	actors, err := parse(*input)
+	if *diagFormat == "json" {
+		if err != nil {
+			writeDiagnostics(compiler.DiagnosticsOf(*input, err))
+			os.Exit(3)
+		}
+	} else if err != nil {
 		fmt.Printf("%s\n", err)
 		os.Exit(3)
+	} else if *loose {
+		for _, w := range actors.Warnings {
+			fmt.Fprintf(os.Stderr, "warning: %s\n", w)
+		}
+	}
+
+	switch {
+	case actors.BuildTags == "":
+		actors.BuildTags = *tags
+	case *tags != "":
+		actors.BuildTags = "(" + actors.BuildTags + ") && (" + *tags + ")"
+	}
+	if *header != "" {
+		text, err := ioutil.ReadFile(*header)
+		if err != nil {
+			fmt.Printf("Unable to read header file %s\n", *header)
+			os.Exit(3)
+		}
+		actors.Header = compiler.HeaderComment(string(text))
+	}
+
+	if *jsonMethods {
+		actors.EnableJSON()
 	}
 
 	var bldr strings.Builder
@@ -55,6 +134,22 @@ func main() {
 		os.Exit(4)
 	}
 
+	// the generation fails if the hot path breaks the contract of an actor
+	audit, err := compiler.AuditHotPath(actors, src)
+	if *diagFormat == "json" {
+		diags := actors.Diagnostics
+		if err != nil {
+			diags = append(diags, compiler.DiagnosticsOf(*input, err)...)
+		}
+		writeDiagnostics(diags)
+		if err != nil {
+			os.Exit(8)
+		}
+	} else if err != nil {
+		fmt.Printf("%s\n", err)
+		os.Exit(8)
+	}
+
 	var out *os.File
 	if *output == "" {
 		out = os.Stdout
@@ -70,4 +165,112 @@ func main() {
 	// compiler.Generate(out, actors)
 
 	out.Write(src)
+
+	if *fakes != "" {
+		var bldr strings.Builder
+		compiler.GenerateFakes(&bldr, actors)
+		src, err := format.Source([]byte(bldr.String()))
+		if err == nil {
+			err = ioutil.WriteFile(*fakes, src, 0644)
+		}
+		if err != nil {
+			fmt.Printf("Unable to write test helpers file %s: %s\n", *fakes, err)
+			os.Exit(7)
+		}
+	}
+
+	if *fuzz != "" {
+		var bldr strings.Builder
+		compiler.GenerateFuzz(&bldr, actors)
+		src, err := format.Source([]byte(bldr.String()))
+		if err == nil {
+			err = ioutil.WriteFile(*fuzz, src, 0644)
+		}
+		if err != nil {
+			fmt.Printf("Unable to write fuzz targets file %s: %s\n", *fuzz, err)
+			os.Exit(7)
+		}
+	}
+
+	if *linCheck != "" {
+		var bldr strings.Builder
+		compiler.GenerateLinCheck(&bldr, actors)
+		src, err := format.Source([]byte(bldr.String()))
+		if err == nil {
+			err = ioutil.WriteFile(*linCheck, src, 0644)
+		}
+		if err != nil {
+			fmt.Printf("Unable to write linearizability tests file %s: %s\n", *linCheck, err)
+			os.Exit(7)
+		}
+	}
+
+	if *hooks != "" {
+		if _, err := os.Stat(*hooks); os.IsNotExist(err) {
+			var bldr strings.Builder
+			compiler.GenerateHooks(&bldr, actors)
+			src, err := format.Source([]byte(bldr.String()))
+			if err == nil {
+				err = ioutil.WriteFile(*hooks, src, 0644)
+			}
+			if err != nil {
+				fmt.Printf("Unable to write hooks file %s: %s\n", *hooks, err)
+				os.Exit(7)
+			}
+		}
+	}
+
+	if *report != "" {
+		data, err := json.MarshalIndent(compiler.NewReport(actors), "", "  ")
+		if err == nil {
+			err = ioutil.WriteFile(*report, append(data, '\n'), 0644)
+		}
+		if err != nil {
+			fmt.Printf("Unable to write report file %s: %s\n", *report, err)
+			os.Exit(6)
+		}
+	}
+
+	if *auditFile != "" {
+		data, err := json.MarshalIndent(audit, "", "  ")
+		if err == nil {
+			err = ioutil.WriteFile(*auditFile, append(data, '\n'), 0644)
+		}
+		if err != nil {
+			fmt.Printf("Unable to write audit file %s: %s\n", *auditFile, err)
+			os.Exit(6)
+		}
+	}
+}
+
+// writeDiagnostics writes the diagnostics to the standard error as a JSON
+// array, empty if there are none
+func writeDiagnostics(diags compiler.Diagnostics) {
+	if diags == nil {
+		diags = compiler.Diagnostics{}
+	}
+	data, _ := json.MarshalIndent(diags, "", "  ")
+	os.Stderr.Write(append(data, '\n'))
+}
+
+// doctor runs the doctor command: actorc doctor [dir]
+func doctor(args []string) {
+	var dir = "."
+	if len(args) > 0 {
+		dir = args[0]
+	}
+
+	log.SetOutput(ioutil.Discard)
+	findings, err := compiler.Doctor(dir)
+	if err != nil {
+		fmt.Printf("%s\n", err)
+		os.Exit(3)
+	}
+
+	for _, f := range findings {
+		fmt.Println(f)
+	}
+	if len(findings) > 0 {
+		os.Exit(1)
+	}
 }
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// actorVars are the metrics of the actors published by a process, by actor name
+type actorVars map[string]map[string]int64
+
+// attach runs the attach command: actorc attach addr
+func attach(args []string) {
+	if len(args) != 1 {
+		fmt.Println("Usage: actorc attach addr")
+		os.Exit(1)
+	}
+	url := varsURL(args[0])
+	if _, err := fetchVars(url); err != nil {
+		fmt.Printf("%s\n", err)
+		os.Exit(3)
+	}
+
+	fmt.Printf("Attached to %s. Type help for the list of commands\n", url)
+	repl(url, os.Stdin, os.Stdout)
+}
+
+// varsURL returns the URL of the expvar endpoint of the process at addr
+func varsURL(addr string) string {
+	if !strings.HasPrefix(addr, "http://") && !strings.HasPrefix(addr, "https://") {
+		addr = "http://" + addr
+	}
+	if strings.Count(addr, "/") == 2 {
+		addr += "/debug/vars"
+	}
+	return addr
+}
+
+// fetchVars reads the metrics of the actors from the expvar endpoint
+func fetchVars(url string) (actorVars, error) {
+	resp, err := http.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %s", url, resp.Status)
+	}
+
+	var vars struct {
+		Goactors actorVars `json:"goactors"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&vars); err != nil {
+		return nil, fmt.Errorf("%s: %v", url, err)
+	}
+	if vars.Goactors == nil {
+		return nil, fmt.Errorf("%s: no goactors metrics published", url)
+	}
+	return vars.Goactors, nil
+}
+
+const attachHelp = `Commands:
+  list                 list the actors and their metrics
+  show <actor>         show the metrics of an actor
+  watch <actor> [n]    show the messages processed by an actor every second, n times (10 by default)
+  help                 show this help
+  quit                 detach from the process`
+
+// repl reads commands from in and writes their results to out
+func repl(url string, in io.Reader, out io.Writer) {
+	scanner := bufio.NewScanner(in)
+	for {
+		fmt.Fprint(out, "> ")
+		if !scanner.Scan() {
+			fmt.Fprintln(out)
+			return
+		}
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		var err error
+		switch fields[0] {
+		case "list":
+			err = list(url, out)
+		case "show":
+			if len(fields) != 2 {
+				err = fmt.Errorf("usage: show <actor>")
+				break
+			}
+			err = show(url, fields[1], out)
+		case "watch":
+			n := 10
+			if len(fields) == 3 {
+				n, err = strconv.Atoi(fields[2])
+			}
+			if len(fields) < 2 || len(fields) > 3 || err != nil {
+				err = fmt.Errorf("usage: watch <actor> [n]")
+				break
+			}
+			err = watch(url, fields[1], n, out)
+		case "help":
+			fmt.Fprintln(out, attachHelp)
+		case "quit", "exit":
+			return
+		default:
+			err = fmt.Errorf("unknown command %s, type help for the list of commands", fields[0])
+		}
+		if err != nil {
+			fmt.Fprintln(out, err)
+		}
+	}
+}
+
+func list(url string, out io.Writer) error {
+	vars, err := fetchVars(url)
+	if err != nil {
+		return err
+	}
+	var names []string
+	for name := range vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintf(out, "%-24s %10s %10s %8s %8s\n", "ACTOR", "IN", "OUT", "PANICS", "QUEUE")
+	for _, name := range names {
+		m := vars[name]
+		fmt.Fprintf(out, "%-24s %10d %10d %8d %8d\n", name, m["in"], m["out"], m["panics"], m["queue"])
+	}
+	return nil
+}
+
+func show(url, name string, out io.Writer) error {
+	vars, err := fetchVars(url)
+	if err != nil {
+		return err
+	}
+	m, ok := vars[name]
+	if !ok {
+		return fmt.Errorf("no actor named %s", name)
+	}
+	fmt.Fprintf(out, "messages received:  %d\n", m["in"])
+	fmt.Fprintf(out, "messages processed: %d\n", m["out"])
+	fmt.Fprintf(out, "panics:             %d\n", m["panics"])
+	fmt.Fprintf(out, "queued messages:    %d\n", m["queue"])
+	fmt.Fprintf(out, "dropped responses:  %d\n", m["dropped"])
+	return nil
+}
+
+func watch(url, name string, n int, out io.Writer) error {
+	var last int64 = -1
+	for i := 0; i <= n; i++ {
+		if i > 0 {
+			time.Sleep(time.Second)
+		}
+		vars, err := fetchVars(url)
+		if err != nil {
+			return err
+		}
+		m, ok := vars[name]
+		if !ok {
+			return fmt.Errorf("no actor named %s", name)
+		}
+		if last >= 0 {
+			fmt.Fprintf(out, "%s %d msg/s, %d queued, %d panics\n", time.Now().Format("15:04:05"), m["out"]-last, m["queue"], m["panics"])
+		}
+		last = m["out"]
+	}
+	return nil
+}
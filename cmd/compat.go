@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+
+	"github.com/carevaloc/goactors/compiler"
+)
+
+// compat runs the compat command: actorc compat old.go new.go
+func compat(args []string) {
+	if len(args) != 2 {
+		fmt.Println("Usage: actorc compat old.go new.go")
+		os.Exit(2)
+	}
+
+	log.SetOutput(ioutil.Discard)
+	old, err := parseSpec(args[0])
+	if err != nil {
+		fmt.Printf("%s\n", err)
+		os.Exit(3)
+	}
+	new, err := parseSpec(args[1])
+	if err != nil {
+		fmt.Printf("%s\n", err)
+		os.Exit(3)
+	}
+
+	changes := compiler.Compat(old.Spec(), new.Spec())
+	for _, c := range changes {
+		fmt.Println(c)
+	}
+	if len(changes) > 0 {
+		os.Exit(1)
+	}
+}
+
+// parseSpec parses a source file, in loose mode if it doesn't type check on
+// its own, as the old versions taken from the history
+func parseSpec(fileName string) (compiler.Package, error) {
+	pkg, err := compiler.ParseFile(fileName)
+	if err != nil {
+		pkg, err = compiler.ParseFileLoose(fileName)
+	}
+	if err != nil {
+		return compiler.Package{}, fmt.Errorf("%s: %v", fileName, err)
+	}
+	return pkg, nil
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/carevaloc/goactors/actor/deadlog"
+)
+
+// deadLetters runs the deadletters command: actorc deadletters [-actor
+// name] [-message name] [-since time] [-until time] [-json] [-stack]
+// [-replay url [-to name]] file
+func deadLetters(args []string) {
+	flags := flag.NewFlagSet("deadletters", flag.ExitOnError)
+	actorName := flags.String("actor", "", "show only the dead letters of the actor")
+	message := flags.String("message", "", "show only the messages whose name contains this text")
+	since := flags.String("since", "", "show only the dead letters since this time, RFC 3339 or a duration ago like 2h")
+	until := flags.String("until", "", "show only the dead letters until this time, RFC 3339 or a duration ago like 2h")
+	asJSON := flags.Bool("json", false, "write the dead letters as JSON, one per line")
+	stack := flags.Bool("stack", false, "show the stacks of the panics")
+	replayURL := flags.String("replay", "", "replay the dead letters shown into the System served by the admin page at this URL")
+	to := flags.String("to", "", "name of the actor the dead letters are replayed to, by default the actor of each one")
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		fmt.Println("Usage: actorc deadletters [-actor name] [-message name] [-since time] [-until time] [-json] [-stack] [-replay url [-to name]] file")
+		os.Exit(1)
+	}
+
+	now := time.Now()
+	start, err := parseTime(*since, now)
+	if err != nil {
+		fmt.Printf("Invalid -since: %s\n", err)
+		os.Exit(1)
+	}
+	end, err := parseTime(*until, now)
+	if err != nil {
+		fmt.Printf("Invalid -until: %s\n", err)
+		os.Exit(1)
+	}
+
+	f, err := os.Open(flags.Arg(0))
+	if err != nil {
+		fmt.Printf("%s\n", err)
+		os.Exit(3)
+	}
+	defer f.Close()
+	r, err := deadlog.NewReader(f)
+	if err != nil {
+		fmt.Printf("%s: %s\n", flags.Arg(0), err)
+		os.Exit(3)
+	}
+
+	var replayed, failed int
+	for {
+		e, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			fmt.Printf("%s: %s\n", flags.Arg(0), err)
+			os.Exit(3)
+		}
+		if *actorName != "" && e.Actor != *actorName || !strings.Contains(e.Message, *message) ||
+			!start.IsZero() && e.Time.Before(start) || !end.IsZero() && e.Time.After(end) {
+			continue
+		}
+		if !*stack {
+			e.Stack = ""
+		}
+		if *asJSON {
+			data, _ := json.Marshal(e)
+			fmt.Println(string(data))
+		} else {
+			printDeadLetter(e)
+		}
+
+		if *replayURL == "" {
+			continue
+		}
+		name := e.Actor
+		if *to != "" {
+			name = *to
+		}
+		if err := replayDeadLetter(*replayURL, name, e); err != nil {
+			fmt.Fprintf(os.Stderr, "Not replayed: %s\n", err)
+			failed++
+			continue
+		}
+		replayed++
+	}
+	if *replayURL != "" {
+		fmt.Fprintf(os.Stderr, "%d dead letters replayed, %d failed\n", replayed, failed)
+		if failed > 0 {
+			os.Exit(4)
+		}
+	}
+}
+
+// parseTime parses a time in RFC 3339, or a duration before now. An empty
+// string is the zero time
+func parseTime(s string, now time.Time) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return now.Add(-d), nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
+// printDeadLetter writes an entry of the log as text
+func printDeadLetter(e deadlog.Entry) {
+	what := "discarded"
+	if e.Attempts > 0 {
+		what = fmt.Sprintf("quarantined after %d attempts", e.Attempts)
+	}
+	fmt.Printf("%s %s %s v%d %s: %s\n", e.Time.Format(time.RFC3339Nano), e.Actor, e.Message, e.Version, what, e.Panic)
+	if e.Payload != nil {
+		fmt.Printf("\t%s\n", e.Payload)
+	}
+	if e.Stack != "" {
+		fmt.Printf("\t%s\n", strings.ReplaceAll(strings.TrimSpace(e.Stack), "\n", "\n\t"))
+	}
+}
+
+// replayDeadLetter sends an entry to the replay endpoint of the admin page
+// at base
+func replayDeadLetter(base, name string, e deadlog.Entry) error {
+	if e.Payload == nil {
+		return fmt.Errorf("%s %s: no payload, the actor doesn't have the json feature", e.Actor, e.Message)
+	}
+	if !strings.HasPrefix(base, "http://") && !strings.HasPrefix(base, "https://") {
+		base = "http://" + base
+	}
+	resp, err := http.PostForm(strings.TrimSuffix(base, "/")+"/api/replay", url.Values{
+		"name":    {name},
+		"message": {e.Message},
+		"version": {strconv.Itoa(e.Version)},
+		"payload": {string(e.Payload)},
+	})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("%s %s: %s", e.Actor, e.Message, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
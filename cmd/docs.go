@@ -0,0 +1,47 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/carevaloc/goactors/compiler"
+)
+
+// docs runs the docs command: actorc docs [-format markdown|html] [-o file] [dir]
+func docs(args []string) {
+	flags := flag.NewFlagSet("docs", flag.ExitOnError)
+	format := flags.String("format", "markdown", "output format: markdown or html")
+	output := flags.String("o", "", "output file")
+	flags.Parse(args)
+
+	var dir = "."
+	if flags.NArg() > 0 {
+		dir = flags.Arg(0)
+	}
+
+	log.SetOutput(ioutil.Discard)
+	files, err := compiler.Docs(dir)
+	if err != nil {
+		fmt.Printf("%s\n", err)
+		os.Exit(3)
+	}
+
+	var bldr strings.Builder
+	if err := compiler.GenerateDocs(&bldr, files, *format); err != nil {
+		fmt.Printf("%s\n", err)
+		os.Exit(1)
+	}
+
+	if *output == "" {
+		fmt.Print(bldr.String())
+		return
+	}
+	if err := ioutil.WriteFile(*output, []byte(bldr.String()), 0644); err != nil {
+		fmt.Printf("Unable to write docs file %s: %s\n", *output, err)
+		os.Exit(5)
+	}
+}
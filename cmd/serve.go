@@ -0,0 +1,205 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"go/format"
+	"io"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"os"
+	"strings"
+
+	"github.com/carevaloc/goactors/compiler"
+)
+
+// Service is the JSON-RPC service of actorc serve, registered as Actorc.
+// The errors of the input files aren't errors of the calls: they are
+// returned in the diagnostics of the replies
+type Service struct {
+	session *compiler.Session
+}
+
+// DiagnoseArgs are the arguments of Actorc.Diagnose
+type DiagnoseArgs struct {
+	// File is the name of the input file
+	File string `json:"file"`
+	// Src is the source of the file, like the unsaved buffer of an editor.
+	// If it's empty the file is read
+	Src string `json:"src,omitempty"`
+	// Loose parses the file as the -loose flag
+	Loose bool `json:"loose,omitempty"`
+}
+
+// DiagnoseReply is the reply of Actorc.Diagnose
+type DiagnoseReply struct {
+	Diagnostics compiler.Diagnostics `json:"diagnostics"`
+}
+
+// GenerateArgs are the arguments of Actorc.Generate. Tags, Header, JSON,
+// Fakes, Fuzz, LinCheck and Audit are the flags of actorc with the same
+// names, but Header is the text of the header instead of a file, and Fakes,
+// Fuzz, LinCheck and Audit return the test helpers, the fuzz targets, the
+// linearizability tests and the hot path audit in the reply
+type GenerateArgs struct {
+	DiagnoseArgs
+	Tags     string `json:"tags,omitempty"`
+	Header   string `json:"header,omitempty"`
+	JSON     bool   `json:"json,omitempty"`
+	Fakes    bool   `json:"fakes,omitempty"`
+	Fuzz     bool   `json:"fuzz,omitempty"`
+	LinCheck bool   `json:"lincheck,omitempty"`
+	Audit    bool   `json:"audit,omitempty"`
+}
+
+// GenerateReply is the reply of Actorc.Generate. Code, Fakes, Fuzz,
+// LinCheck and Audit are empty if the file has errors or the hot path of an
+// actor breaks its contract
+type GenerateReply struct {
+	Code        string                 `json:"code"`
+	Fakes       string                 `json:"fakes,omitempty"`
+	Fuzz        string                 `json:"fuzz,omitempty"`
+	LinCheck    string                 `json:"lincheck,omitempty"`
+	Audit       *compiler.HotPathAudit `json:"audit,omitempty"`
+	Diagnostics compiler.Diagnostics   `json:"diagnostics"`
+}
+
+// Empty are the arguments and the reply of the calls without them
+type Empty struct{}
+
+// Diagnose returns the errors and warnings of a file
+func (s *Service) Diagnose(args DiagnoseArgs, reply *DiagnoseReply) error {
+	pkg, err := s.session.Parse(args.File, args.Src, args.Loose)
+	reply.Diagnostics = diagnosticsOf(args.File, pkg, err)
+	return nil
+}
+
+// Generate returns the code generated for a file, and its errors and
+// warnings
+func (s *Service) Generate(args GenerateArgs, reply *GenerateReply) error {
+	pkg, err := s.session.Parse(args.File, args.Src, args.Loose)
+	reply.Diagnostics = diagnosticsOf(args.File, pkg, err)
+	if err != nil {
+		return nil
+	}
+
+	switch {
+	case pkg.BuildTags == "":
+		pkg.BuildTags = args.Tags
+	case args.Tags != "":
+		pkg.BuildTags = "(" + pkg.BuildTags + ") && (" + args.Tags + ")"
+	}
+	if args.Header != "" {
+		pkg.Header = compiler.HeaderComment(args.Header)
+	}
+	if args.JSON {
+		pkg.EnableJSON()
+	}
+
+	var bldr strings.Builder
+	compiler.Generate(&bldr, pkg)
+	src, err := format.Source([]byte(bldr.String()))
+	if err != nil {
+		return err
+	}
+	audit, err := compiler.AuditHotPath(pkg, src)
+	if err != nil {
+		reply.Diagnostics = append(reply.Diagnostics, compiler.DiagnosticsOf(args.File, err)...)
+		return nil
+	}
+	reply.Code = string(src)
+	if args.Audit {
+		reply.Audit = &audit
+	}
+
+	if args.Fakes {
+		var bldr strings.Builder
+		compiler.GenerateFakes(&bldr, pkg)
+		src, err := format.Source([]byte(bldr.String()))
+		if err != nil {
+			return err
+		}
+		reply.Fakes = string(src)
+	}
+	if args.Fuzz {
+		var bldr strings.Builder
+		compiler.GenerateFuzz(&bldr, pkg)
+		src, err := format.Source([]byte(bldr.String()))
+		if err != nil {
+			return err
+		}
+		reply.Fuzz = string(src)
+	}
+	if args.LinCheck {
+		var bldr strings.Builder
+		compiler.GenerateLinCheck(&bldr, pkg)
+		src, err := format.Source([]byte(bldr.String()))
+		if err != nil {
+			return err
+		}
+		reply.LinCheck = string(src)
+	}
+	return nil
+}
+
+// Reset forgets the imported packages and the files parsed. It should be
+// called when the imported packages change
+func (s *Service) Reset(args Empty, reply *Empty) error {
+	s.session.Reset()
+	return nil
+}
+
+// diagnosticsOf returns the diagnostics of a parsed file, never nil so
+// they are encoded as an array
+func diagnosticsOf(fileName string, pkg compiler.Package, err error) compiler.Diagnostics {
+	diags := pkg.Diagnostics
+	if err != nil {
+		diags = compiler.DiagnosticsOf(fileName, err)
+	}
+	if diags == nil {
+		diags = compiler.Diagnostics{}
+	}
+	return diags
+}
+
+// stdio is the connection of the service when it's run by an editor
+type stdio struct {
+	io.Reader
+	io.Writer
+}
+
+func (stdio) Close() error {
+	return nil
+}
+
+// serve runs the serve command: actorc serve [-listen address]
+func serve(args []string) {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	listen := flags.String("listen", "", "TCP address to listen to, instead of the standard input and output")
+	flags.Parse(args)
+
+	log.SetOutput(ioutil.Discard)
+	server := rpc.NewServer()
+	server.RegisterName("Actorc", &Service{session: compiler.NewSession()})
+
+	if *listen == "" {
+		server.ServeCodec(jsonrpc.NewServerCodec(stdio{os.Stdin, os.Stdout}))
+		return
+	}
+	l, err := net.Listen("tcp", *listen)
+	if err != nil {
+		fmt.Printf("%s\n", err)
+		os.Exit(3)
+	}
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			fmt.Printf("%s\n", err)
+			os.Exit(3)
+		}
+		go server.ServeCodec(jsonrpc.NewServerCodec(conn))
+	}
+}
@@ -0,0 +1,307 @@
+package compiler
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"sort"
+	"strings"
+)
+
+// Guarantees of the hot path that an actor can declare in its hotpath tag
+const (
+	// GuaranteeNoReflect guarantees that the messages aren't processed with
+	// reflection
+	GuaranteeNoReflect = "noreflect"
+	// GuaranteeNoIface guarantees that there are no type assertions or
+	// conversions to interfaces besides the ones of the messages received
+	// from the mailbox and the response channel, the envelope
+	GuaranteeNoIface = "noiface"
+	// GuaranteeNoLock guarantees that no locks are taken
+	GuaranteeNoLock = "nolock"
+)
+
+// guarantees are the known guarantees
+var guarantees = []string{GuaranteeNoReflect, GuaranteeNoIface, GuaranteeNoLock}
+
+// HotPathAudit is the result of the audit of the hot path of the actors of
+// a package. It is meant to be encoded as JSON, as the proof that the
+// performance contracts hold
+type HotPathAudit struct {
+	Package string          `json:"package"`
+	Actors  []HotPathReport `json:"actors"`
+}
+
+// HotPathReport is the audit of the hot path of an actor: the generated
+// functions run for each message, the runtime functions they call, and
+// which guarantees hold
+type HotPathReport struct {
+	Actor string `json:"actor"`
+	// Contract are the guarantees declared in the hotpath tag
+	Contract   []string        `json:"contract"`
+	Functions  []string        `json:"functions"`
+	Runtime    []string        `json:"runtime"`
+	Guarantees map[string]bool `json:"guarantees"`
+	Violations []Violation     `json:"violations"`
+}
+
+// Violation is a construct of the hot path that breaks a guarantee, in a
+// generated function or in the runtime code of a feature of the actor
+type Violation struct {
+	Guarantee string `json:"guarantee"`
+	Function  string `json:"function,omitempty"`
+	Feature   string `json:"feature,omitempty"`
+	Detail    string `json:"detail"`
+}
+
+func (v Violation) String() string {
+	where := v.Function
+	if v.Feature != "" {
+		where = "feature " + v.Feature
+	}
+	return fmt.Sprintf("%s: %s", where, v.Detail)
+}
+
+// featureViolations returns the guarantees broken by the runtime code of
+// the features of the actor. The runtime functions called by all the
+// actors, like the ones that deliver the messages, are not included
+func (a *Actor) featureViolations() []Violation {
+	var vs []Violation
+	if a.Enabled(FeatureMetrics) {
+		vs = append(vs,
+			Violation{Guarantee: GuaranteeNoIface, Feature: FeatureMetrics, Detail: "the metrics of the actor are loaded from a sync.Map for each message"},
+			Violation{Guarantee: GuaranteeNoReflect, Feature: FeatureMetrics, Detail: "allocation sampling, when enabled, formats the type of the sampled messages"})
+	}
+	if a.Enabled(FeatureJSON) {
+		for _, m := range a.Methods {
+			for _, p := range append(append([]Param(nil), m.Params...), m.RetVals()...) {
+				if p.codec.method == "" && !p.Blob {
+					vs = append(vs, Violation{Guarantee: GuaranteeNoReflect, Feature: FeatureJSON, Detail: fmt.Sprintf("%s of method %s is encoded with encoding/json", p.Type, m.Name)})
+				}
+			}
+		}
+	}
+	if a.weights != nil {
+		vs = append(vs, Violation{Guarantee: GuaranteeNoIface, Feature: "weights", Detail: "the fair queue asserts the requests to actor.Request to read their method"})
+	}
+	if a.HasSeq() {
+		vs = append(vs, Violation{Guarantee: GuaranteeNoLock, Feature: "seq", Detail: "the callers waiting for the sequence tokens are woken with a lock"})
+	}
+	for _, m := range a.Methods {
+		if m.Timeout > 0 {
+			vs = append(vs, Violation{Guarantee: GuaranteeNoLock, Feature: "timeout", Detail: fmt.Sprintf("the watchdog of method %s starts a timer, which locks the timers of the runtime", m.Name)})
+		}
+		for _, p := range m.Params {
+			if p.Blob {
+				vs = append(vs,
+					Violation{Guarantee: GuaranteeNoLock, Feature: "blob", Detail: fmt.Sprintf("parameter %s of method %s is stored in the blob store, guarded by a lock", p.Name, m.Name)},
+					Violation{Guarantee: GuaranteeNoIface, Feature: "blob", Detail: fmt.Sprintf("parameter %s of method %s is stored through the BlobStore interface", p.Name, m.Name)})
+			}
+		}
+	}
+	return vs
+}
+
+// hotPath returns the generated functions run for each message of the
+// actor, by receiver and name
+func (a *Actor) hotPath() map[string]map[string]bool {
+	funcs := map[string]map[string]bool{
+		a.Ref(): {},
+		a.Impl:  {"receive": true, "mailbox": true, "dispatch": true, "handle": true, "slot": true},
+	}
+	for _, m := range a.Methods {
+		funcs[a.Ref()][m.Name] = true
+		funcs[m.Request()] = map[string]bool{"Method": true, "release": true}
+	}
+	return funcs
+}
+
+// AuditHotPath audits the hot path of the actors of the package in src,
+// the code generated for it: it checks the source of the generated
+// functions run for each message, and the runtime code of the features of
+// the actors. It returns an error if the guarantees declared in the hotpath
+// tag of an actor don't hold
+func AuditHotPath(pkg Package, src []byte) (HotPathAudit, error) {
+	audit := HotPathAudit{Package: pkg.Name, Actors: []HotPathReport{}}
+	f, err := parser.ParseFile(token.NewFileSet(), "", src, 0)
+	if err != nil {
+		return audit, err
+	}
+
+	var diags Diagnostics
+	for _, a := range pkg.Actors {
+		report := HotPathReport{Actor: a.Name, Contract: []string{}, Functions: []string{}, Runtime: []string{}, Guarantees: make(map[string]bool)}
+		report.Violations = append([]Violation{}, a.featureViolations()...)
+		runtime := make(map[string]bool)
+		hot := a.hotPath()
+		for _, decl := range f.Decls {
+			fd, ok := decl.(*ast.FuncDecl)
+			if !ok || fd.Recv == nil || fd.Body == nil || !hot[receiverName(fd)][fd.Name.Name] {
+				continue
+			}
+			name := receiverName(fd) + "." + fd.Name.Name
+			report.Functions = append(report.Functions, name)
+			report.Violations = append(report.Violations, auditFunc(name, fd, runtime)...)
+		}
+		for r := range runtime {
+			report.Runtime = append(report.Runtime, r)
+		}
+		sort.Strings(report.Runtime)
+
+		for _, g := range guarantees {
+			report.Guarantees[g] = true
+			if a.contract[g] {
+				report.Contract = append(report.Contract, g)
+			}
+		}
+		for _, v := range report.Violations {
+			report.Guarantees[v.Guarantee] = false
+			if a.contract[v.Guarantee] {
+				diags = append(diags, pkg.diagnosticAt(a.pos, SeverityError, CodeHotPath, fmt.Sprintf("actor %s breaks the %s guarantee of its hotpath tag: %s", a.Name, v.Guarantee, v)))
+			}
+		}
+		audit.Actors = append(audit.Actors, report)
+	}
+	if diags != nil {
+		return audit, diags
+	}
+	return audit, nil
+}
+
+// receiverName returns the name of the type of the receiver of a method
+func receiverName(fd *ast.FuncDecl) string {
+	t := fd.Recv.List[0].Type
+	if star, ok := t.(*ast.StarExpr); ok {
+		t = star.X
+	}
+	if id, ok := t.(*ast.Ident); ok {
+		return id.Name
+	}
+	return ""
+}
+
+// auditFunc returns the violations of the guarantees in the body of a
+// generated function, and adds the runtime functions it calls to runtime.
+// The arguments of panic are skipped, as panics leave the hot path
+func auditFunc(name string, fd *ast.FuncDecl, runtime map[string]bool) []Violation {
+	envelope := envelopes(fd)
+	var vs []Violation
+	ast.Inspect(fd.Body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.CallExpr:
+			if id, ok := n.Fun.(*ast.Ident); ok && id.Name == "panic" {
+				return false
+			}
+			if _, ok := n.Fun.(*ast.InterfaceType); ok {
+				vs = append(vs, Violation{Guarantee: GuaranteeNoIface, Function: name, Detail: "conversion to " + exprString(n.Fun)})
+			}
+			if sel, ok := n.Fun.(*ast.SelectorExpr); ok {
+				switch sel.Sel.Name {
+				case "Lock", "RLock", "Unlock", "RUnlock":
+					vs = append(vs, Violation{Guarantee: GuaranteeNoLock, Function: name, Detail: "call to " + exprString(sel)})
+				}
+				switch x := sel.X.(type) {
+				case *ast.Ident:
+					if x.Name == "actor" {
+						runtime["actor."+sel.Sel.Name] = true
+					} else if x.Name == "act" && ast.IsExported(sel.Sel.Name) {
+						runtime["Actor."+sel.Sel.Name] = true
+					}
+				case *ast.SelectorExpr:
+					if x.Sel.Name == "act" && ast.IsExported(sel.Sel.Name) {
+						runtime["Actor."+sel.Sel.Name] = true
+					}
+				}
+			}
+		case *ast.SelectorExpr:
+			if x, ok := n.X.(*ast.Ident); ok {
+				switch x.Name {
+				case "reflect", "fmt":
+					vs = append(vs, Violation{Guarantee: GuaranteeNoReflect, Function: name, Detail: "use of " + exprString(n)})
+				case "sync":
+					vs = append(vs, Violation{Guarantee: GuaranteeNoLock, Function: name, Detail: "use of " + exprString(n)})
+				}
+			}
+		case *ast.TypeAssertExpr:
+			if !envelope(n.X) {
+				t := "type"
+				if n.Type != nil {
+					t = exprString(n.Type)
+				}
+				vs = append(vs, Violation{Guarantee: GuaranteeNoIface, Function: name, Detail: fmt.Sprintf("assertion of %s to %s", exprString(n.X), t)})
+			}
+		}
+		return true
+	})
+	return vs
+}
+
+// envelopes returns the function that tells if an expression of a generated
+// function is the envelope of a message: a parameter or variable of type
+// interface{}, a value received from a channel, or a variable assigned one
+func envelopes(fd *ast.FuncDecl) func(ast.Expr) bool {
+	names := make(map[string]bool)
+	for _, field := range fd.Type.Params.List {
+		if _, ok := field.Type.(*ast.InterfaceType); ok {
+			for _, n := range field.Names {
+				names[n.Name] = true
+			}
+		}
+	}
+	isReceive := func(e ast.Expr) bool {
+		for {
+			p, ok := e.(*ast.ParenExpr)
+			if !ok {
+				break
+			}
+			e = p.X
+		}
+		u, ok := e.(*ast.UnaryExpr)
+		return ok && u.Op == token.ARROW
+	}
+	ast.Inspect(fd.Body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.ValueSpec:
+			if _, ok := n.Type.(*ast.InterfaceType); ok {
+				for _, id := range n.Names {
+					names[id.Name] = true
+				}
+			}
+		case *ast.AssignStmt:
+			if len(n.Rhs) == 1 && isReceive(n.Rhs[0]) {
+				if id, ok := n.Lhs[0].(*ast.Ident); ok {
+					names[id.Name] = true
+				}
+			}
+		}
+		return true
+	})
+	return func(e ast.Expr) bool {
+		if id, ok := e.(*ast.Ident); ok {
+			return names[id.Name]
+		}
+		return isReceive(e)
+	}
+}
+
+// exprString returns the source of a short expression of the generated code
+func exprString(e ast.Expr) string {
+	switch e := e.(type) {
+	case *ast.Ident:
+		return e.Name
+	case *ast.SelectorExpr:
+		return exprString(e.X) + "." + e.Sel.Name
+	case *ast.StarExpr:
+		return "*" + exprString(e.X)
+	case *ast.CallExpr:
+		return exprString(e.Fun) + "()"
+	case *ast.ArrayType:
+		return "[]" + exprString(e.Elt)
+	case *ast.ParenExpr:
+		return "(" + exprString(e.X) + ")"
+	case *ast.InterfaceType:
+		return "interface"
+	}
+	return strings.TrimPrefix(fmt.Sprintf("%T", e), "*ast.")
+}
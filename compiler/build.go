@@ -0,0 +1,84 @@
+package compiler
+
+import (
+	"go/ast"
+	"go/build/constraint"
+	"path/filepath"
+	"strings"
+)
+
+// knownOS and knownArch are the values of GOOS and GOARCH that restrict the
+// build of a file when they are used as suffixes of its name
+var knownOS = map[string]bool{
+	"aix": true, "android": true, "darwin": true, "dragonfly": true, "freebsd": true,
+	"hurd": true, "illumos": true, "ios": true, "js": true, "linux": true, "nacl": true,
+	"netbsd": true, "openbsd": true, "plan9": true, "solaris": true, "wasip1": true,
+	"windows": true, "zos": true,
+}
+
+var knownArch = map[string]bool{
+	"386": true, "amd64": true, "amd64p32": true, "arm": true, "armbe": true, "arm64": true,
+	"arm64be": true, "loong64": true, "mips": true, "mipsle": true, "mips64": true,
+	"mips64le": true, "mips64p32": true, "mips64p32le": true, "ppc": true, "ppc64": true,
+	"ppc64le": true, "riscv": true, "riscv64": true, "s390": true, "s390x": true,
+	"sparc": true, "sparc64": true, "wasm": true,
+}
+
+// buildConstraint returns the build constraint of the input file: its
+// //go:build (or // +build) lines and the GOOS and GOARCH suffixes of its
+// name, which would be lost in the name of the generated file
+func buildConstraint(f *ast.File, fileName string) (string, error) {
+	var exprs []constraint.Expr
+	var plusBuild []constraint.Expr
+	for _, cg := range f.Comments {
+		if cg.Pos() >= f.Package {
+			break
+		}
+		for _, c := range cg.List {
+			switch {
+			case constraint.IsGoBuild(c.Text):
+				expr, err := constraint.Parse(c.Text)
+				if err != nil {
+					return "", errorAt(c.Pos(), CodeBuildConstraint, "%v", err)
+				}
+				exprs = append(exprs, expr)
+			case constraint.IsPlusBuild(c.Text):
+				expr, err := constraint.Parse(c.Text)
+				if err != nil {
+					return "", errorAt(c.Pos(), CodeBuildConstraint, "%v", err)
+				}
+				plusBuild = append(plusBuild, expr)
+			}
+		}
+	}
+	// //go:build lines take precedence over // +build lines
+	if len(exprs) == 0 {
+		exprs = plusBuild
+	}
+	exprs = append(exprs, nameConstraint(fileName)...)
+
+	if len(exprs) == 0 {
+		return "", nil
+	}
+	expr := exprs[0]
+	for _, e := range exprs[1:] {
+		expr = &constraint.AndExpr{X: expr, Y: e}
+	}
+	return expr.String(), nil
+}
+
+// nameConstraint returns the constraints implied by the name of a file:
+// name_GOOS_GOARCH.go, name_GOOS.go or name_GOARCH.go
+func nameConstraint(fileName string) []constraint.Expr {
+	name := strings.TrimSuffix(filepath.Base(fileName), ".go")
+	name = strings.TrimSuffix(name, "_test")
+	parts := strings.Split(name, "_")
+	n := len(parts)
+	if n >= 3 && knownOS[parts[n-2]] && knownArch[parts[n-1]] {
+		return []constraint.Expr{&constraint.TagExpr{Tag: parts[n-2]}, &constraint.TagExpr{Tag: parts[n-1]}}
+	}
+	if n >= 2 && (knownOS[parts[n-1]] || knownArch[parts[n-1]]) {
+		return []constraint.Expr{&constraint.TagExpr{Tag: parts[n-1]}}
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package compiler
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/carevaloc/goactors/compiler/spec"
+)
+
+// Change is a breaking change of the messages of an actor between two
+// versions of its source
+type Change struct {
+	Actor   string
+	Method  string
+	Message string
+}
+
+func (c Change) String() string {
+	if c.Method == "" {
+		return fmt.Sprintf("%s: %s", c.Actor, c.Message)
+	}
+	return fmt.Sprintf("%s.%s: %s", c.Actor, c.Method, c.Message)
+}
+
+// Compat compares the actors of two versions of a source file and returns
+// the changes that break the callers built with the old version: removed
+// actors and methods, parameters and results added, removed, renamed or
+// with a different type, and methods that switched between synchronous and
+// asynchronous. Parameter names are part of the contract because they are
+// the keys of the JSON encoding of the requests. Added actors and methods
+// aren't breaking
+func Compat(old, new spec.Package) []Change {
+	newActors := make(map[string]spec.Actor)
+	for _, a := range new.Actors() {
+		newActors[a.Name()] = a
+	}
+
+	var changes []Change
+	for _, oa := range old.Actors() {
+		na, ok := newActors[oa.Name()]
+		if !ok {
+			changes = append(changes, Change{Actor: oa.Name(), Message: "actor removed"})
+			continue
+		}
+		newMethods := make(map[string]spec.Method)
+		for _, m := range na.Methods() {
+			newMethods[m.Name()] = m
+		}
+		for _, om := range oa.Methods() {
+			nm, ok := newMethods[om.Name()]
+			if !ok {
+				changes = append(changes, Change{Actor: oa.Name(), Method: om.Name(), Message: "method removed"})
+				continue
+			}
+			for _, msg := range compareMethods(om, nm) {
+				changes = append(changes, Change{Actor: oa.Name(), Method: om.Name(), Message: msg})
+			}
+		}
+	}
+	return changes
+}
+
+// compareMethods returns the breaking changes between two versions of a
+// method
+func compareMethods(old, new spec.Method) []string {
+	var changes []string
+	switch {
+	case old.Async() && !new.Async():
+		changes = append(changes, "switched from asynchronous to synchronous")
+	case !old.Async() && new.Async():
+		changes = append(changes, "switched from synchronous to asynchronous")
+	}
+	changes = append(changes, compareParams("parameter", old.Params(), new.Params())...)
+	changes = append(changes, compareParams("result", old.Results(), new.Results())...)
+	return changes
+}
+
+// compareParams returns the breaking changes between two versions of the
+// parameters or the results of a method
+func compareParams(kind string, old, new []spec.Param) []string {
+	if len(old) != len(new) {
+		return []string{fmt.Sprintf("%ss changed from (%s) to (%s)", kind, paramList(old), paramList(new))}
+	}
+	var changes []string
+	for i := range old {
+		if old[i].Type != new[i].Type {
+			changes = append(changes, fmt.Sprintf("%s %d type changed from %s to %s", kind, i+1, old[i].Type, new[i].Type))
+		}
+		if old[i].Name != new[i].Name {
+			changes = append(changes, fmt.Sprintf("%s %d renamed from %q to %q", kind, i+1, old[i].Name, new[i].Name))
+		}
+	}
+	return changes
+}
+
+func paramList(params []spec.Param) string {
+	var list []string
+	for _, p := range params {
+		if p.Name != "" {
+			list = append(list, p.Name+" "+p.Type)
+		} else {
+			list = append(list, p.Type)
+		}
+	}
+	return strings.Join(list, ", ")
+}
@@ -0,0 +1,76 @@
+package compiler
+
+import (
+	"fmt"
+	"go/ast"
+	"go/types"
+	"strings"
+)
+
+// Conversion is a struct type of the package whose fields hold the
+// parameters of a method, so callers holding a value of the type can send it
+// without unpacking its fields
+type Conversion struct {
+	Type   string
+	Fields []ConversionField
+}
+
+// ConversionField is the field of the struct type that holds a parameter
+type ConversionField struct {
+	Field string
+	Param Param
+}
+
+// parseConvertTag adds the method=Type pairs in the convert tag to the map
+// passed as parameter
+func parseConvertTag(name, str string, convert map[string]string) error {
+	for _, item := range strings.Split(str, ",") {
+		kv := strings.SplitN(item, "=", 2)
+		if len(kv) != 2 || strings.TrimSpace(kv[0]) == "" || strings.TrimSpace(kv[1]) == "" {
+			return fmt.Errorf("actor %s: invalid conversion %q, expected method=Type", name, item)
+		}
+		convert[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+	}
+	return nil
+}
+
+// conversion matches the parameters of a method with the fields of the
+// struct type typeName. Each parameter must have a field with the same name,
+// ignoring case, and the same type
+func conversion(fd *ast.FuncDecl, tw *typeWriter, params []Param, typeName string) (*Conversion, error) {
+	if tw.pkg == nil || tw.info == nil {
+		return nil, fmt.Errorf("method %s: the conversion from %s needs type information", fd.Name.Name, typeName)
+	}
+	tn, ok := tw.pkg.Scope().Lookup(typeName).(*types.TypeName)
+	if !ok {
+		return nil, fmt.Errorf("method %s: type %s of the conversion is not declared in the package", fd.Name.Name, typeName)
+	}
+	st, ok := tn.Type().Underlying().(*types.Struct)
+	if !ok {
+		return nil, fmt.Errorf("method %s: type %s of the conversion is not a struct", fd.Name.Name, typeName)
+	}
+
+	conv := &Conversion{Type: typeName}
+	i := 0
+	for _, field := range fd.Type.Params.List {
+		for range field.Names {
+			param := params[i]
+			i++
+			var match *types.Var
+			for j := 0; j < st.NumFields(); j++ {
+				if strings.EqualFold(st.Field(j).Name(), param.Name) {
+					match = st.Field(j)
+					break
+				}
+			}
+			if match == nil {
+				return nil, fmt.Errorf("method %s: type %s has no field for parameter %s", fd.Name.Name, typeName, param.Name)
+			}
+			if tv, ok := tw.info.Types[field.Type]; !ok || !types.Identical(tv.Type, match.Type()) {
+				return nil, fmt.Errorf("method %s: field %s of type %s is not a %s", fd.Name.Name, match.Name(), typeName, param.Type)
+			}
+			conv.Fields = append(conv.Fields, ConversionField{Field: match.Name(), Param: param})
+		}
+	}
+	return conv, nil
+}
@@ -0,0 +1,177 @@
+package compiler
+
+import (
+	"fmt"
+	"go/scanner"
+	"go/token"
+	"go/types"
+	"strings"
+)
+
+// Severity is the severity of a diagnostic
+type Severity string
+
+// Severities of the diagnostics. Notes add details to the error or warning
+// before them
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+	SeverityNote    Severity = "note"
+)
+
+// Codes of the diagnostics. They are stable, so tools can rely on them to
+// filter or explain the diagnostics
+const (
+	// CodeError is the code of the errors without a more specific one
+	CodeError = "error"
+	// CodeIO is the code of the errors reading the input file
+	CodeIO = "io"
+	// CodeSyntax is the code of the syntax errors of the input file
+	CodeSyntax = "syntax"
+	// CodeType is the code of the type checking errors of the input file
+	CodeType = "type"
+	// CodeBuildConstraint is the code of the invalid build constraints
+	CodeBuildConstraint = "build-constraint"
+	// CodeTag is the code of the invalid tags of the embedded Actor fields,
+	// and of the invalid directives of the actors
+	CodeTag = "invalid-tag"
+	// CodeSignature is the code of the methods whose signature doesn't
+	// match their role, like constructors, views, stream stages or methods
+	// with blob parameters
+	CodeSignature = "invalid-signature"
+	// CodeConversion is the code of the invalid conversions from structs
+	CodeConversion = "invalid-conversion"
+	// CodeCollision is the code of the generated identifiers that collide
+	CodeCollision = "name-collision"
+	// CodeUnverified is the code of the warning of the loose mode when the
+	// input file doesn't type check
+	CodeUnverified = "types-not-verified"
+	// CodeImportName is the code of the warnings of the loose mode about
+	// package names guessed from the import paths
+	CodeImportName = "import-name-not-verified"
+	// CodeUndeclaredMethod is the code of the warnings about methods named
+	// in the tags that aren't declared
+	CodeUndeclaredMethod = "undeclared-tag-method"
+	// CodeHotPath is the code of the constructs of the hot path of an actor
+	// that break the guarantees declared in its hotpath tag
+	CodeHotPath = "hot-path-contract"
+)
+
+// Diagnostic is an error, warning or note about the input file. Line and
+// Column are zero when the diagnostic isn't about a position of the file
+type Diagnostic struct {
+	Severity Severity `json:"severity"`
+	Code     string   `json:"code"`
+	File     string   `json:"file"`
+	Line     int      `json:"line,omitempty"`
+	Column   int      `json:"column,omitempty"`
+	Message  string   `json:"message"`
+}
+
+// Error returns the message, preceded by the position if the diagnostic
+// has one
+func (d Diagnostic) Error() string {
+	if d.Line == 0 {
+		return d.Message
+	}
+	return fmt.Sprintf("%s:%d:%d: %s", d.File, d.Line, d.Column, d.Message)
+}
+
+// String returns the diagnostic in the format of the compilers:
+// file:line:column: severity: message [code]
+func (d Diagnostic) String() string {
+	pos := d.File
+	if d.Line > 0 {
+		pos = fmt.Sprintf("%s:%d:%d", d.File, d.Line, d.Column)
+	}
+	return fmt.Sprintf("%s: %s: %s [%s]", pos, d.Severity, d.Message, d.Code)
+}
+
+// Diagnostics are the diagnostics of a file. They are the error returned by
+// ParseFile and ParseFileLoose, which contains at least one error
+type Diagnostics []Diagnostic
+
+// Error returns the errors and their notes, a line each
+func (ds Diagnostics) Error() string {
+	var lines []string
+	for _, d := range ds {
+		switch d.Severity {
+		case SeverityError:
+			lines = append(lines, d.Error())
+		case SeverityNote:
+			lines = append(lines, "\t"+d.Error())
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// DiagnosticsOf returns the diagnostics of an error about a file: the
+// error itself if it's returned by ParseFile, or an error with the generic
+// code otherwise
+func DiagnosticsOf(fileName string, err error) Diagnostics {
+	if ds, ok := err.(Diagnostics); ok {
+		return ds
+	}
+	return Diagnostics{{Severity: SeverityError, Code: CodeError, File: fileName, Message: err.Error()}}
+}
+
+// located is an error or a warning at a position of the input file, before
+// the position is resolved with the file set of the parser
+type located struct {
+	pos  token.Pos
+	code string
+	msg  string
+}
+
+func (l located) Error() string {
+	return l.msg
+}
+
+// errorAt returns an error at a position of the input file
+func errorAt(pos token.Pos, code string, format string, args ...interface{}) error {
+	return located{pos: pos, code: code, msg: fmt.Sprintf(format, args...)}
+}
+
+// diagnostic resolves the position of a located error or warning
+func diagnostic(fset *token.FileSet, fileName string, severity Severity, l located) Diagnostic {
+	d := Diagnostic{Severity: severity, Code: l.code, File: fileName, Message: l.msg}
+	if l.pos.IsValid() {
+		p := fset.Position(l.pos)
+		d.File, d.Line, d.Column = p.Filename, p.Line, p.Column
+	}
+	return d
+}
+
+// diagnosticAt returns a diagnostic at a position of the input file of the
+// package, once it's parsed
+func (p Package) diagnosticAt(pos token.Pos, severity Severity, code, msg string) Diagnostic {
+	if p.fset == nil {
+		pos = token.NoPos
+	}
+	return diagnostic(p.fset, p.fileName, severity, located{pos: pos, code: code, msg: msg})
+}
+
+// diagnose returns the diagnostics of an error of the parser
+func diagnose(fset *token.FileSet, fileName string, err error) Diagnostics {
+	switch err := err.(type) {
+	case Diagnostics:
+		for i := range err {
+			if err[i].File == "" {
+				err[i].File = fileName
+			}
+		}
+		return err
+	case located:
+		return Diagnostics{diagnostic(fset, fileName, SeverityError, err)}
+	case scanner.ErrorList:
+		var ds Diagnostics
+		for _, e := range err {
+			ds = append(ds, Diagnostic{Severity: SeverityError, Code: CodeSyntax, File: e.Pos.Filename, Line: e.Pos.Line, Column: e.Pos.Column, Message: e.Msg})
+		}
+		return ds
+	case types.Error:
+		p := err.Fset.Position(err.Pos)
+		return Diagnostics{{Severity: SeverityError, Code: CodeType, File: p.Filename, Line: p.Line, Column: p.Column, Message: err.Msg}}
+	}
+	return DiagnosticsOf(fileName, err)
+}
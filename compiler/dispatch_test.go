@@ -0,0 +1,963 @@
+package compiler
+
+import (
+	"fmt"
+	"testing"
+)
+
+// BenchmarkDispatch compares the type switch of the handle functions of the
+// generated code with a jump table indexed by an op-code of the requests,
+// dispatching a rotating mix of the requests of 10, 40 and 100 methods
+func BenchmarkDispatch(b *testing.B) {
+	for _, methods := range []int{10, 40, 100} {
+		msgs := dispatchRequests[:methods]
+		b.Run(fmt.Sprintf("methods=%d/type-switch", methods), func(b *testing.B) {
+			handle := dispatchSwitches[methods]
+			for i := 0; i < b.N; i++ {
+				handle(msgs[i%len(msgs)])
+			}
+		})
+		b.Run(fmt.Sprintf("methods=%d/jump-table", methods), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				msg := msgs[i%len(msgs)]
+				dispatchTable[msg.(dispatchOp).op()](msg)
+			}
+		})
+	}
+}
+
+// dispatchSink keeps the work of the handlers
+var dispatchSink int
+
+// dispatchOp is implemented by the requests with an op-code, the index of
+// their handler in the jump table
+type dispatchOp interface {
+	op() int
+}
+
+var dispatchSwitches = map[int]func(interface{}){
+	10:  dispatchSwitch10,
+	40:  dispatchSwitch40,
+	100: dispatchSwitch100,
+}
+
+var dispatchRequests = []interface{}{
+	dispatchReq0{0},
+	dispatchReq1{1},
+	dispatchReq2{2},
+	dispatchReq3{3},
+	dispatchReq4{4},
+	dispatchReq5{5},
+	dispatchReq6{6},
+	dispatchReq7{7},
+	dispatchReq8{8},
+	dispatchReq9{9},
+	dispatchReq10{10},
+	dispatchReq11{11},
+	dispatchReq12{12},
+	dispatchReq13{13},
+	dispatchReq14{14},
+	dispatchReq15{15},
+	dispatchReq16{16},
+	dispatchReq17{17},
+	dispatchReq18{18},
+	dispatchReq19{19},
+	dispatchReq20{20},
+	dispatchReq21{21},
+	dispatchReq22{22},
+	dispatchReq23{23},
+	dispatchReq24{24},
+	dispatchReq25{25},
+	dispatchReq26{26},
+	dispatchReq27{27},
+	dispatchReq28{28},
+	dispatchReq29{29},
+	dispatchReq30{30},
+	dispatchReq31{31},
+	dispatchReq32{32},
+	dispatchReq33{33},
+	dispatchReq34{34},
+	dispatchReq35{35},
+	dispatchReq36{36},
+	dispatchReq37{37},
+	dispatchReq38{38},
+	dispatchReq39{39},
+	dispatchReq40{40},
+	dispatchReq41{41},
+	dispatchReq42{42},
+	dispatchReq43{43},
+	dispatchReq44{44},
+	dispatchReq45{45},
+	dispatchReq46{46},
+	dispatchReq47{47},
+	dispatchReq48{48},
+	dispatchReq49{49},
+	dispatchReq50{50},
+	dispatchReq51{51},
+	dispatchReq52{52},
+	dispatchReq53{53},
+	dispatchReq54{54},
+	dispatchReq55{55},
+	dispatchReq56{56},
+	dispatchReq57{57},
+	dispatchReq58{58},
+	dispatchReq59{59},
+	dispatchReq60{60},
+	dispatchReq61{61},
+	dispatchReq62{62},
+	dispatchReq63{63},
+	dispatchReq64{64},
+	dispatchReq65{65},
+	dispatchReq66{66},
+	dispatchReq67{67},
+	dispatchReq68{68},
+	dispatchReq69{69},
+	dispatchReq70{70},
+	dispatchReq71{71},
+	dispatchReq72{72},
+	dispatchReq73{73},
+	dispatchReq74{74},
+	dispatchReq75{75},
+	dispatchReq76{76},
+	dispatchReq77{77},
+	dispatchReq78{78},
+	dispatchReq79{79},
+	dispatchReq80{80},
+	dispatchReq81{81},
+	dispatchReq82{82},
+	dispatchReq83{83},
+	dispatchReq84{84},
+	dispatchReq85{85},
+	dispatchReq86{86},
+	dispatchReq87{87},
+	dispatchReq88{88},
+	dispatchReq89{89},
+	dispatchReq90{90},
+	dispatchReq91{91},
+	dispatchReq92{92},
+	dispatchReq93{93},
+	dispatchReq94{94},
+	dispatchReq95{95},
+	dispatchReq96{96},
+	dispatchReq97{97},
+	dispatchReq98{98},
+	dispatchReq99{99},
+}
+
+var dispatchTable = [...]func(interface{}){
+	func(msg interface{}) { dispatchSink += msg.(dispatchReq0).n },
+	func(msg interface{}) { dispatchSink += msg.(dispatchReq1).n },
+	func(msg interface{}) { dispatchSink += msg.(dispatchReq2).n },
+	func(msg interface{}) { dispatchSink += msg.(dispatchReq3).n },
+	func(msg interface{}) { dispatchSink += msg.(dispatchReq4).n },
+	func(msg interface{}) { dispatchSink += msg.(dispatchReq5).n },
+	func(msg interface{}) { dispatchSink += msg.(dispatchReq6).n },
+	func(msg interface{}) { dispatchSink += msg.(dispatchReq7).n },
+	func(msg interface{}) { dispatchSink += msg.(dispatchReq8).n },
+	func(msg interface{}) { dispatchSink += msg.(dispatchReq9).n },
+	func(msg interface{}) { dispatchSink += msg.(dispatchReq10).n },
+	func(msg interface{}) { dispatchSink += msg.(dispatchReq11).n },
+	func(msg interface{}) { dispatchSink += msg.(dispatchReq12).n },
+	func(msg interface{}) { dispatchSink += msg.(dispatchReq13).n },
+	func(msg interface{}) { dispatchSink += msg.(dispatchReq14).n },
+	func(msg interface{}) { dispatchSink += msg.(dispatchReq15).n },
+	func(msg interface{}) { dispatchSink += msg.(dispatchReq16).n },
+	func(msg interface{}) { dispatchSink += msg.(dispatchReq17).n },
+	func(msg interface{}) { dispatchSink += msg.(dispatchReq18).n },
+	func(msg interface{}) { dispatchSink += msg.(dispatchReq19).n },
+	func(msg interface{}) { dispatchSink += msg.(dispatchReq20).n },
+	func(msg interface{}) { dispatchSink += msg.(dispatchReq21).n },
+	func(msg interface{}) { dispatchSink += msg.(dispatchReq22).n },
+	func(msg interface{}) { dispatchSink += msg.(dispatchReq23).n },
+	func(msg interface{}) { dispatchSink += msg.(dispatchReq24).n },
+	func(msg interface{}) { dispatchSink += msg.(dispatchReq25).n },
+	func(msg interface{}) { dispatchSink += msg.(dispatchReq26).n },
+	func(msg interface{}) { dispatchSink += msg.(dispatchReq27).n },
+	func(msg interface{}) { dispatchSink += msg.(dispatchReq28).n },
+	func(msg interface{}) { dispatchSink += msg.(dispatchReq29).n },
+	func(msg interface{}) { dispatchSink += msg.(dispatchReq30).n },
+	func(msg interface{}) { dispatchSink += msg.(dispatchReq31).n },
+	func(msg interface{}) { dispatchSink += msg.(dispatchReq32).n },
+	func(msg interface{}) { dispatchSink += msg.(dispatchReq33).n },
+	func(msg interface{}) { dispatchSink += msg.(dispatchReq34).n },
+	func(msg interface{}) { dispatchSink += msg.(dispatchReq35).n },
+	func(msg interface{}) { dispatchSink += msg.(dispatchReq36).n },
+	func(msg interface{}) { dispatchSink += msg.(dispatchReq37).n },
+	func(msg interface{}) { dispatchSink += msg.(dispatchReq38).n },
+	func(msg interface{}) { dispatchSink += msg.(dispatchReq39).n },
+	func(msg interface{}) { dispatchSink += msg.(dispatchReq40).n },
+	func(msg interface{}) { dispatchSink += msg.(dispatchReq41).n },
+	func(msg interface{}) { dispatchSink += msg.(dispatchReq42).n },
+	func(msg interface{}) { dispatchSink += msg.(dispatchReq43).n },
+	func(msg interface{}) { dispatchSink += msg.(dispatchReq44).n },
+	func(msg interface{}) { dispatchSink += msg.(dispatchReq45).n },
+	func(msg interface{}) { dispatchSink += msg.(dispatchReq46).n },
+	func(msg interface{}) { dispatchSink += msg.(dispatchReq47).n },
+	func(msg interface{}) { dispatchSink += msg.(dispatchReq48).n },
+	func(msg interface{}) { dispatchSink += msg.(dispatchReq49).n },
+	func(msg interface{}) { dispatchSink += msg.(dispatchReq50).n },
+	func(msg interface{}) { dispatchSink += msg.(dispatchReq51).n },
+	func(msg interface{}) { dispatchSink += msg.(dispatchReq52).n },
+	func(msg interface{}) { dispatchSink += msg.(dispatchReq53).n },
+	func(msg interface{}) { dispatchSink += msg.(dispatchReq54).n },
+	func(msg interface{}) { dispatchSink += msg.(dispatchReq55).n },
+	func(msg interface{}) { dispatchSink += msg.(dispatchReq56).n },
+	func(msg interface{}) { dispatchSink += msg.(dispatchReq57).n },
+	func(msg interface{}) { dispatchSink += msg.(dispatchReq58).n },
+	func(msg interface{}) { dispatchSink += msg.(dispatchReq59).n },
+	func(msg interface{}) { dispatchSink += msg.(dispatchReq60).n },
+	func(msg interface{}) { dispatchSink += msg.(dispatchReq61).n },
+	func(msg interface{}) { dispatchSink += msg.(dispatchReq62).n },
+	func(msg interface{}) { dispatchSink += msg.(dispatchReq63).n },
+	func(msg interface{}) { dispatchSink += msg.(dispatchReq64).n },
+	func(msg interface{}) { dispatchSink += msg.(dispatchReq65).n },
+	func(msg interface{}) { dispatchSink += msg.(dispatchReq66).n },
+	func(msg interface{}) { dispatchSink += msg.(dispatchReq67).n },
+	func(msg interface{}) { dispatchSink += msg.(dispatchReq68).n },
+	func(msg interface{}) { dispatchSink += msg.(dispatchReq69).n },
+	func(msg interface{}) { dispatchSink += msg.(dispatchReq70).n },
+	func(msg interface{}) { dispatchSink += msg.(dispatchReq71).n },
+	func(msg interface{}) { dispatchSink += msg.(dispatchReq72).n },
+	func(msg interface{}) { dispatchSink += msg.(dispatchReq73).n },
+	func(msg interface{}) { dispatchSink += msg.(dispatchReq74).n },
+	func(msg interface{}) { dispatchSink += msg.(dispatchReq75).n },
+	func(msg interface{}) { dispatchSink += msg.(dispatchReq76).n },
+	func(msg interface{}) { dispatchSink += msg.(dispatchReq77).n },
+	func(msg interface{}) { dispatchSink += msg.(dispatchReq78).n },
+	func(msg interface{}) { dispatchSink += msg.(dispatchReq79).n },
+	func(msg interface{}) { dispatchSink += msg.(dispatchReq80).n },
+	func(msg interface{}) { dispatchSink += msg.(dispatchReq81).n },
+	func(msg interface{}) { dispatchSink += msg.(dispatchReq82).n },
+	func(msg interface{}) { dispatchSink += msg.(dispatchReq83).n },
+	func(msg interface{}) { dispatchSink += msg.(dispatchReq84).n },
+	func(msg interface{}) { dispatchSink += msg.(dispatchReq85).n },
+	func(msg interface{}) { dispatchSink += msg.(dispatchReq86).n },
+	func(msg interface{}) { dispatchSink += msg.(dispatchReq87).n },
+	func(msg interface{}) { dispatchSink += msg.(dispatchReq88).n },
+	func(msg interface{}) { dispatchSink += msg.(dispatchReq89).n },
+	func(msg interface{}) { dispatchSink += msg.(dispatchReq90).n },
+	func(msg interface{}) { dispatchSink += msg.(dispatchReq91).n },
+	func(msg interface{}) { dispatchSink += msg.(dispatchReq92).n },
+	func(msg interface{}) { dispatchSink += msg.(dispatchReq93).n },
+	func(msg interface{}) { dispatchSink += msg.(dispatchReq94).n },
+	func(msg interface{}) { dispatchSink += msg.(dispatchReq95).n },
+	func(msg interface{}) { dispatchSink += msg.(dispatchReq96).n },
+	func(msg interface{}) { dispatchSink += msg.(dispatchReq97).n },
+	func(msg interface{}) { dispatchSink += msg.(dispatchReq98).n },
+	func(msg interface{}) { dispatchSink += msg.(dispatchReq99).n },
+}
+
+func dispatchSwitch10(msg interface{}) {
+	switch msg := msg.(type) {
+	case dispatchReq0:
+		dispatchSink += msg.n
+	case dispatchReq1:
+		dispatchSink += msg.n
+	case dispatchReq2:
+		dispatchSink += msg.n
+	case dispatchReq3:
+		dispatchSink += msg.n
+	case dispatchReq4:
+		dispatchSink += msg.n
+	case dispatchReq5:
+		dispatchSink += msg.n
+	case dispatchReq6:
+		dispatchSink += msg.n
+	case dispatchReq7:
+		dispatchSink += msg.n
+	case dispatchReq8:
+		dispatchSink += msg.n
+	case dispatchReq9:
+		dispatchSink += msg.n
+	}
+}
+
+func dispatchSwitch40(msg interface{}) {
+	switch msg := msg.(type) {
+	case dispatchReq0:
+		dispatchSink += msg.n
+	case dispatchReq1:
+		dispatchSink += msg.n
+	case dispatchReq2:
+		dispatchSink += msg.n
+	case dispatchReq3:
+		dispatchSink += msg.n
+	case dispatchReq4:
+		dispatchSink += msg.n
+	case dispatchReq5:
+		dispatchSink += msg.n
+	case dispatchReq6:
+		dispatchSink += msg.n
+	case dispatchReq7:
+		dispatchSink += msg.n
+	case dispatchReq8:
+		dispatchSink += msg.n
+	case dispatchReq9:
+		dispatchSink += msg.n
+	case dispatchReq10:
+		dispatchSink += msg.n
+	case dispatchReq11:
+		dispatchSink += msg.n
+	case dispatchReq12:
+		dispatchSink += msg.n
+	case dispatchReq13:
+		dispatchSink += msg.n
+	case dispatchReq14:
+		dispatchSink += msg.n
+	case dispatchReq15:
+		dispatchSink += msg.n
+	case dispatchReq16:
+		dispatchSink += msg.n
+	case dispatchReq17:
+		dispatchSink += msg.n
+	case dispatchReq18:
+		dispatchSink += msg.n
+	case dispatchReq19:
+		dispatchSink += msg.n
+	case dispatchReq20:
+		dispatchSink += msg.n
+	case dispatchReq21:
+		dispatchSink += msg.n
+	case dispatchReq22:
+		dispatchSink += msg.n
+	case dispatchReq23:
+		dispatchSink += msg.n
+	case dispatchReq24:
+		dispatchSink += msg.n
+	case dispatchReq25:
+		dispatchSink += msg.n
+	case dispatchReq26:
+		dispatchSink += msg.n
+	case dispatchReq27:
+		dispatchSink += msg.n
+	case dispatchReq28:
+		dispatchSink += msg.n
+	case dispatchReq29:
+		dispatchSink += msg.n
+	case dispatchReq30:
+		dispatchSink += msg.n
+	case dispatchReq31:
+		dispatchSink += msg.n
+	case dispatchReq32:
+		dispatchSink += msg.n
+	case dispatchReq33:
+		dispatchSink += msg.n
+	case dispatchReq34:
+		dispatchSink += msg.n
+	case dispatchReq35:
+		dispatchSink += msg.n
+	case dispatchReq36:
+		dispatchSink += msg.n
+	case dispatchReq37:
+		dispatchSink += msg.n
+	case dispatchReq38:
+		dispatchSink += msg.n
+	case dispatchReq39:
+		dispatchSink += msg.n
+	}
+}
+
+func dispatchSwitch100(msg interface{}) {
+	switch msg := msg.(type) {
+	case dispatchReq0:
+		dispatchSink += msg.n
+	case dispatchReq1:
+		dispatchSink += msg.n
+	case dispatchReq2:
+		dispatchSink += msg.n
+	case dispatchReq3:
+		dispatchSink += msg.n
+	case dispatchReq4:
+		dispatchSink += msg.n
+	case dispatchReq5:
+		dispatchSink += msg.n
+	case dispatchReq6:
+		dispatchSink += msg.n
+	case dispatchReq7:
+		dispatchSink += msg.n
+	case dispatchReq8:
+		dispatchSink += msg.n
+	case dispatchReq9:
+		dispatchSink += msg.n
+	case dispatchReq10:
+		dispatchSink += msg.n
+	case dispatchReq11:
+		dispatchSink += msg.n
+	case dispatchReq12:
+		dispatchSink += msg.n
+	case dispatchReq13:
+		dispatchSink += msg.n
+	case dispatchReq14:
+		dispatchSink += msg.n
+	case dispatchReq15:
+		dispatchSink += msg.n
+	case dispatchReq16:
+		dispatchSink += msg.n
+	case dispatchReq17:
+		dispatchSink += msg.n
+	case dispatchReq18:
+		dispatchSink += msg.n
+	case dispatchReq19:
+		dispatchSink += msg.n
+	case dispatchReq20:
+		dispatchSink += msg.n
+	case dispatchReq21:
+		dispatchSink += msg.n
+	case dispatchReq22:
+		dispatchSink += msg.n
+	case dispatchReq23:
+		dispatchSink += msg.n
+	case dispatchReq24:
+		dispatchSink += msg.n
+	case dispatchReq25:
+		dispatchSink += msg.n
+	case dispatchReq26:
+		dispatchSink += msg.n
+	case dispatchReq27:
+		dispatchSink += msg.n
+	case dispatchReq28:
+		dispatchSink += msg.n
+	case dispatchReq29:
+		dispatchSink += msg.n
+	case dispatchReq30:
+		dispatchSink += msg.n
+	case dispatchReq31:
+		dispatchSink += msg.n
+	case dispatchReq32:
+		dispatchSink += msg.n
+	case dispatchReq33:
+		dispatchSink += msg.n
+	case dispatchReq34:
+		dispatchSink += msg.n
+	case dispatchReq35:
+		dispatchSink += msg.n
+	case dispatchReq36:
+		dispatchSink += msg.n
+	case dispatchReq37:
+		dispatchSink += msg.n
+	case dispatchReq38:
+		dispatchSink += msg.n
+	case dispatchReq39:
+		dispatchSink += msg.n
+	case dispatchReq40:
+		dispatchSink += msg.n
+	case dispatchReq41:
+		dispatchSink += msg.n
+	case dispatchReq42:
+		dispatchSink += msg.n
+	case dispatchReq43:
+		dispatchSink += msg.n
+	case dispatchReq44:
+		dispatchSink += msg.n
+	case dispatchReq45:
+		dispatchSink += msg.n
+	case dispatchReq46:
+		dispatchSink += msg.n
+	case dispatchReq47:
+		dispatchSink += msg.n
+	case dispatchReq48:
+		dispatchSink += msg.n
+	case dispatchReq49:
+		dispatchSink += msg.n
+	case dispatchReq50:
+		dispatchSink += msg.n
+	case dispatchReq51:
+		dispatchSink += msg.n
+	case dispatchReq52:
+		dispatchSink += msg.n
+	case dispatchReq53:
+		dispatchSink += msg.n
+	case dispatchReq54:
+		dispatchSink += msg.n
+	case dispatchReq55:
+		dispatchSink += msg.n
+	case dispatchReq56:
+		dispatchSink += msg.n
+	case dispatchReq57:
+		dispatchSink += msg.n
+	case dispatchReq58:
+		dispatchSink += msg.n
+	case dispatchReq59:
+		dispatchSink += msg.n
+	case dispatchReq60:
+		dispatchSink += msg.n
+	case dispatchReq61:
+		dispatchSink += msg.n
+	case dispatchReq62:
+		dispatchSink += msg.n
+	case dispatchReq63:
+		dispatchSink += msg.n
+	case dispatchReq64:
+		dispatchSink += msg.n
+	case dispatchReq65:
+		dispatchSink += msg.n
+	case dispatchReq66:
+		dispatchSink += msg.n
+	case dispatchReq67:
+		dispatchSink += msg.n
+	case dispatchReq68:
+		dispatchSink += msg.n
+	case dispatchReq69:
+		dispatchSink += msg.n
+	case dispatchReq70:
+		dispatchSink += msg.n
+	case dispatchReq71:
+		dispatchSink += msg.n
+	case dispatchReq72:
+		dispatchSink += msg.n
+	case dispatchReq73:
+		dispatchSink += msg.n
+	case dispatchReq74:
+		dispatchSink += msg.n
+	case dispatchReq75:
+		dispatchSink += msg.n
+	case dispatchReq76:
+		dispatchSink += msg.n
+	case dispatchReq77:
+		dispatchSink += msg.n
+	case dispatchReq78:
+		dispatchSink += msg.n
+	case dispatchReq79:
+		dispatchSink += msg.n
+	case dispatchReq80:
+		dispatchSink += msg.n
+	case dispatchReq81:
+		dispatchSink += msg.n
+	case dispatchReq82:
+		dispatchSink += msg.n
+	case dispatchReq83:
+		dispatchSink += msg.n
+	case dispatchReq84:
+		dispatchSink += msg.n
+	case dispatchReq85:
+		dispatchSink += msg.n
+	case dispatchReq86:
+		dispatchSink += msg.n
+	case dispatchReq87:
+		dispatchSink += msg.n
+	case dispatchReq88:
+		dispatchSink += msg.n
+	case dispatchReq89:
+		dispatchSink += msg.n
+	case dispatchReq90:
+		dispatchSink += msg.n
+	case dispatchReq91:
+		dispatchSink += msg.n
+	case dispatchReq92:
+		dispatchSink += msg.n
+	case dispatchReq93:
+		dispatchSink += msg.n
+	case dispatchReq94:
+		dispatchSink += msg.n
+	case dispatchReq95:
+		dispatchSink += msg.n
+	case dispatchReq96:
+		dispatchSink += msg.n
+	case dispatchReq97:
+		dispatchSink += msg.n
+	case dispatchReq98:
+		dispatchSink += msg.n
+	case dispatchReq99:
+		dispatchSink += msg.n
+	}
+}
+
+type dispatchReq0 struct{ n int }
+
+func (dispatchReq0) op() int { return 0 }
+
+type dispatchReq1 struct{ n int }
+
+func (dispatchReq1) op() int { return 1 }
+
+type dispatchReq2 struct{ n int }
+
+func (dispatchReq2) op() int { return 2 }
+
+type dispatchReq3 struct{ n int }
+
+func (dispatchReq3) op() int { return 3 }
+
+type dispatchReq4 struct{ n int }
+
+func (dispatchReq4) op() int { return 4 }
+
+type dispatchReq5 struct{ n int }
+
+func (dispatchReq5) op() int { return 5 }
+
+type dispatchReq6 struct{ n int }
+
+func (dispatchReq6) op() int { return 6 }
+
+type dispatchReq7 struct{ n int }
+
+func (dispatchReq7) op() int { return 7 }
+
+type dispatchReq8 struct{ n int }
+
+func (dispatchReq8) op() int { return 8 }
+
+type dispatchReq9 struct{ n int }
+
+func (dispatchReq9) op() int { return 9 }
+
+type dispatchReq10 struct{ n int }
+
+func (dispatchReq10) op() int { return 10 }
+
+type dispatchReq11 struct{ n int }
+
+func (dispatchReq11) op() int { return 11 }
+
+type dispatchReq12 struct{ n int }
+
+func (dispatchReq12) op() int { return 12 }
+
+type dispatchReq13 struct{ n int }
+
+func (dispatchReq13) op() int { return 13 }
+
+type dispatchReq14 struct{ n int }
+
+func (dispatchReq14) op() int { return 14 }
+
+type dispatchReq15 struct{ n int }
+
+func (dispatchReq15) op() int { return 15 }
+
+type dispatchReq16 struct{ n int }
+
+func (dispatchReq16) op() int { return 16 }
+
+type dispatchReq17 struct{ n int }
+
+func (dispatchReq17) op() int { return 17 }
+
+type dispatchReq18 struct{ n int }
+
+func (dispatchReq18) op() int { return 18 }
+
+type dispatchReq19 struct{ n int }
+
+func (dispatchReq19) op() int { return 19 }
+
+type dispatchReq20 struct{ n int }
+
+func (dispatchReq20) op() int { return 20 }
+
+type dispatchReq21 struct{ n int }
+
+func (dispatchReq21) op() int { return 21 }
+
+type dispatchReq22 struct{ n int }
+
+func (dispatchReq22) op() int { return 22 }
+
+type dispatchReq23 struct{ n int }
+
+func (dispatchReq23) op() int { return 23 }
+
+type dispatchReq24 struct{ n int }
+
+func (dispatchReq24) op() int { return 24 }
+
+type dispatchReq25 struct{ n int }
+
+func (dispatchReq25) op() int { return 25 }
+
+type dispatchReq26 struct{ n int }
+
+func (dispatchReq26) op() int { return 26 }
+
+type dispatchReq27 struct{ n int }
+
+func (dispatchReq27) op() int { return 27 }
+
+type dispatchReq28 struct{ n int }
+
+func (dispatchReq28) op() int { return 28 }
+
+type dispatchReq29 struct{ n int }
+
+func (dispatchReq29) op() int { return 29 }
+
+type dispatchReq30 struct{ n int }
+
+func (dispatchReq30) op() int { return 30 }
+
+type dispatchReq31 struct{ n int }
+
+func (dispatchReq31) op() int { return 31 }
+
+type dispatchReq32 struct{ n int }
+
+func (dispatchReq32) op() int { return 32 }
+
+type dispatchReq33 struct{ n int }
+
+func (dispatchReq33) op() int { return 33 }
+
+type dispatchReq34 struct{ n int }
+
+func (dispatchReq34) op() int { return 34 }
+
+type dispatchReq35 struct{ n int }
+
+func (dispatchReq35) op() int { return 35 }
+
+type dispatchReq36 struct{ n int }
+
+func (dispatchReq36) op() int { return 36 }
+
+type dispatchReq37 struct{ n int }
+
+func (dispatchReq37) op() int { return 37 }
+
+type dispatchReq38 struct{ n int }
+
+func (dispatchReq38) op() int { return 38 }
+
+type dispatchReq39 struct{ n int }
+
+func (dispatchReq39) op() int { return 39 }
+
+type dispatchReq40 struct{ n int }
+
+func (dispatchReq40) op() int { return 40 }
+
+type dispatchReq41 struct{ n int }
+
+func (dispatchReq41) op() int { return 41 }
+
+type dispatchReq42 struct{ n int }
+
+func (dispatchReq42) op() int { return 42 }
+
+type dispatchReq43 struct{ n int }
+
+func (dispatchReq43) op() int { return 43 }
+
+type dispatchReq44 struct{ n int }
+
+func (dispatchReq44) op() int { return 44 }
+
+type dispatchReq45 struct{ n int }
+
+func (dispatchReq45) op() int { return 45 }
+
+type dispatchReq46 struct{ n int }
+
+func (dispatchReq46) op() int { return 46 }
+
+type dispatchReq47 struct{ n int }
+
+func (dispatchReq47) op() int { return 47 }
+
+type dispatchReq48 struct{ n int }
+
+func (dispatchReq48) op() int { return 48 }
+
+type dispatchReq49 struct{ n int }
+
+func (dispatchReq49) op() int { return 49 }
+
+type dispatchReq50 struct{ n int }
+
+func (dispatchReq50) op() int { return 50 }
+
+type dispatchReq51 struct{ n int }
+
+func (dispatchReq51) op() int { return 51 }
+
+type dispatchReq52 struct{ n int }
+
+func (dispatchReq52) op() int { return 52 }
+
+type dispatchReq53 struct{ n int }
+
+func (dispatchReq53) op() int { return 53 }
+
+type dispatchReq54 struct{ n int }
+
+func (dispatchReq54) op() int { return 54 }
+
+type dispatchReq55 struct{ n int }
+
+func (dispatchReq55) op() int { return 55 }
+
+type dispatchReq56 struct{ n int }
+
+func (dispatchReq56) op() int { return 56 }
+
+type dispatchReq57 struct{ n int }
+
+func (dispatchReq57) op() int { return 57 }
+
+type dispatchReq58 struct{ n int }
+
+func (dispatchReq58) op() int { return 58 }
+
+type dispatchReq59 struct{ n int }
+
+func (dispatchReq59) op() int { return 59 }
+
+type dispatchReq60 struct{ n int }
+
+func (dispatchReq60) op() int { return 60 }
+
+type dispatchReq61 struct{ n int }
+
+func (dispatchReq61) op() int { return 61 }
+
+type dispatchReq62 struct{ n int }
+
+func (dispatchReq62) op() int { return 62 }
+
+type dispatchReq63 struct{ n int }
+
+func (dispatchReq63) op() int { return 63 }
+
+type dispatchReq64 struct{ n int }
+
+func (dispatchReq64) op() int { return 64 }
+
+type dispatchReq65 struct{ n int }
+
+func (dispatchReq65) op() int { return 65 }
+
+type dispatchReq66 struct{ n int }
+
+func (dispatchReq66) op() int { return 66 }
+
+type dispatchReq67 struct{ n int }
+
+func (dispatchReq67) op() int { return 67 }
+
+type dispatchReq68 struct{ n int }
+
+func (dispatchReq68) op() int { return 68 }
+
+type dispatchReq69 struct{ n int }
+
+func (dispatchReq69) op() int { return 69 }
+
+type dispatchReq70 struct{ n int }
+
+func (dispatchReq70) op() int { return 70 }
+
+type dispatchReq71 struct{ n int }
+
+func (dispatchReq71) op() int { return 71 }
+
+type dispatchReq72 struct{ n int }
+
+func (dispatchReq72) op() int { return 72 }
+
+type dispatchReq73 struct{ n int }
+
+func (dispatchReq73) op() int { return 73 }
+
+type dispatchReq74 struct{ n int }
+
+func (dispatchReq74) op() int { return 74 }
+
+type dispatchReq75 struct{ n int }
+
+func (dispatchReq75) op() int { return 75 }
+
+type dispatchReq76 struct{ n int }
+
+func (dispatchReq76) op() int { return 76 }
+
+type dispatchReq77 struct{ n int }
+
+func (dispatchReq77) op() int { return 77 }
+
+type dispatchReq78 struct{ n int }
+
+func (dispatchReq78) op() int { return 78 }
+
+type dispatchReq79 struct{ n int }
+
+func (dispatchReq79) op() int { return 79 }
+
+type dispatchReq80 struct{ n int }
+
+func (dispatchReq80) op() int { return 80 }
+
+type dispatchReq81 struct{ n int }
+
+func (dispatchReq81) op() int { return 81 }
+
+type dispatchReq82 struct{ n int }
+
+func (dispatchReq82) op() int { return 82 }
+
+type dispatchReq83 struct{ n int }
+
+func (dispatchReq83) op() int { return 83 }
+
+type dispatchReq84 struct{ n int }
+
+func (dispatchReq84) op() int { return 84 }
+
+type dispatchReq85 struct{ n int }
+
+func (dispatchReq85) op() int { return 85 }
+
+type dispatchReq86 struct{ n int }
+
+func (dispatchReq86) op() int { return 86 }
+
+type dispatchReq87 struct{ n int }
+
+func (dispatchReq87) op() int { return 87 }
+
+type dispatchReq88 struct{ n int }
+
+func (dispatchReq88) op() int { return 88 }
+
+type dispatchReq89 struct{ n int }
+
+func (dispatchReq89) op() int { return 89 }
+
+type dispatchReq90 struct{ n int }
+
+func (dispatchReq90) op() int { return 90 }
+
+type dispatchReq91 struct{ n int }
+
+func (dispatchReq91) op() int { return 91 }
+
+type dispatchReq92 struct{ n int }
+
+func (dispatchReq92) op() int { return 92 }
+
+type dispatchReq93 struct{ n int }
+
+func (dispatchReq93) op() int { return 93 }
+
+type dispatchReq94 struct{ n int }
+
+func (dispatchReq94) op() int { return 94 }
+
+type dispatchReq95 struct{ n int }
+
+func (dispatchReq95) op() int { return 95 }
+
+type dispatchReq96 struct{ n int }
+
+func (dispatchReq96) op() int { return 96 }
+
+type dispatchReq97 struct{ n int }
+
+func (dispatchReq97) op() int { return 97 }
+
+type dispatchReq98 struct{ n int }
+
+func (dispatchReq98) op() int { return 98 }
+
+type dispatchReq99 struct{ n int }
+
+func (dispatchReq99) op() int { return 99 }
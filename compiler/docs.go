@@ -0,0 +1,217 @@
+package compiler
+
+import (
+	"fmt"
+	"go/build"
+	"go/parser"
+	"go/token"
+	htmltemplate "html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// DocFile contains the actors declared in a source file, for the reference
+// documentation
+type DocFile struct {
+	Path    string
+	Package Package
+}
+
+// Docs finds the source files that declare actors in dir and its
+// subdirectories and parses them. Files that don't type check on their own,
+// because they use other files of their package, are parsed in loose mode.
+// The generated files and the tests are skipped
+func Docs(dir string) ([]DocFile, error) {
+	var docs []DocFile
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		name := info.Name()
+		if info.IsDir() {
+			if path != dir && (strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") || name == "vendor" || name == "testdata") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") {
+			return nil
+		}
+		if match, err := build.Default.MatchFile(filepath.Dir(path), name); err != nil || !match {
+			return nil
+		}
+
+		f, err := parser.ParseFile(token.NewFileSet(), path, nil, parser.ParseComments)
+		if err != nil {
+			return err
+		}
+		if isGeneratedFile(f) || len(actorNames(f)) == 0 {
+			return nil
+		}
+		pkg, err := ParseFile(path)
+		if err != nil {
+			pkg, err = ParseFileLoose(path)
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+		if len(pkg.Actors) > 0 {
+			docs = append(docs, DocFile{Path: path, Package: pkg})
+		}
+		return nil
+	})
+	sort.Slice(docs, func(i, j int) bool {
+		return docs[i].Path < docs[j].Path
+	})
+	return docs, err
+}
+
+// GenerateDocs writes the reference of the actors declared in the files, in
+// the format markdown or html
+func GenerateDocs(output io.Writer, docs []DocFile, format string) error {
+	funcMap := map[string]interface{}{
+		"signature": signature,
+		"docText":   docText,
+	}
+	switch format {
+	case "markdown":
+		t := template.Must(template.New("docs").Funcs(funcMap).Parse(docsMarkdownTmpl))
+		return t.Execute(output, docs)
+	case "html":
+		t := htmltemplate.Must(htmltemplate.New("docs").Funcs(funcMap).Parse(docsHTMLTmpl))
+		return t.Execute(output, docs)
+	}
+	return fmt.Errorf("unknown docs format %s", format)
+}
+
+// signature returns the signature of a method of the actor reference, as
+// it's generated
+func signature(m Method) string {
+	var params []string
+	for _, p := range m.Params {
+		params = append(params, p.Name+" "+p.Type)
+	}
+	sig := m.Name + "(" + strings.Join(params, ", ") + ")"
+
+	var results []string
+	for _, r := range m.RetValues {
+		if r.Name != "" {
+			results = append(results, r.Name+" "+r.Type)
+		} else {
+			results = append(results, r.Type)
+		}
+	}
+	switch {
+	case m.Seq:
+		sig += " actor.Seq"
+	case len(results) == 0:
+	case m.Async:
+		sig += " func() (" + strings.Join(results, ", ") + ")"
+	case len(results) == 1 && m.RetValues[0].Name == "":
+		sig += " " + results[0]
+	default:
+		sig += " (" + strings.Join(results, ", ") + ")"
+	}
+	return sig
+}
+
+// docText returns the text of a doc comment, without the comment markers
+func docText(comments []string) string {
+	var lines []string
+	for _, c := range comments {
+		switch {
+		case strings.HasPrefix(c, "//"):
+			lines = append(lines, strings.TrimPrefix(strings.TrimPrefix(c, "//"), " "))
+		case strings.HasPrefix(c, "/*"):
+			for _, line := range strings.Split(strings.TrimSuffix(strings.TrimPrefix(c, "/*"), "*/"), "\n") {
+				lines = append(lines, strings.TrimSpace(line))
+			}
+		}
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// docsMarkdownTmpl is the template of the reference in Markdown
+const docsMarkdownTmpl = `# Actors
+{{range .}}{{$file := .}}{{range .Package.Actors}}
+## {{$file.Package.Name}}.{{.Name}}
+
+Declared in ` + "`{{$file.Path}}`" + `, implemented by ` + "`{{.Impl}}`" + `.
+{{- if .Constructors}}
+
+Constructors:
+{{range .Constructors}}
+* ` + "`{{.Constructor}}({{range $i, $p := .Params}}{{if $i}}, {{end}}{{$p.Name}} {{$p.Type}}{{end}})`" + `{{if .Fails}}, can fail{{end}}
+{{- end}}
+{{- end}}
+{{- with .View}}
+
+Projection: ` + "`View() {{(index .RetValues 0).Type}}`" + `
+{{- end}}
+{{range .Methods}}
+### {{.Name}}
+
+` + "```Go\n{{signature .}}\n```" + `
+{{with docText .Comments}}
+{{.}}
+{{end}}
+* {{if .Seq}}asynchronous: the call returns the sequence token of the write{{else if .Async}}asynchronous: the call returns a function that polls the results{{else}}synchronous{{end}}
+{{- if .Timeout}}
+* timeout: {{.Timeout}}
+{{- end}}
+{{- if .Stage}}
+* stream stage: {{.Stage}}
+{{- end}}
+{{end}}{{end}}{{end}}`
+
+// docsHTMLTmpl is the template of the reference in HTML
+const docsHTMLTmpl = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Actors</title>
+<style>
+body { font-family: sans-serif; max-width: 60em; margin: auto; }
+pre { background: #f4f4f4; padding: .5em; }
+</style>
+</head>
+<body>
+<h1>Actors</h1>
+{{- range .}}{{$file := .}}{{range .Package.Actors}}
+<h2 id="{{$file.Package.Name}}.{{.Name}}">{{$file.Package.Name}}.{{.Name}}</h2>
+<p>Declared in <code>{{$file.Path}}</code>, implemented by <code>{{.Impl}}</code>.</p>
+{{- if .Constructors}}
+<p>Constructors:</p>
+<ul>
+{{- range .Constructors}}
+<li><code>{{.Constructor}}({{range $i, $p := .Params}}{{if $i}}, {{end}}{{$p.Name}} {{$p.Type}}{{end}})</code>{{if .Fails}}, can fail{{end}}</li>
+{{- end}}
+</ul>
+{{- end}}
+{{- with .View}}
+<p>Projection: <code>View() {{(index .RetValues 0).Type}}</code></p>
+{{- end}}
+{{- range .Methods}}
+<h3>{{.Name}}</h3>
+<pre>{{signature .}}</pre>
+{{- with docText .Comments}}
+<p>{{.}}</p>
+{{- end}}
+<ul>
+<li>{{if .Seq}}asynchronous: the call returns the sequence token of the write{{else if .Async}}asynchronous: the call returns a function that polls the results{{else}}synchronous{{end}}</li>
+{{- if .Timeout}}
+<li>timeout: {{.Timeout}}</li>
+{{- end}}
+{{- if .Stage}}
+<li>stream stage: {{.Stage}}</li>
+{{- end}}
+</ul>
+{{- end}}
+{{- end}}{{end}}
+</body>
+</html>
+`
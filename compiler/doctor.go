@@ -0,0 +1,334 @@
+package compiler
+
+import (
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Finding is a misuse of the actors found by Doctor
+type Finding struct {
+	Pos     token.Position
+	Message string
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("%s: %s", f.Pos, f.Message)
+}
+
+// generatedFile matches the comment that identifies generated files
+var generatedFile = regexp.MustCompile(`^// Code generated .* DO NOT EDIT\.$`)
+
+// Doctor scans the packages in dir and its subdirectories looking for
+// common misuses of the actors:
+//
+//   - actor methods called directly instead of through a reference
+//   - actor implementations shared with other goroutines
+//   - blocking reference calls inside actor methods
+//   - actors that are never stopped
+//
+// Each package is checked on its own, so only actors declared in the same
+// package are considered
+func Doctor(dir string) ([]Finding, error) {
+	var findings []Finding
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			return nil
+		}
+		name := info.Name()
+		if path != dir && (strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") || name == "vendor" || name == "testdata") {
+			return filepath.SkipDir
+		}
+		found, err := doctorPackage(path)
+		if err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+		findings = append(findings, found...)
+		return nil
+	})
+	return findings, err
+}
+
+// doctorActor contains what Doctor knows about an actor
+type doctorActor struct {
+	impl         *types.TypeName
+	methods      map[string]bool
+	async        map[string]bool
+	constructors map[string]bool
+	stopped      bool
+	created      []token.Pos
+}
+
+// stopMethods are the methods of the actor interface that stop an actor
+var stopMethods = map[string]bool{
+	actorInterface.Stop: true,
+	"Close":             true,
+	"Kill":              true,
+}
+
+// systemStopMethods are the methods of the System that stop its actors
+var systemStopMethods = map[string]bool{
+	"Stop":     true,
+	"Shutdown": true,
+}
+
+// doctorPackage checks the package in dir
+func doctorPackage(dir string) ([]Finding, error) {
+	fset := token.NewFileSet()
+	// only the files built for the current platform are checked
+	pkgs, err := parser.ParseDir(fset, dir, func(fi os.FileInfo) bool {
+		match, err := build.Default.MatchFile(dir, fi.Name())
+		return err == nil && match && !strings.HasSuffix(fi.Name(), "_test.go")
+	}, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+
+	var findings []Finding
+	for _, p := range pkgs {
+		var files []*ast.File
+		for _, f := range p.Files {
+			files = append(files, f)
+		}
+		sort.Slice(files, func(i, j int) bool {
+			return fset.File(files[i].Pos()).Name() < fset.File(files[j].Pos()).Name()
+		})
+
+		info := &types.Info{
+			Uses:       make(map[*ast.Ident]types.Object),
+			Selections: make(map[*ast.SelectorExpr]*types.Selection),
+		}
+		conf := types.Config{Importer: importer.Default(), FakeImportC: true, Error: func(error) {}}
+		pkg, _ := conf.Check(p.Name, fset, files, info)
+
+		actors := doctorActors(pkg, files)
+		if len(actors) == 0 {
+			continue
+		}
+		for _, f := range files {
+			if isGeneratedFile(f) {
+				continue
+			}
+			findings = append(findings, doctorFile(f, fset, info, pkg.Scope(), actors)...)
+		}
+
+		// actors are usually stopped through the actor interface, or by
+		// the System they were added to
+		for sel, s := range info.Selections {
+			name := namedName(s.Recv())
+			for _, a := range actors {
+				if name == toUpper(a.impl.Name()) && stopMethods[sel.Sel.Name] || name == "System" && systemStopMethods[sel.Sel.Name] {
+					a.stopped = true
+				}
+			}
+		}
+		for _, a := range actors {
+			if a.stopped {
+				continue
+			}
+			for _, pos := range a.created {
+				findings = append(findings, Finding{fset.Position(pos), fmt.Sprintf("actor %s is created but Stop, Close or Kill is never called", toUpper(a.impl.Name()))})
+			}
+		}
+	}
+	sort.Slice(findings, func(i, j int) bool {
+		pi, pj := findings[i].Pos, findings[j].Pos
+		if pi.Filename != pj.Filename {
+			return pi.Filename < pj.Filename
+		}
+		return pi.Offset < pj.Offset
+	})
+	return findings, nil
+}
+
+// doctorActors finds the actors declared in the package and their methods
+func doctorActors(pkg *types.Package, files []*ast.File) map[*types.TypeName]*doctorActor {
+	actors := make(map[*types.TypeName]*doctorActor)
+	scope := pkg.Scope()
+	for _, name := range scope.Names() {
+		tn, ok := scope.Lookup(name).(*types.TypeName)
+		if !ok {
+			continue
+		}
+		st, ok := tn.Type().Underlying().(*types.Struct)
+		if !ok {
+			continue
+		}
+		for i := 0; i < st.NumFields(); i++ {
+			if st.Field(i).Embedded() && st.Field(i).Name() == "Actor" {
+				a := &doctorActor{impl: tn, methods: make(map[string]bool), async: make(map[string]bool), constructors: make(map[string]bool)}
+				a.constructors[actorInterface.New+toUpper(tn.Name())] = true
+				parseTagList(reflect.StructTag(st.Tag(i)), "async", a.async)
+				actors[tn] = a
+			}
+		}
+	}
+
+	for _, f := range files {
+		if isGeneratedFile(f) {
+			continue
+		}
+		for _, decl := range f.Decls {
+			if fd, ok := decl.(*ast.FuncDecl); ok && fd.Recv != nil {
+				a := actors[recvTypeName(fd, scope)]
+				switch {
+				case a == nil:
+				case isConstructor(fd.Name.Name, actorInterface.Init):
+					a.constructors[actorInterface.New+toUpper(a.impl.Name())+strings.TrimPrefix(fd.Name.Name, actorInterface.Init)] = true
+				case !excludedMethods[fd.Name.Name]:
+					a.methods[fd.Name.Name] = true
+				}
+			}
+		}
+	}
+	return actors
+}
+
+// doctorFile checks the uses of the actors in a file
+func doctorFile(f *ast.File, fset *token.FileSet, info *types.Info, scope *types.Scope, actors map[*types.TypeName]*doctorActor) []Finding {
+	var findings []Finding
+	report := func(pos token.Pos, format string, args ...interface{}) {
+		findings = append(findings, Finding{fset.Position(pos), fmt.Sprintf(format, args...)})
+	}
+
+	for _, decl := range f.Decls {
+		fd, ok := decl.(*ast.FuncDecl)
+		if !ok || fd.Body == nil {
+			continue
+		}
+		var handler *doctorActor
+		if fd.Recv != nil {
+			handler = actors[recvTypeName(fd, scope)]
+		}
+
+		ast.Inspect(fd.Body, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.GoStmt:
+				ast.Inspect(n.Call, func(n ast.Node) bool {
+					if id, ok := n.(*ast.Ident); ok {
+						if a := actorOf(info.Uses[id], actors); a != nil {
+							report(id.Pos(), "implementation of actor %s shared with another goroutine, use a reference", toUpper(a.impl.Name()))
+						}
+					}
+					return true
+				})
+
+			case *ast.CallExpr:
+				if id, ok := n.Fun.(*ast.Ident); ok {
+					for _, a := range actors {
+						if a.constructors[id.Name] {
+							a.created = append(a.created, n.Pos())
+						}
+					}
+				}
+
+			case *ast.SelectorExpr:
+				sel := info.Selections[n]
+				if sel == nil || sel.Kind() != types.MethodVal {
+					return true
+				}
+				name := n.Sel.Name
+				if a := actors[namedOf(sel.Recv())]; a != nil {
+					switch {
+					case name == actorInterface.Stop:
+						a.stopped = true
+					case a.methods[name] && handler != a:
+						report(n.Sel.Pos(), "method %s of actor %s called directly, use a reference", name, toUpper(a.impl.Name()))
+					}
+					return true
+				}
+				if a := refOf(sel.Recv(), actors); a != nil && handler != nil {
+					if m := toLower(name); a.methods[m] && !a.async[m] {
+						report(n.Sel.Pos(), "blocking call to %s.%s inside an actor method", toUpper(a.impl.Name())+"Ref", name)
+					}
+				}
+			}
+			return true
+		})
+	}
+	return findings
+}
+
+// isGeneratedFile returns true if the file has the generated code comment
+func isGeneratedFile(f *ast.File) bool {
+	for _, cg := range f.Comments {
+		if cg.Pos() >= f.Package {
+			break
+		}
+		for _, c := range cg.List {
+			if generatedFile.MatchString(c.Text) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// recvTypeName returns the type name of a method receiver
+func recvTypeName(fd *ast.FuncDecl, scope *types.Scope) *types.TypeName {
+	expr := fd.Recv.List[0].Type
+	if star, ok := expr.(*ast.StarExpr); ok {
+		expr = star.X
+	}
+	id, ok := expr.(*ast.Ident)
+	if !ok {
+		return nil
+	}
+	tn, _ := scope.Lookup(id.Name).(*types.TypeName)
+	return tn
+}
+
+// namedOf returns the type name of t or *t
+func namedOf(t types.Type) *types.TypeName {
+	if p, ok := t.(*types.Pointer); ok {
+		t = p.Elem()
+	}
+	if n, ok := t.(*types.Named); ok {
+		return n.Obj()
+	}
+	return nil
+}
+
+// namedName returns the name of the type t or *t
+func namedName(t types.Type) string {
+	if tn := namedOf(t); tn != nil {
+		return tn.Name()
+	}
+	return ""
+}
+
+// actorOf returns the actor implemented by the type of obj, if it's an
+// actor implementation
+func actorOf(obj types.Object, actors map[*types.TypeName]*doctorActor) *doctorActor {
+	if obj == nil {
+		return nil
+	}
+	if _, ok := obj.(*types.Var); !ok {
+		return nil
+	}
+	return actors[namedOf(obj.Type())]
+}
+
+// refOf returns the actor whose reference type is t
+func refOf(t types.Type, actors map[*types.TypeName]*doctorActor) *doctorActor {
+	name := namedName(t)
+	for _, a := range actors {
+		if name == toUpper(a.impl.Name())+"Ref" {
+			return a
+		}
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package compiler
+
+import (
+	"fmt"
+	"go/format"
+	"go/importer"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// doctorCounter is the actor of the packages checked by TestDoctorStopped
+const doctorCounter = `package counter
+
+import "github.com/carevaloc/goactors/actor"
+
+type counter struct {
+	actor.Actor
+	n int
+}
+
+func (c *counter) incr() {
+	c.n++
+}
+`
+
+// doctorRun creates the actor and stops it with the statements in %s
+const doctorRun = `package counter
+
+import (
+	"context"
+
+	"github.com/carevaloc/goactors/actor"
+)
+
+var (
+	_ = context.Background
+	_ = actor.NewSystem
+)
+
+func run() {
+	c := NewCounter()
+	%s
+}
+`
+
+// TestDoctorStopped checks that an actor stopped with any of the methods of
+// its interface or of a System isn't reported as never stopped
+func TestDoctorStopped(t *testing.T) {
+	if _, err := importer.Default().Import("github.com/carevaloc/goactors/actor"); err != nil {
+		t.Skipf("actor package not installed: %v", err)
+	}
+	tests := []struct {
+		name    string
+		stop    string
+		stopped bool
+	}{
+		{"Stop", "c.Stop()", true},
+		{"Close", "c.Close(context.Background())", true},
+		{"Kill", "c.Kill()", true},
+		{"System.Stop", "sys := actor.NewSystem()\n\tsys.Add(c)\n\tsys.Stop()", true},
+		{"System.Shutdown", "sys := actor.NewSystem()\n\tsys.Add(c)\n\tsys.Shutdown(context.Background())", true},
+		{"never", "c.Ref().Incr()", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			input := filepath.Join(dir, "counter.go")
+			if err := ioutil.WriteFile(input, []byte(doctorCounter), 0644); err != nil {
+				t.Fatal(err)
+			}
+			pkg, err := ParseFile(input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var bldr strings.Builder
+			Generate(&bldr, pkg)
+			src, err := format.Source([]byte(bldr.String()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := ioutil.WriteFile(filepath.Join(dir, "counter_actor.go"), src, 0644); err != nil {
+				t.Fatal(err)
+			}
+			use := fmt.Sprintf(doctorRun, test.stop)
+			if err := ioutil.WriteFile(filepath.Join(dir, "run.go"), []byte(use), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			findings, err := Doctor(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var reported bool
+			for _, f := range findings {
+				reported = reported || strings.Contains(f.Message, "is created but")
+			}
+			if reported == test.stopped {
+				t.Errorf("stopped with %s: findings %v", test.stop, findings)
+			}
+		})
+	}
+}
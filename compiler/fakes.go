@@ -0,0 +1,307 @@
+package compiler
+
+import (
+	"io"
+	"log"
+	"text/template"
+)
+
+// Dependency is a parameter of an actor's init method that receives the
+// reference of another actor of the package
+type Dependency struct {
+	Param Param
+	Actor *Actor
+}
+
+// FieldName returns the name of the field holding the fake of the dependency
+func (d Dependency) FieldName() string {
+	return toUpper(d.Param.Name)
+}
+
+// dependencies returns the parameters of the actor's init method that are
+// references to other actors of the package. They are only replaced by fakes
+// if all the actors have them, otherwise it returns nil
+func dependencies(pkg Package, a *Actor) []Dependency {
+	var deps []Dependency
+	if a.Init == nil {
+		return deps
+	}
+	for _, param := range a.Init.Params {
+		for _, other := range pkg.Actors {
+			if param.Type == "*"+other.Ref() {
+				if !other.Enabled(FeatureFakes) {
+					return nil
+				}
+				deps = append(deps, Dependency{Param: param, Actor: other})
+			}
+		}
+	}
+	return deps
+}
+
+// isDependency returns true if the parameter is one of the dependencies
+func isDependency(deps []Dependency, param Param) bool {
+	for _, d := range deps {
+		if d.Param == param {
+			return true
+		}
+	}
+	return false
+}
+
+// fakeImports returns the imports used by the test helpers of the actors
+// with fakes: the ones used by the methods, and by the init methods with
+// dependencies
+func fakeImports(pkg Package, fakes []*Actor) map[string]string {
+	imports := make(map[string]string)
+	if len(fakes) > 0 {
+		imports["github.com/carevaloc/goactors/actor"] = ""
+	}
+	for _, a := range fakes {
+		for _, m := range a.Methods {
+			for path, alias := range m.imports {
+				imports[path] = alias
+			}
+		}
+		if len(dependencies(pkg, a)) > 0 {
+			for path, alias := range a.Init.imports {
+				imports[path] = alias
+			}
+		}
+	}
+	return imports
+}
+
+// GenerateFakes generates the test helpers of the actors in the package: a
+// fake for each actor, that can be used in place of its reference, and a
+// constructor that wires fakes to the actors that receive references to
+// other actors in their init method. Only the actors with the fakes feature
+// have them. The output is meant to be a _test.go file
+func GenerateFakes(output io.Writer, pkg Package) {
+	var fakes []*Actor
+	for _, a := range pkg.Actors {
+		if a.Enabled(FeatureFakes) {
+			fakes = append(fakes, a)
+		}
+	}
+
+	funcMap := template.FuncMap{
+		"toLower":      toLower,
+		"toUpper":      toUpper,
+		"isDependency": isDependency,
+		"dependencies": func(a *Actor) []Dependency {
+			return dependencies(pkg, a)
+		},
+	}
+
+	t := template.New("Fakes template").Funcs(funcMap)
+
+	t, err := t.Parse(fakesTmpl)
+	if err != nil {
+		log.Fatal("Parse: ", err)
+	}
+
+	fakesPkg := pkg
+	fakesPkg.Actors = fakes
+	err = t.Execute(output, struct {
+		Package
+		Imports map[string]string
+	}{fakesPkg, fakeImports(pkg, fakes)})
+}
+
+// fakesTmpl is the template used to generate the test helpers
+const fakesTmpl = `
+{{- range .Header}}{{.}}
+{{end}}{{if .Header}}
+{{end}}
+{{- if .BuildTags}}//go:build {{.BuildTags}}
+
+{{end -}}
+// Code generated by actorc. DO NOT EDIT.
+
+package {{.Name}}
+
+import (
+{{- if .Actors}}
+	"sync"
+	"testing"
+{{- end}}
+{{- range $key, $value := .Imports}}
+	{{$value}} "{{$key}}"
+{{- end}}
+)
+{{range .Actors}}{{$actor := .}}{{$actorName := .ExpName}}{{$actorRef := .Ref}}{{$actorImpl := .Impl}}{{$fake := print "Fake" .ExpName}}
+// {{$fake}} replaces a {{$actorName}} actor in tests. Its reference receives the
+// requests as a real one, and each method calls the function of the field with
+// the same name plus the On prefix, if it's set, or returns zero values.
+// Every request is recorded
+type {{$fake}} struct {
+{{- range .Methods}}
+	On{{.Name}} func({{range $i, $param := .Params}}{{if $i}}, {{end}}{{.Name}} {{.Type}}{{end}}){{if .RetVals}} ({{range $i, $ret := .RetVals}}{{if $i}}, {{end}}{{.Type}}{{end}}){{end}}
+{{- end}}
+
+	in     chan interface{}
+	stopCh chan struct{}
+	act    *{{$actorImpl}}
+	mu     sync.Mutex
+	calls  []actor.Request
+	stop   sync.Once
+}
+
+// NewFake{{$actorName}} creates and starts a fake {{$actorName}}, which is stopped
+// when the test finishes
+func NewFake{{$actorName}}(t testing.TB) *{{$fake}} {
+	f := &{{$fake}}{
+		in:     make(chan interface{}, actor.DefaultInCap),
+		stopCh: make(chan struct{}),
+		act:    &{{$actorImpl}}{},
+	}
+	f.act.SetID()
+{{- if .HasSeq}}
+	f.act.EnableSeq()
+{{- end}}
+	bound := make(chan struct{})
+	go f.receive(bound)
+	<-bound
+	t.Cleanup(f.Stop)
+	return f
+}
+
+// Ref returns a reference to the fake
+func (f *{{$fake}}) Ref() *{{$actorRef}} {
+	return &{{$actorRef}}{
+		in:     f.in,
+		stopCh: f.stopCh,
+		act:    f.act,
+	}
+}
+
+{{- with .View}}{{$type := (index .RetValues 0).Type}}
+
+// SetView sets the projection returned by the View method of the
+// references
+func (f *{{$fake}}) SetView(v {{$type}}) {
+	f.act.Publish(v)
+}
+{{- end}}
+
+// Stop stops the fake. Only the first call has effect
+func (f *{{$fake}}) Stop() {
+	f.stop.Do(func() {
+		close(f.stopCh)
+	})
+}
+
+// Calls returns the requests received for the given method, or all of them
+// if method is empty
+func (f *{{$fake}}) Calls(method string) []actor.Request {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var calls []actor.Request
+	for _, req := range f.calls {
+		if method == "" || req.Method() == method {
+			calls = append(calls, req)
+		}
+	}
+	return calls
+}
+
+// AssertCalled fails the test if the method was not called the given number
+// of times
+func (f *{{$fake}}) AssertCalled(t testing.TB, method string, times int) {
+	t.Helper()
+	if n := len(f.Calls(method)); n != times {
+		t.Errorf("{{$actorName}}.%s called %d times, expected %d", method, n, times)
+	}
+}
+
+func (f *{{$fake}}) receive(bound chan struct{}) {
+	f.act.Bind()
+{{- if .HasSeq}}
+	defer f.act.CloseSeq()
+{{- end}}
+	close(bound)
+	for {
+		var msg interface{}
+		select {
+		case msg = <-f.in:
+		case <-f.stopCh:
+			return
+		}
+		if req, ok := msg.(actor.Request); ok {
+			f.mu.Lock()
+			f.calls = append(f.calls, req)
+			f.mu.Unlock()
+		}
+		switch msg := msg.(type) {
+{{- range .Methods}}{{$met := .}}
+		case {{if $actor.Arena}}*{{end}}{{.Request}}:
+{{- if .HasResponse}}
+			var resp {{.Response}}
+			if f.On{{.Name}} != nil {
+				{{range $i, $ret := .RetVals}}{{if $i}}, {{end}}resp.{{$met.ResponseField $i}}{{end}}{{if .RetVals}} = {{end}}f.On{{.Name}}({{range $i, $param := .Params}}{{if $i}}, {{end}}{{if .Blob}}actor.GetBlob(msg.{{.Field}}){{else}}msg.{{.Field}}{{end}}{{end}})
+			}
+			msg.out <- resp
+{{- else}}
+			if f.On{{.Name}} != nil {
+				f.On{{.Name}}({{range $i, $param := .Params}}{{if $i}}, {{end}}{{if .Blob}}actor.GetBlob(msg.{{.Field}}){{else}}msg.{{.Field}}{{end}}{{end}})
+			}
+{{- if .Seq}}
+			f.act.SeqDone(msg.seq)
+{{- end}}
+{{- end}}
+{{- if eq .Endpoint "Producer"}}
+		case {{.EndpointMessage}}:
+			msg.link.Produce(msg.n, func() (interface{}, bool) {
+				if f.On{{.Name}} == nil {
+					return nil, false
+				}
+				return f.On{{.Name}}()
+			})
+{{- else if eq .Endpoint "Consumer"}}
+		case {{.EndpointMessage}}:
+			if !msg.end && f.On{{.Name}} != nil {
+				f.On{{.Name}}(msg.v)
+			}
+			msg.consumed()
+{{- end}}
+{{- end}}
+		}
+	}
+}
+{{$deps := dependencies .}}{{if $deps}}{{$init := .Init}}
+// {{$actorName}}Fakes contains the fakes wired to a {{$actorName}} actor
+type {{$actorName}}Fakes struct {
+{{- range $deps}}
+	{{.FieldName}} *Fake{{.Actor.ExpName}}
+{{- end}}
+}
+
+// New{{$actorName}}WithFakes creates and starts a {{$actorName}} actor whose
+// dependencies are fakes, which are returned. The actor is stopped when the
+// test finishes
+func New{{$actorName}}WithFakes(t testing.TB
+{{- range $init.Params}}{{if not (isDependency $deps .)}}, {{.Name}} {{.Type}}{{end}}{{end}}) ({{$actorName}}, *{{$actorName}}Fakes) {
+	fakes := &{{$actorName}}Fakes{
+{{- range $deps}}
+		{{.FieldName}}: NewFake{{.Actor.ExpName}}(t),
+{{- end}}
+	}
+{{- range $deps}}
+	{{.Param.Name}} := fakes.{{.FieldName}}.Ref()
+{{- end}}
+{{- if $init.Fails}}
+	created, err := New{{$actorName}}({{range $i, $param := $init.Params}}{{if $i}}, {{end}}{{.Name}}{{end}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	act := created.Start()
+{{- else}}
+	act := New{{$actorName}}({{range $i, $param := $init.Params}}{{if $i}}, {{end}}{{.Name}}{{end}}).Start()
+{{- end}}
+	t.Cleanup(act.Stop)
+	return act, fakes
+}
+{{end}}
+{{- end}}
+`
@@ -0,0 +1,178 @@
+package compiler
+
+import (
+	"io"
+	"log"
+	"text/template"
+)
+
+// fuzzTypes are the types of the parameters supported by the fuzzing engine,
+// with the zero value added to the seed corpus
+var fuzzTypes = map[string]string{
+	"string":  `""`,
+	"[]byte":  "[]byte{}",
+	"bool":    "false",
+	"byte":    "byte(0)",
+	"rune":    "rune(0)",
+	"int":     "int(0)",
+	"int8":    "int8(0)",
+	"int16":   "int16(0)",
+	"int32":   "int32(0)",
+	"int64":   "int64(0)",
+	"uint":    "uint(0)",
+	"uint8":   "uint8(0)",
+	"uint16":  "uint16(0)",
+	"uint32":  "uint32(0)",
+	"uint64":  "uint64(0)",
+	"float32": "float32(0)",
+	"float64": "float64(0)",
+}
+
+// Fuzzable returns true if the method has parameters and the fuzzing engine
+// supports all their types
+func (m *Method) Fuzzable() bool {
+	for _, p := range m.Params {
+		if _, ok := fuzzTypes[p.Type]; !ok {
+			return false
+		}
+	}
+	return len(m.Params) > 0
+}
+
+// FuzzTarget returns the name of the fuzz target of a method of the actor
+func (a *Actor) FuzzTarget(m Method) string {
+	return "Fuzz" + a.Name + m.Name
+}
+
+// FuzzHooks returns the name of the variable with the hooks of the fuzz
+// targets of the actor
+func (a *Actor) FuzzHooks() string {
+	return a.Impl + "Fuzz"
+}
+
+// FuzzConstructor returns the constructor used by the fuzz targets when
+// the New hook isn't set: the one without parameters, or nil if there is
+// none and the hook must be set. The New function of the actors without
+// constructors is returned as a method without parameters
+func (a *Actor) FuzzConstructor() *Method {
+	if len(a.Constructors) == 0 {
+		return &Method{Name: actorInterface.Init, actor: a.Impl}
+	}
+	for _, c := range a.Constructors {
+		if len(c.Params) == 0 {
+			return c
+		}
+	}
+	return nil
+}
+
+// fuzzActors returns the actors with fuzz targets
+func fuzzActors(pkg Package) []*Actor {
+	var actors []*Actor
+	for _, a := range pkg.Actors {
+		if !a.Enabled(FeatureFuzz) {
+			continue
+		}
+		for _, m := range a.Methods {
+			if m.Fuzzable() {
+				actors = append(actors, a)
+				break
+			}
+		}
+	}
+	return actors
+}
+
+// GenerateFuzz generates the fuzz targets of the actors in the package: one
+// for each method whose parameters are supported by the fuzzing engine,
+// that calls it with the fuzzed parameters on a new actor and checks the
+// invariants supplied by the user once the actor stops. The output is meant
+// to be a _test.go file
+func GenerateFuzz(output io.Writer, pkg Package) {
+	funcMap := template.FuncMap{
+		"zero": func(p Param) string {
+			return fuzzTypes[p.Type]
+		},
+	}
+
+	t := template.New("Fuzz template").Funcs(funcMap)
+
+	t, err := t.Parse(fuzzTmpl)
+	if err != nil {
+		log.Fatal("Parse: ", err)
+	}
+
+	fuzzPkg := pkg
+	fuzzPkg.Actors = fuzzActors(pkg)
+	err = t.Execute(output, fuzzPkg)
+}
+
+// fuzzTmpl is the template used to generate the fuzz targets
+const fuzzTmpl = `
+{{- range .Header}}{{.}}
+{{end}}{{if .Header}}
+{{end}}
+{{- if .BuildTags}}//go:build {{.BuildTags}}
+
+{{end -}}
+// Code generated by actorc. DO NOT EDIT.
+
+package {{.Name}}
+{{- if .Actors}}
+
+import (
+	"context"
+	"testing"
+
+	"github.com/carevaloc/goactors/actor"
+)
+{{- end}}
+{{range .Actors}}{{$actor := .}}{{$actorName := .ExpName}}{{$hooks := .FuzzHooks}}
+// {{$hooks}} are the hooks of the fuzz targets of {{$actorName}}, set by the
+// tests of the package in an init function
+var {{$hooks}} struct {
+	// New creates the actor of each input, which is started by the target.
+	// It's required if {{$actorName}} has no constructor without parameters
+	New func(t *testing.T) {{$actorName}}
+	// Check checks the invariants of the actor, once it processed the
+	// message and stopped
+	Check func(t *testing.T, act *{{.Impl}})
+}
+
+// start{{$actorName}}Fuzz creates and starts the actor of a fuzz input
+func start{{$actorName}}Fuzz(t *testing.T) {{$actorName}} {
+	if {{$hooks}}.New != nil {
+		return {{$hooks}}.New(t).Start()
+	}
+{{- with .FuzzConstructor}}
+{{- if .Fails}}
+	act, err := {{.Constructor}}()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return act.Start()
+{{- else}}
+	return {{.Constructor}}().Start()
+{{- end}}
+{{- else}}
+	t.Skip("{{$hooks}}.New is not set")
+	return nil
+{{- end}}
+}
+{{range .Methods}}{{if .Fuzzable}}
+// {{$actor.FuzzTarget .}} calls {{.Name}} with the fuzzed parameters on a new
+// {{$actorName}} and checks its invariants with {{$hooks}}.Check
+func {{$actor.FuzzTarget .}}(f *testing.F) {
+	f.Add({{range $i, $p := .Params}}{{if $i}}, {{end}}{{zero $p}}{{end}})
+	f.Fuzz(func(t *testing.T{{range $i, $p := .Params}}, p{{$i}} {{$p.Type}}{{end}}) {
+		act := start{{$actorName}}Fuzz(t)
+		act.Ref().{{.Name}}({{range $i, $p := .Params}}{{if $i}}, {{end}}p{{$i}}{{end}})
+		if err := act.Close(context.Background()); err != nil && err != actor.ErrStopped {
+			t.Error(err)
+		}
+		if {{$hooks}}.Check != nil {
+			{{$hooks}}.Check(t, act.(*{{$actor.Impl}}))
+		}
+	})
+}
+{{end}}{{end}}{{end}}`
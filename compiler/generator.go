@@ -3,9 +3,30 @@ package compiler
 import (
 	"io"
 	"log"
+	"strings"
 	"text/template"
 )
 
+// TemplateName is the name of the template used to generate the actor code
+const TemplateName = "Actor template"
+
+// HeaderComment turns the text of a header, like a license block, into comment
+// lines. Lines that are already comments are left as they are
+func HeaderComment(text string) []string {
+	var lines []string
+	for _, line := range strings.Split(strings.TrimRight(text, "\n"), "\n") {
+		switch {
+		case strings.HasPrefix(line, "//"):
+			lines = append(lines, line)
+		case strings.TrimSpace(line) == "":
+			lines = append(lines, "//")
+		default:
+			lines = append(lines, "// "+line)
+		}
+	}
+	return lines
+}
+
 // Generate generates the actor code passing a Packate
 // object containig actor definitions to a text/Template
 func Generate(output io.Writer, pkg Package) {
@@ -14,7 +35,7 @@ func Generate(output io.Writer, pkg Package) {
 		"toUpper": toUpper,
 	}
 
-	t := template.New("Actor template").Funcs(funcMap)
+	t := template.New(TemplateName).Funcs(funcMap)
 
 	t, err := t.Parse(actorTmpl)
 	if err != nil {
@@ -25,52 +46,227 @@ func Generate(output io.Writer, pkg Package) {
 }
 
 // actorTmpl is a template (/text/Template) used to generate the actor code
-const actorTmpl = `package {{.Name}}
+const actorTmpl = `
+{{- range .Header}}{{.}}
+{{end}}{{if .Header}}
+{{end}}
+{{- if .BuildTags}}//go:build {{.BuildTags}}
+
+{{end -}}
+// Code generated by actorc. DO NOT EDIT.
+
+package {{.Name}}
 {{$actorInt := .ActorInt}}
 import (
 {{- range $key, $value := .Imports}}
-	"{{$key}}"
+	{{$value}} "{{$key}}"
 {{- end}}
 )
-{{range .Actors}}{{$actorName := .ExpName}}{{$actorRef := .Ref}}{{$actorImpl := .Impl}}{{$methods := .Methods}}{{$init := .Init}}{{$stopRequest := .StopRequest}}
+{{range .Actors}}{{$actor := .}}{{$actorName := .ExpName}}{{$actorRef := .Ref}}{{$actorImpl := .Impl}}{{$methods := .Methods}}{{$stopRequest := .StopRequest}}
 type {{$actorName}} interface {
+	actor.Instance
 	Start() {{$actorName}}
+	StartChecked() ({{$actorName}}, error)
+	StartOn(sched *actor.Scheduler) ({{$actorName}}, error)
 	Ref() *{{$actorRef}}
 	Stop()
+	Close(ctx context.Context) error
+	Kill()
 }
 
 type {{$actorRef}} struct {
 	in  chan interface{}
-	out chan interface{}
 	stopCh chan struct{}	
+	sender interface{}
+	act    *{{$actorImpl}}
 }
 
-func {{$actorInt.New}}{{$actorName}}({{if $init}}{{- range $i, $param:=$init.Params}}{{if $i}}, {{end}}{{- .Name}} {{.Type}}{{end}}{{end}}) {{$actorName}} {
+{{- if not .Constructors}}
+func {{$actorInt.New}}{{$actorName}}() {{$actorName}} {
+	act := &{{$actorImpl}} {
+		Actor: actor.Actor{},
+	}
+	act.In = make(chan interface{}, act.InCapacity())
+	act.StopCh = make(chan struct{})
+	act.SetLoop(act.receive)
+	act.SetHandler("{{$actorName}}", act.mailbox, act.dispatch)
+{{- if $actor.Enabled "json"}}
+	act.SetReplay(func(msg interface{}) error {
+		return act.{{$actorInt.Ref}}().replay(msg)
+	})
+{{- end}}
+{{- if $actor.Arena}}
+	act.SetArena(actor.NewArena("{{$actorName}}", make([]{{$actor.Slot}}, {{$actor.Arena}}), {{$actor.Arena}}), act.handle)
+{{- end}}
+{{- if $actor.Batched}}
+	act.SetReplies({{$actor.Batch}})
+{{- end}}
+{{- if $actor.View}}
+	act.Publish(act.view())
+{{- end}}
+	return act
+}
+{{- end}}
+{{- range .Constructors}}{{$ctor := .}}
+func {{.Constructor}}({{- range $i, $param:=.Params}}{{if $i}}, {{end}}{{- .Name}} {{.Type}}{{end}}) {{if .Fails}}({{$actorName}}, error){{else}}{{$actorName}}{{end}} {
 	act := &{{$actorImpl}} {
 		Actor: actor.Actor{},
 	}
 	act.In = make(chan interface{}, act.InCapacity())
 	act.StopCh = make(chan struct{})
-{{- if $init}}
-	act.{{$actorInt.Init}}({{- range $i, $param:=$init.Params}}{{if $i}}, {{end}}{{- .Name}}{{end}})
-{{- end}}	
+	act.SetLoop(act.receive)
+	act.SetHandler("{{$actorName}}", act.mailbox, act.dispatch)
+{{- if $actor.Enabled "json"}}
+	act.SetReplay(func(msg interface{}) error {
+		return act.{{$actorInt.Ref}}().replay(msg)
+	})
+{{- end}}
+{{- if $actor.Arena}}
+	act.SetArena(actor.NewArena("{{$actorName}}", make([]{{$actor.Slot}}, {{$actor.Arena}}), {{$actor.Arena}}), act.handle)
+{{- end}}
+{{- if $actor.Batched}}
+	act.SetReplies({{$actor.Batch}})
+{{- end}}
+{{- if .Fails}}
+	if err := act.{{.Name}}({{- range $i, $param:=.Params}}{{if $i}}, {{end}}{{- .Name}}{{end}}); err != nil {
+		return nil, err
+	}
+	act.SetInit(func() {
+		if err := act.{{.Name}}({{- range $i, $param:=.Params}}{{if $i}}, {{end}}{{if and (not $i) $ctor.Context}}context.Background(){{else}}{{- .Name}}{{end}}{{end}}); err != nil {
+			actor.Log.Printf("{{$actorName}}: {{.Name}} failed on restart: %v\n", err)
+		}
+	})
+{{- if $actor.View}}
+	act.Publish(act.view())
+{{- end}}
+	return act, nil
+{{- else}}
+	act.{{.Name}}({{- range $i, $param:=.Params}}{{if $i}}, {{end}}{{- .Name}}{{end}})
+	act.SetInit(func() {
+		act.{{.Name}}({{- range $i, $param:=.Params}}{{if $i}}, {{end}}{{if and (not $i) $ctor.Context}}context.Background(){{else}}{{- .Name}}{{end}}{{end}})
+	})
+{{- if $actor.View}}
+	act.Publish(act.view())
+{{- end}}
 	return act
+{{- end}}
 }
+{{end}}
 
+// {{$actorInt.Start}} starts the actor. It panics if StartChecked fails
 func (act *{{$actorImpl}}) {{$actorInt.Start}}() {{$actorName}} {
-	go act.receive()
+	if _, err := act.StartChecked(); err != nil {
+		panic(err)
+	}
 	return act
 }
 
+// StartChecked starts the actor, or returns an error if it wasn't created
+// with its New function, it's already started or stopped, or its PreStart
+// hook fails
+func (act *{{$actorImpl}}) StartChecked() ({{$actorName}}, error) {
+	if err := actor.Prepare("{{$actorName}}", act); err != nil {
+		return nil, err
+	}
+	go act.receive()
+	return act, nil
+}
+
+// StartOn starts the actor as a task of the scheduler, instead of in its
+// own goroutine. It returns the same errors as StartChecked
+func (act *{{$actorImpl}}) StartOn(sched *actor.Scheduler) ({{$actorName}}, error) {
+	if err := actor.Prepare("{{$actorName}}", act); err != nil {
+		return nil, err
+	}
+	sched.Schedule(act)
+	return act, nil
+}
+
+// Methods describes the methods of the actor
+func (act *{{$actorImpl}}) Methods() []actor.MethodInfo {
+	return []actor.MethodInfo{
+{{- range $methods}}
+		{Name: "{{.Name}}", Params: []actor.ParamInfo{ {{- range $i, $p := .Params}}{{if $i}}, {{end}}{Name: {{printf "%q" $p.Name}}, Type: {{printf "%q" $p.Type}}}{{end}}}, Results: []actor.ParamInfo{ {{- range $i, $r := .RetVals}}{{if $i}}, {{end}}{Name: {{printf "%q" $r.Name}}, Type: {{printf "%q" $r.Type}}}{{end}}}, Async: {{.Async}}{{if .Seq}}, Seq: true{{end}}, Stage: "{{.Stage}}"},
+{{- end}}
+	}
+}
+
+// Methods describes the methods of the actor
+func (ref *{{$actorRef}}) Methods() []actor.MethodInfo {
+	return ref.act.Methods()
+}
+
 func (act *{{$actorImpl}}) {{$actorInt.Ref}}() *{{$actorRef}} {
 	ref := &{{$actorRef}}{
 		in:  act.In,
 		stopCh: act.StopCh,		
-		out: make(chan interface{}),
+		act: act,
 	}
 	return ref
 }
 
+func (ref *{{$actorRef}}) From(sender interface{}) *{{$actorRef}} {
+	r := *ref
+	r.sender = sender
+	return &r
+}
+
+// ID returns the ID of the actor the reference points to
+func (ref *{{$actorRef}}) ID() actor.ID {
+	return ref.act.ActorID()
+}
+
+// Equals returns true if both references point to the same actor, whatever
+// their senders
+func (ref *{{$actorRef}}) Equals(other *{{$actorRef}}) bool {
+	return other != nil && ref.act == other.act
+}
+
+{{- with .View}}{{$type := (index .RetValues 0).Type}}
+
+// View returns the projection of the actor's state published after the
+// last message it processed, without waiting for the actor
+func (ref *{{$actorRef}}) View() {{$type}} {
+	v, _ := ref.act.Projection().({{$type}})
+	return v
+}
+{{- end}}
+{{- if .HasSeq}}
+
+// WaitFor waits until the actor processed the write that returned the
+// sequence token seq, and the ones before it, so the reads sent afterwards
+// reflect them. It returns an error if the actor stopped before or ctx is
+// done first
+func (ref *{{$actorRef}}) WaitFor(ctx context.Context, seq actor.Seq) error {
+	return ref.act.WaitFor(ctx, seq)
+}
+{{- end}}
+
+{{- if $actor.Enabled "json"}}
+
+// replay sends again a request decoded from a dead letter log, calling the
+// method with its parameters. The results are discarded
+func (ref *{{$actorRef}}) replay(msg interface{}) error {
+	switch {{if $actor.ReplaysParams}}msg := {{end}}msg.(type) {
+{{- range $methods}}{{if .Replayable}}
+	case *{{.Request}}:
+{{- if and .Async .RetVals}}
+		poll := ref.{{.Name}}({{range $i, $p := .Params}}{{if $i}}, {{end}}msg.{{.Field}}{{end}})
+		actor.AwaitPoll(func() bool {
+			{{range .RetVals}}_, {{end}}done := poll()
+			return done
+		})
+{{- else}}
+		ref.{{.Name}}({{range $i, $p := .Params}}{{if $i}}, {{end}}msg.{{.Field}}{{end}})
+{{- end}}
+{{- end}}{{end}}
+	default:
+		return actor.ErrNotReplayable
+	}
+	return nil
+}
+{{- end}}
+
 func (ref *{{$actorRef}}) Stopped() bool {
 	select {
 	case <-ref.stopCh:
@@ -80,21 +276,202 @@ func (ref *{{$actorRef}}) Stopped() bool {
 	}
 }
 
-type {{$stopRequest}} struct {}
+type {{$stopRequest}} struct {
+	ctx  context.Context
+	done chan error
+}
 
+// Stop stops the actor once it processes the messages already sent. Only
+// the first call has effect
 func (act *{{$actorImpl}}) Stop() {
-	act.In <- {{$stopRequest}}{}
+	if act.BeginStop() {
+		act.In <- {{$stopRequest}}{}
+		act.Notify()
+	}
+}
+
+// Kill stops the actor immediately: it finishes the message being processed
+// and the messages waiting in its mailbox are discarded as dead letters. It
+// can be called after Stop or Close to abort them
+func (act *{{$actorImpl}}) Kill() {
+	act.SetKilled()
+	if act.BeginStop() {
+		act.In <- {{$stopRequest}}{}
+		act.Notify()
+	}
+}
+
+// Close stops the actor like Stop and waits until it processes the messages
+// already sent and its PostStop hook, which receives ctx, returns. It
+// returns the error of the hook, the one of ctx if it's done first, or
+// actor.ErrStopped if the actor was already stopped
+func (act *{{$actorImpl}}) Close(ctx context.Context) error {
+	if !act.BeginStop() {
+		return actor.ErrStopped
+	}
+	done := make(chan error, 1)
+	act.In <- {{$stopRequest}}{ctx: ctx, done: done}
+	act.Notify()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func init() {
+{{- if $actor.Enabled "metrics"}}
+	actor.RegisterMetrics("{{$actorName}}")
+{{- end}}
+	actor.RegisterMessage("{{$actor.MessageName $.Name "Stop"}}", {{$actor.Version}}, func() interface{} { return new({{$stopRequest}}) })
+{{- range .Methods}}
+	actor.RegisterMessage("{{$actor.MessageName $.Name (print .Name "Request")}}", {{$actor.Version}}, func() interface{} { return new({{.Request}}) })
+	actor.RegisterMessage("{{$actor.MessageName $.Name (print .Name "Response")}}", {{$actor.Version}}, func() interface{} { return new({{.Response}}) })
+{{- end}}
+}
+{{- if .Arena}}
+
+// {{$actor.Slot}} is an element of the arena of the actor, with a request
+// of each method
+type {{$actor.Slot}} struct {
+{{- range .Methods}}
+	{{.Name}} {{.Request}}
+{{- end}}
 }
+
+// slot acquires a slot of the arena, waiting for one if they are all in
+// use. Actors without arena, as the ones of the fakes, allocate it
+func (act *{{$actorImpl}}) slot() (int, *{{$actor.Slot}}) {
+	arena := act.Arena()
+	if arena == nil {
+		return -1, new({{$actor.Slot}})
+	}
+	i := arena.Acquire()
+	return i, &arena.Slots().([]{{$actor.Slot}})[i]
+}
+{{- end}}
 {{range .Methods}}{{$met := .}}
 {{$params := $met.Params}}{{$retValues := $met.RetValues -}}
+{{if $actor.ExportMessages}}// {{$met.Request}} is the request of the method {{$met.Name}} of {{$actorName}}
+{{end -}}
 type {{$met.Request}} struct {
 	ref *{{$actorRef}}
-{{range $params}}	{{.Name}} {{.Type}} 
+{{- if $met.HasResponse}}
+	out chan interface{}
+{{- end}}
+{{range $params}}	{{.Field}} {{.FieldType}} 
+{{end -}}
+{{- if $met.Seq}}	seq actor.Seq
+{{end -}}
+{{- if $actor.Arena}}	slot int
 {{end -}} }
+{{- if $actor.Arena}}
+
+// Copy returns a copy of the request that doesn't use its slot of the arena
+func (req *{{$met.Request}}) Copy() interface{} {
+	c := *req
+	c.slot = -1
+	return c
+}
+
+// release clears the request and returns its slot to the arena
+func (req *{{$met.Request}}) release() {
+	arena, slot := req.ref.act.Arena(), req.slot
+	*req = {{$met.Request}}{}
+	arena.Release(slot)
+}
+{{- end}}
+
+func (req {{$met.Request}}) Method() string {
+	return "{{$met.Name}}"
+}
+
+func (req {{$met.Request}}) Async() bool {
+	return {{$met.Async}}
+}
 
+{{- if $met.Seq}}
+
+func (req {{$met.Request}}) sequence() actor.Seq {
+	return req.seq
+}
+{{- end}}
+
+func (req {{$met.Request}}) fail(dl actor.DeadLetter) {
+{{- if $met.HasResponse}}
+	req.out <- dl
+{{- end}}
+}
+
+{{- if $met.HasBlobs}}
+
+func (req {{$met.Request}}) deleteBlobs() {
+{{- range $met.Params}}{{if .Blob}}
+	actor.DeleteBlob(req.{{.Field}})
+{{- end}}{{end}}
+}
+{{- end}}
+
+{{if $actor.ExportMessages}}// {{$met.Response}} is the response of the method {{$met.Name}} of {{$actorName}}
+{{end -}}
 type {{$met.Response}} struct {
-{{range $i, $retVal := $met.RetVals}} r{{$i}} {{.Type}}
+{{range $i, $retVal := $met.RetVals}} {{$met.ResponseField $i}} {{.Type}}
 {{end -}} }
+{{- if $actor.Enabled "json"}}
+
+// MarshalJSON encodes the request without reflection
+func (req {{$met.Request}}) MarshalJSON() ([]byte, error) {
+	var e msgjson.Encoder
+{{- range $params}}
+	e.Key("{{.Name}}")
+	{{.JSONEncode (print "req." .Field)}}
+{{- end}}
+	return e.End()
+}
+
+// UnmarshalJSON decodes the request without reflection
+func (req *{{$met.Request}}) UnmarshalJSON(data []byte) error {
+	d := msgjson.NewDecoder(data)
+	for d.Next() {
+		switch d.Key() {
+{{- range $params}}
+		case "{{.Name}}":
+			{{.JSONDecode (print "req." .Field)}}
+{{- end}}
+		default:
+			d.Skip()
+		}
+	}
+	return d.Err()
+}
+
+// MarshalJSON encodes the response without reflection
+func (resp {{$met.Response}}) MarshalJSON() ([]byte, error) {
+	var e msgjson.Encoder
+{{- range $i, $ret := $met.RetVals}}
+	e.Key("{{.JSONKey $i}}")
+	{{.JSONEncode (print "resp." ($met.ResponseField $i))}}
+{{- end}}
+	return e.End()
+}
+
+// UnmarshalJSON decodes the response without reflection
+func (resp *{{$met.Response}}) UnmarshalJSON(data []byte) error {
+	d := msgjson.NewDecoder(data)
+	for d.Next() {
+		switch d.Key() {
+{{- range $i, $ret := $met.RetVals}}
+		case "{{.JSONKey $i}}":
+			{{.JSONDecode (print "resp." ($met.ResponseField $i))}}
+{{- end}}
+		default:
+			d.Skip()
+		}
+	}
+	return d.Err()
+}
+{{- end}}
 
 {{range $i, $comment := $met.Comments}}
 {{$comment}}
@@ -103,79 +480,437 @@ func (ref *{{$actorRef}}) {{$met.Name}}(
 {{- range $i, $param:=$met.Params}}{{if $i}}, {{end}}{{- .Name}} {{.Type}}{{end}})
 {{- if $retValues}} {{- if $met.Async}} func(){{end}} (
 {{- range $i, $ret:=$retValues}}{{- if $i}}, {{end}}{{if .Name}}{{- .Name}} {{end}}{{.Type}}{{end}})
+{{- else if $met.Seq}} actor.Seq
 {{- end}} {
+{{- if not $met.Async}}
+	if ref.act.InlineCalls() && ref.act.CanInline() {
+		defer ref.act.SetSender(ref.act.Sender())
+		ref.act.SetSender(ref.sender)
+		{{if $retValues}}return {{end}}ref.act.{{$met.LName}}({{- range $i, $param:=$met.Params}}{{if $i}}, {{end}}{{- .Name}}{{end}})
+{{- if not $retValues}}
+		return
+{{- end}}
+	}
+	ref.act.CheckSelfCall("{{$actorName}}.{{$met.Name}}")
+{{- end}}
 	select {
 	case <-ref.stopCh:
 		panic("Actor stopped")
 	default:
 	}
+	ref.act.Admit("{{$actorName}}.{{$met.Name}}", ref.sender, ref.act.Throttle())
+{{- if $actor.Arena}}
+	slot, reqs := ref.act.slot()
+{{- end}}
+{{- if $met.Seq}}
+	seq := ref.act.NextSeq()
+{{- end}}
+{{- if $met.HasResponse}}
+	out := make(chan interface{}, 1)
+{{- end}}
+{{- if $actor.Arena}}
+	reqs.{{$met.Name}} = {{$met.Request}}{ref{{if $met.HasResponse}}, out{{end}}{{if $met.Params}}, {{- range $i, $param:=$met.Params}}{{if $i}}, {{end}}{{if .Blob}}actor.PutBlob({{$param.Name}}){{else}}{{$param.Name}}{{end}}{{- end}}{{end}}{{if $met.Seq}}, seq{{end}}, slot}
+{{- end}}
 	select {
-	case ref.in <- {{$met.Request}}{ref{{if $met.Params}}, {{- range $i, $param:=$met.Params}}{{if $i}}, {{end}}{{- $param.Name}}{{- end}}{{end}}}:
+	case ref.in <- {{if $actor.Arena}}&reqs.{{$met.Name}}{{else}}{{$met.Request}}{ref{{if $met.HasResponse}}, out{{end}}{{if $met.Params}}, {{- range $i, $param:=$met.Params}}{{if $i}}, {{end}}{{if .Blob}}actor.PutBlob({{$param.Name}}){{else}}{{$param.Name}}{{end}}{{- end}}{{end}}{{if $met.Seq}}, seq{{end}}}{{end}}:
+		ref.act.Notify()
 {{- if $retValues}}
 {{- if $met.Async}}
 		return func() {{if $retValues -}}
 			({{- range $i, $ret:=$retValues}}{{if $i}}, {{end}}{{.Type}}{{end}}){{end}} {
 			select {
-			case result := <-ref.out:
+			case result := <-out:
 				if result, ok := result.({{$met.Response}}); ok {
-					return {{range $i, $ret := $met.RetVals}}{{if $i}}, {{end}}result.r{{$i}}{{end}}, true
+					return {{range $i, $ret := $met.RetVals}}{{if $i}}, {{end}}result.{{$met.ResponseField $i}}{{end}}, true
+				}
+				if err, ok := result.(error); ok {
+					panic(err)
 				}
 				panic("Wrong type of result message received")			
 			default:
 				result := {{$met.Response}}{}
-				return {{range $i, $ret := $met.RetVals}}{{if $i}}, {{end}}result.r{{$i}}{{end}}, false
+				return {{range $i, $ret := $met.RetVals}}{{if $i}}, {{end}}result.{{$met.ResponseField $i}}{{end}}, false
 			}
 		}
 	}
 {{- else}}
-		result := <-ref.out
+		result := <-out
 		if result, ok := result.({{$met.Response}}); ok {
-			return {{range $i, $ret := $met.RetValues}}{{if $i}}, {{end}}result.r{{$i}}{{end}}
+			return {{range $i, $ret := $met.RetValues}}{{if $i}}, {{end}}result.{{$met.ResponseField $i}}{{end}}
+		}
+		if err, ok := result.(error); ok {
+			panic(err)
 		}
 		panic("Wrong type of result message received")
 	default:
+{{- if $actor.Arena}}
+		reqs.{{$met.Name}}.release()
+{{- end}}
 		panic("Unknown error")
 	}
 {{- end}}
 {{else}}
 {{- if not $met.Async}}
-		<-ref.out
+		if err, ok := (<-out).(error); ok {
+			panic(err)
+		}
 {{end -}}
 	}
+{{- if $met.Seq}}
+	return seq
+{{- end}}
 {{end -}} }
+{{- if and $met.RetVals (not $met.Async)}}
+
+func (ref *{{$actorRef}}) {{$met.Name}}To(
+{{- range $params}}{{.Name}} {{.Type}}, {{end}}next func({{range $i, $ret := $met.RetVals}}{{if $i}}, {{end}}{{.Type}}{{end}})) {
+	next(ref.{{$met.Name}}({{- range $i, $param:=$met.Params}}{{if $i}}, {{end}}{{- .Name}}{{end}}))
+}
+{{- end}}
+{{- if not $met.Async}}{{$ctx := $met.ContextParam}}
+
+// {{$met.Name}}Context calls {{$met.Name}} and waits for its results until {{$ctx}} is done. It
+// returns an error instead of panicking when the call fails or the actor is
+// stopped{{if $met.ReturnsError}}, or the error returned by {{$met.Name}}{{end}}
+func (ref *{{$actorRef}}) {{$met.Name}}Context({{$ctx}} context.Context
+{{- range $params}}, {{.Name}} {{.Type}}{{end}}) ({{range $met.CallRetVals}}{{.Type}}, {{end}}error) {
+	if err := {{$ctx}}.Err(); err != nil {
+		return {{range $i, $ret := $met.CallRetVals}}{{$met.Response}}{}.{{$met.ResponseField $i}}, {{end}}err
+	}
+	if ref.act.InlineCalls() && ref.act.CanInline() {
+		{{range $i, $ret := $met.RetVals}}{{if $i}}, {{end}}v{{$i}}{{end}}{{if $met.RetVals}} := {{end}}ref.{{$met.Name}}({{- range $i, $param:=$met.Params}}{{if $i}}, {{end}}{{- .Name}}{{end}})
+		return {{range $i, $ret := $met.CallRetVals}}v{{$i}}, {{end}}{{if $met.ReturnsError}}v{{len $met.CallRetVals}}{{else}}nil{{end}}
+	}
+	ref.act.CheckSelfCall("{{$actorName}}.{{$met.Name}}")
+	select {
+	case <-ref.stopCh:
+		return {{range $i, $ret := $met.CallRetVals}}{{$met.Response}}{}.{{$met.ResponseField $i}}, {{end}}actor.StoppedCall("{{$actorName}}.{{$met.Name}}")
+	default:
+	}
+	ref.act.Admit("{{$actorName}}.{{$met.Name}}", ref.sender, ref.act.Throttle())
+{{- if $actor.Arena}}
+	slot, reqs := ref.act.slot()
+{{- end}}
+	out := make(chan interface{}, 1)
+{{- if $actor.Arena}}
+	reqs.{{$met.Name}} = {{$met.Request}}{ref, out{{range $params}}, {{if .Blob}}actor.PutBlob({{.Name}}){{else}}{{.Name}}{{end}}{{end}}, slot}
+{{- end}}
+	select {
+	case ref.in <- {{if $actor.Arena}}&reqs.{{$met.Name}}{{else}}{{$met.Request}}{ref, out{{range $params}}, {{if .Blob}}actor.PutBlob({{.Name}}){{else}}{{.Name}}{{end}}{{end}}}{{end}}:
+		ref.act.Notify()
+	case <-ref.stopCh:
+{{- if $actor.Arena}}
+		reqs.{{$met.Name}}.release()
+{{- end}}
+		return {{range $i, $ret := $met.CallRetVals}}{{$met.Response}}{}.{{$met.ResponseField $i}}, {{end}}actor.StoppedCall("{{$actorName}}.{{$met.Name}}")
+	case <-{{$ctx}}.Done():
+{{- if $actor.Arena}}
+		reqs.{{$met.Name}}.release()
+{{- end}}
+		return {{range $i, $ret := $met.CallRetVals}}{{$met.Response}}{}.{{$met.ResponseField $i}}, {{end}}{{$ctx}}.Err()
+	}
+{{- if $met.RetVals}}
+	result, err := actor.Await({{$ctx}}, out)
+	if err != nil {
+		return {{range $i, $ret := $met.CallRetVals}}{{$met.Response}}{}.{{$met.ResponseField $i}}, {{end}}err
+	}
+	resp := result.({{$met.Response}})
+	return {{range $i, $ret := $met.CallRetVals}}resp.{{$met.ResponseField $i}}, {{end}}{{if $met.ReturnsError}}resp.{{$met.ResponseField (len $met.CallRetVals)}}{{else}}nil{{end}}
+{{- else}}
+	_, err := actor.Await({{$ctx}}, out)
+	return err
+{{- end}}
+}
+{{- end}}
+{{- if not $met.Async}}{{$ctx := $met.ContextParam}}{{$timeout := $met.TimeoutParam}}
+
+// {{$met.Name}}Timeout calls {{$met.Name}} and waits up to {{$timeout}} for its results. It
+// returns an error wrapping actor.ErrCallTimeout if they don't arrive in
+// time, or the other errors of {{$met.Name}}Context
+func (ref *{{$actorRef}}) {{$met.Name}}Timeout({{$timeout}} {{$.Duration}}
+{{- range $params}}, {{.Name}} {{.Type}}{{end}}) ({{range $met.CallRetVals}}{{.Type}}, {{end}}error) {
+	{{$ctx}}, cancel := context.WithTimeout(context.Background(), {{$timeout}})
+	defer cancel()
+{{- if $met.CallRetVals}}
+	{{range $i, $ret := $met.CallRetVals}}v{{$i}}, {{end}}err := ref.{{$met.Name}}Context({{$ctx}}{{range $params}}, {{.Name}}{{end}})
+	return {{range $i, $ret := $met.CallRetVals}}v{{$i}}, {{end}}actor.CallTimeout("{{$actorName}}.{{$met.Name}}", {{$timeout}}, err)
+{{- else}}
+	return actor.CallTimeout("{{$actorName}}.{{$met.Name}}", {{$timeout}}, ref.{{$met.Name}}Context({{$ctx}}{{range $params}}, {{.Name}}{{end}}))
+{{- end}}
+}
+{{- end}}
+{{- with $met.Conversion}}
+
+// {{$met.Name}}From{{.Type}} calls {{$met.Name}} with the fields of v
+func (ref *{{$actorRef}}) {{$met.Name}}From{{.Type}}(v {{.Type}})
+{{- if $retValues}} {{if $met.Async}}func() {{end}}({{range $i, $ret := $retValues}}{{if $i}}, {{end}}{{.Type}}{{end}}){{else if $met.Seq}} actor.Seq{{end}} {
+	{{if or $retValues $met.Seq}}return {{end}}ref.{{$met.Name}}({{range $i, $f := .Fields}}{{if $i}}, {{end}}v.{{$f.Field}}{{end}})
+}
+
+// To{{.Type}} returns the {{.Type}} with the parameters of the request
+func (req {{$met.Request}}) To{{.Type}}() {{.Type}} {
+	return {{.Type}}{ {{- range $i, $f := .Fields}}{{if $i}}, {{end}}{{$f.Field}}: {{if $f.Param.Blob}}actor.GetBlob(req.{{$f.Param.Field}}){{else}}req.{{$f.Param.Field}}{{end}}{{end}}}
+}
+{{- end}}
+{{- if $met.Stage}}
+
+func (ref *{{$actorRef}}) {{$met.Name}}{{$met.Stage}}() stream.{{$met.Stage}} {
+	r := ref.From(ref.sender)
+{{- if eq $met.Stage "Source"}}
+	return func() (interface{}, bool) {
+		v, ok := r.{{$met.Name}}()
+		return v, ok
+	}
+{{- else if eq $met.Stage "Flow"}}
+	return func(v interface{}) interface{} {
+		return r.{{$met.Name}}(v.({{(index $met.Params 0).Type}}))
+	}
+{{- else}}
+	return func(v interface{}) {
+		r.{{$met.Name}}(v.({{(index $met.Params 0).Type}}))
+	}
+{{- end}}
+}
+{{- end}}
+{{- if eq $met.Endpoint "Producer"}}
+
+type {{$met.EndpointMessage}} struct {
+	link *stream.Link
+	n    int
+}
+
+func (req {{$met.EndpointMessage}}) Method() string {
+	return "{{$met.Name}}"
+}
+
+func (req {{$met.EndpointMessage}}) Async() bool {
+	return true
+}
+
+// {{$met.Name}}Producer returns the producer of a credit-based stream of the
+// elements returned by {{$met.Name}}, to be connected to a consumer with
+// stream.Connect
+func (ref *{{$actorRef}}) {{$met.Name}}Producer() *stream.Producer {
+	return stream.NewProducer(reflect.TypeOf((*{{$met.EndpointElem}})(nil)).Elem(), func(l *stream.Link, n int) bool {
+		select {
+		case <-ref.stopCh:
+			return false
+		default:
+		}
+		select {
+		case ref.in <- {{$met.EndpointMessage}}{l, n}:
+			ref.act.Notify()
+			return true
+		case <-ref.stopCh:
+			return false
+		}
+	})
+}
+{{- else if eq $met.Endpoint "Consumer"}}
+
+type {{$met.EndpointMessage}} struct {
+	link *stream.Link
+	v    {{$met.EndpointElem}}
+	end  bool
+}
+
+func (req {{$met.EndpointMessage}}) Method() string {
+	return "{{$met.Name}}"
+}
+
+func (req {{$met.EndpointMessage}}) Async() bool {
+	return true
+}
+
+func (req {{$met.EndpointMessage}}) consumed() {
+	req.link.Consumed(req.end)
+}
+
+// {{$met.Name}}Consumer returns the consumer of a credit-based stream whose
+// elements are passed to {{$met.Name}}, to be connected to a producer with
+// stream.Connect
+func (ref *{{$actorRef}}) {{$met.Name}}Consumer() *stream.Consumer {
+	return stream.NewConsumer(reflect.TypeOf((*{{$met.EndpointElem}})(nil)).Elem(), cap(ref.in), func(l *stream.Link, v interface{}, end bool) bool {
+		msg := {{$met.EndpointMessage}}{link: l, end: end}
+		if !end {
+			msg.v = v.({{$met.EndpointElem}})
+		}
+		select {
+		case <-ref.stopCh:
+			return false
+		default:
+		}
+		select {
+		case ref.in <- msg:
+			ref.act.Notify()
+			return true
+		case <-ref.stopCh:
+			return false
+		}
+	})
+}
+{{- end}}
+{{- if $met.MultiResult}}
+
+type {{$met.Result}} struct {
+{{range $met.ResultFields}}	{{.Name}} {{.Type}}
+{{end -}} }
+
+func (ref *{{$actorRef}}) {{$met.Name}}Result(
+{{- range $i, $param:=$met.Params}}{{if $i}}, {{end}}{{- .Name}} {{.Type}}{{end}}) {{if $met.Async}}func() ({{$met.Result}}, bool){{else}}{{$met.Result}}{{end}} {
+{{- if $met.Async}}
+	poll := ref.{{$met.Name}}({{- range $i, $param:=$met.Params}}{{if $i}}, {{end}}{{- .Name}}{{end}})
+	return func() ({{$met.Result}}, bool) {
+		{{range $i, $ret := $met.RetVals}}r{{$i}}, {{end}}done := poll()
+		return {{$met.Result}}{ {{- range $i, $ret := $met.RetVals}}{{if $i}}, {{end}}r{{$i}}{{end}}}, done
+	}
+{{- else}}
+	{{range $i, $ret := $met.RetVals}}{{if $i}}, {{end}}r{{$i}}{{end}} := ref.{{$met.Name}}({{- range $i, $param:=$met.Params}}{{if $i}}, {{end}}{{- .Name}}{{end}})
+	return {{$met.Result}}{ {{- range $i, $ret := $met.RetVals}}{{if $i}}, {{end}}r{{$i}}{{end}}}
+{{- end}}
+}
+{{- end}}
 {{end}}
 func (act *{{$actorImpl}}) receive() {
-	var stopped = false
-	var msg interface{}
+	act.Bind()
+	defer actor.Track("{{$actorName}}", act)()
+	queue := act.mailbox()
+	stopped := false
 	for {
+		var msg interface{}
 		if !stopped {
-			msg = <-act.In
+{{- if $actor.Batched}}
+			var ok bool
+			if msg, ok = queue.TryNext(); !ok {
+				act.FlushReplies()
+				msg = queue.Next()
+			}
+{{- else}}
+			msg = queue.Next()
+{{- end}}
 		} else {
-			select {
-			case msg = <-act.In:
-			default:
+			var ok bool
+			if msg, ok = queue.TryNext(); !ok {
+{{- if $actor.Batched}}
+				act.FlushReplies()
+{{- end}}
 				actor.Log.Println("No more messages. Exiting")
+				actor.RunPostStop("{{$actorName}}", act)
 				return
 			}
 		}
-		switch msg := msg.(type) {
+		if act.dispatch(msg) {
+			stopped = true
+		}
+	}
+}
+
+func (act *{{$actorImpl}}) mailbox() actor.Mailbox {
+{{- if $actor.Weights}}
+	return actor.NewFairQueue(act.In, map[string]int{ {{- range $method, $weight := $actor.Weights}}"{{$method}}": {{$weight}}, {{end -}} })
+{{- else}}
+	return actor.NewMailbox(act.In)
+{{- end}}
+}
+
+// dispatch processes a message. It returns true if it's the stop request
+func (act *{{$actorImpl}}) dispatch(msg interface{}) bool {
+	if stop, ok := msg.({{$stopRequest}}); ok {
+		act.SetClosing(stop.ctx, stop.done)
+		close(act.StopCh)
+		actor.Log.Println("Actor stopped")
+		return true
+	}
+	if actor.HandleSystem(act, msg) {
+{{- if $actor.View}}
+		act.Publish(act.view())
+{{- end}}
+		return false
+	}
+	var dl *actor.DeadLetter
+	if act.Killed() {
+		dl = actor.Discard("{{$actorName}}", msg, actor.ErrKilled)
+	} else {
+		dl = actor.{{if $actor.Enabled "metrics"}}Process{{else}}ProcessUnmetered{{end}}("{{$actorName}}", act.MaxAttempts(), msg, {{if $actor.Arena}}act.Handler(){{else}}act.handle{{end}})
+	}
+{{- if $actor.HasTimeouts}}
+	// the caller of a method that timed out already received the error
+	timedOut := act.TimedOut()
+{{- end}}
+	if dl != nil {
+		if req, ok := msg.(interface{ fail(actor.DeadLetter) }); ok{{if $actor.HasTimeouts}} && !timedOut{{end}} {
+			req.fail(*dl)
+		}
+		if dl.Attempts > 0 {
+			act.OnPanic(*dl)
+		}
+	}
+{{- if $actor.HasSeq}}
+	if req, ok := msg.(interface{ sequence() actor.Seq }); ok {
+		act.SeqDone(req.sequence())
+	}
+{{- end}}
+{{- if $actor.HasConsumers}}
+	if req, ok := msg.(interface{ consumed() }); ok {
+		req.consumed()
+	}
+{{- end}}
+{{- if $actor.HasBlobs}}
+	if req, ok := msg.(interface{ deleteBlobs() }); ok {
+		req.deleteBlobs()
+	}
+{{- end}}
+{{- if $actor.Arena}}
+	if req, ok := msg.(interface{ release() }); ok {
+		req.release()
+	}
+{{- end}}
+{{- if $actor.View}}
+	act.Publish(act.view())
+{{- end}}
+	return false
+}
+
+func (act *{{$actorImpl}}) handle(msg interface{}) {
+	switch msg := msg.(type) {
 {{- range $methods}}{{$met:=.}}{{$retVals:=$met.RetVals}}
-		case {{$met.Request}}:
-			{{range $i, $ret := $retVals}}{{if $i}}, {{end}}v{{$i}}{{end}}{{if $retVals}} := {{end -}}
-			act.{{$met.LName}}(
-{{- range $i, $param:=$met.Params}}{{if $i}}, {{end}}msg.{{- $param.Name}}{{end}})
-{{- if $met.HasResponse}}
-			msg.ref.out <- {{$met.Response}}{ {{- range $i, $ret:=$retVals}}{{if $i}}, {{end}}v{{$i}}{{end}}}
+	case {{if $actor.Arena}}*{{end}}{{$met.Request}}:
+		act.SetSender(msg.ref.sender)
+{{- if $met.Timeout}}
+		watchdog := act.StartWatchdog("{{$actorName}}.{{$met.Name}}", {{$met.Timeout.Nanoseconds}}, {{if $met.HasResponse}}msg.out{{else}}nil{{end}}) // {{$met.Timeout}}
+		defer watchdog.Stop()
 {{- end}}
+		{{range $i, $ret := $retVals}}{{if $i}}, {{end}}v{{$i}}{{end}}{{if $retVals}} := {{end -}}
+		act.{{$met.LName}}(
+{{- range $i, $param:=$met.Params}}{{if $i}}, {{end}}{{if .Blob}}actor.GetBlob(msg.{{$param.Field}}){{else}}msg.{{$param.Field}}{{end}}{{end}})
+{{- if and $met.Timeout $met.HasResponse}}
+		if !watchdog.Stop() {
+			return
+		}
 {{- end}}
-		case {{$stopRequest}}:
-			close(act.StopCh)
-			stopped = true
-			actor.Log.Println("Actor stopped")
-		default:
-			msg = msg
-			panic("Wrong type of request message received")			
+{{- if and $met.Async $met.HasResponse}}
+		actor.Deliver("{{$actorName}}", "{{$met.Name}}", msg.out, {{$met.Response}}{ {{- range $i, $ret:=$retVals}}{{if $i}}, {{end}}v{{$i}}{{end}}}, act.ResponseTimeout())
+{{- else if and $met.HasResponse $actor.Batched}}
+		act.Reply(msg.out, {{$met.Response}}{ {{- range $i, $ret:=$retVals}}{{if $i}}, {{end}}v{{$i}}{{end}}})
+{{- else if $met.HasResponse}}
+		msg.out <- {{$met.Response}}{ {{- range $i, $ret:=$retVals}}{{if $i}}, {{end}}v{{$i}}{{end}}}
+{{- end}}
+{{- if eq $met.Endpoint "Producer"}}
+	case {{$met.EndpointMessage}}:
+		act.SetSender(nil)
+		msg.link.Produce(msg.n, func() (interface{}, bool) {
+			return act.{{$met.LName}}()
+		})
+{{- else if eq $met.Endpoint "Consumer"}}
+	case {{$met.EndpointMessage}}:
+		act.SetSender(nil)
+		if !msg.end {
+			act.{{$met.LName}}(msg.v)
 		}
+{{- end}}
+{{- end}}
+	default:
+		actor.HandleUnknown("{{$actorName}}", act.Unhandled(), msg, act.OnUnhandled)
 	}
 }
 {{end}}
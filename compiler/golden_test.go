@@ -0,0 +1,87 @@
+package compiler
+
+import (
+	"bytes"
+	"flag"
+	"go/ast"
+	"go/format"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update the golden files")
+
+// sourceImporter type checks the imported packages from their source, so
+// the tests check the generated code against the actor package of the tree
+// without installing it
+var sourceImporter = importer.ForCompiler(token.NewFileSet(), "source", nil)
+
+// TestGolden generates the code of the input files of testdata and compares
+// it with their golden files, named after the input file with the
+// _actor.golden suffix. With -update the golden files are written instead
+func TestGolden(t *testing.T) {
+	if _, err := sourceImporter.Import("github.com/carevaloc/goactors/actor"); err != nil {
+		t.Fatalf("importing the actor package: %v", err)
+	}
+	inputs, err := filepath.Glob(filepath.Join("testdata", "*", "*.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, input := range inputs {
+		t.Run(filepath.Base(filepath.Dir(input)), func(t *testing.T) {
+			src, err := readSrc(input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			pkg, err := parsePackage(input, src, false, sourceImporter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var bldr strings.Builder
+			Generate(&bldr, pkg)
+			gen, err := format.Source([]byte(bldr.String()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkGenerated(t, input, gen)
+
+			golden := strings.TrimSuffix(input, ".go") + "_actor.golden"
+			if *update {
+				if err := ioutil.WriteFile(golden, gen, 0644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			want, err := ioutil.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(gen, want) {
+				t.Errorf("generated code of %s differs from %s, run the test with -update to see the changes", input, golden)
+			}
+		})
+	}
+}
+
+// checkGenerated type checks the generated code with its input file
+func checkGenerated(t *testing.T, input string, src []byte) {
+	fset := token.NewFileSet()
+	in, err := parser.ParseFile(fset, input, nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gen, err := parser.ParseFile(fset, "generated.go", src, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf := types.Config{Importer: sourceImporter}
+	if _, err := conf.Check(in.Name.Name, fset, []*ast.File{in, gen}, nil); err != nil {
+		t.Fatalf("generated code doesn't type check: %v", err)
+	}
+}
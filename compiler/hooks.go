@@ -0,0 +1,90 @@
+package compiler
+
+import (
+	"io"
+	"log"
+	"strings"
+	"text/template"
+)
+
+// hook is a hook of the actors written to the skeleton of the hooks file
+type hook struct {
+	Name string
+	// Signature is the signature of the method, after its name
+	Signature string
+	// Doc is the doc comment of the method, without the name
+	Doc string
+	// Body is the body of the method, with the default behavior
+	Body string
+}
+
+// skeletonHooks are the hooks written to the skeleton, in order
+var skeletonHooks = []hook{
+	{Name: "PreStart", Signature: "() error", Doc: "is executed before the main loop of the actor is launched. If it returns an error the actor isn't started", Body: "return nil"},
+	{Name: "OnPanic", Signature: "(dl actor.DeadLetter)", Doc: "is called with the messages quarantined after causing panics, once the actor has the MaxAttempts hook"},
+	{Name: "Unhandled", Signature: "() actor.Unhandled", Doc: "returns what the actor does with the messages of unknown types. OnUnhandled is only called if it returns actor.UnhandledHook", Body: "return actor.UnhandledPanic"},
+	{Name: "OnUnhandled", Signature: "(msg interface{})", Doc: "is called with the messages of unknown types"},
+}
+
+// hooksOf returns the hooks of the skeleton that the actor doesn't declare
+// in the input file
+func hooksOf(a *Actor) []hook {
+	var hooks []hook
+	for _, h := range skeletonHooks {
+		if !a.hooks[h.Name] {
+			hooks = append(hooks, h)
+		}
+	}
+	return hooks
+}
+
+// GenerateHooks writes the skeleton of the hooks file of the package: the
+// hooks of the actors with their default behavior, to be edited by the user.
+// It's only written if the file doesn't exist, so the hooks are kept when
+// the code is generated again
+func GenerateHooks(output io.Writer, pkg Package) {
+	t, err := template.New("Hooks template").Funcs(template.FuncMap{"hooks": hooksOf}).Parse(hooksTmpl)
+	if err != nil {
+		log.Fatal("Parse: ", err)
+	}
+
+	// the actor package is imported if a hook uses it
+	var imported bool
+	for _, a := range pkg.Actors {
+		for _, h := range hooksOf(a) {
+			imported = imported || strings.Contains(h.Signature+h.Body, "actor.")
+		}
+	}
+	err = t.Execute(output, struct {
+		Package
+		Imported bool
+	}{pkg, imported})
+}
+
+// hooksTmpl is the template used to generate the skeleton of the hooks file
+const hooksTmpl = `
+{{- range .Header}}{{.}}
+{{end}}{{if .Header}}
+{{end}}
+{{- if .BuildTags}}//go:build {{.BuildTags}}
+
+{{end -}}
+// Hooks of the actors, generated by actorc as a skeleton to be edited. actorc
+// doesn't write this file again once it exists.
+
+package {{.Name}}
+
+{{- if .Imported}}
+
+import "github.com/carevaloc/goactors/actor"
+{{- end}}
+{{range .Actors}}{{$actor := .}}
+{{- range hooks $actor}}
+// {{.Name}} {{.Doc}}
+func (act *{{$actor.Impl}}) {{.Name}}{{.Signature}} {
+{{- if .Body}}
+	{{.Body}}
+{{- end}}
+}
+{{end}}
+{{- end}}`
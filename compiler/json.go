@@ -0,0 +1,168 @@
+package compiler
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+)
+
+// msgjsonImport is the package of the encoder and decoder used by the JSON
+// methods of the messages
+const msgjsonImport = "github.com/carevaloc/goactors/actor/msgjson"
+
+// jsonCodec is how a value is encoded in the JSON methods of the messages:
+// the method of the encoder and decoder, and the bit size of the numbers.
+// Values without a codec are encoded with encoding/json
+type jsonCodec struct {
+	method string
+	basic  string
+	bits   int
+}
+
+// jsonCodecOf returns the codec of the type of expr
+func (w *typeWriter) jsonCodecOf(expr ast.Expr) jsonCodec {
+	if w.info == nil {
+		return jsonCodec{}
+	}
+	tv, ok := w.info.Types[expr]
+	if !ok {
+		return jsonCodec{}
+	}
+	return jsonCodecOfType(tv.Type)
+}
+
+// jsonCodecOfType returns the codec of actor/msgjson for the type t. The
+// types with their own JSON or text encoding, like json.RawMessage, have no
+// codec, so they are still encoded by their methods
+func jsonCodecOfType(t types.Type) jsonCodec {
+	if hasJSONMethods(t) {
+		return jsonCodec{}
+	}
+	switch t := t.Underlying().(type) {
+	case *types.Basic:
+		switch t.Kind() {
+		case types.String:
+			return jsonCodec{"String", "string", 0}
+		case types.Bool:
+			return jsonCodec{"Bool", "bool", 0}
+		case types.Int, types.Int8, types.Int16, types.Int32, types.Int64:
+			return jsonCodec{"Int", "int64", basicBits(t.Kind())}
+		case types.Uint, types.Uint8, types.Uint16, types.Uint32, types.Uint64, types.Uintptr:
+			return jsonCodec{"Uint", "uint64", basicBits(t.Kind())}
+		case types.Float32:
+			return jsonCodec{"Float", "float64", 32}
+		case types.Float64:
+			return jsonCodec{"Float", "float64", 64}
+		}
+	case *types.Slice:
+		if b, ok := t.Elem().(*types.Basic); ok && b.Kind() == types.Byte {
+			return jsonCodec{"Bytes", "[]byte", 0}
+		}
+	}
+	return jsonCodec{}
+}
+
+// jsonMarshalers are the interfaces whose methods encoding/json uses to
+// encode and decode a value instead of its kind: json.Marshaler,
+// json.Unmarshaler, encoding.TextMarshaler and encoding.TextUnmarshaler
+var jsonMarshalers = []*types.Interface{
+	marshalerOf("MarshalJSON"),
+	unmarshalerOf("UnmarshalJSON"),
+	marshalerOf("MarshalText"),
+	unmarshalerOf("UnmarshalText"),
+}
+
+// marshalerOf returns the interface with the method name() ([]byte, error)
+func marshalerOf(name string) *types.Interface {
+	bytes := types.NewVar(token.NoPos, nil, "", types.NewSlice(types.Typ[types.Byte]))
+	err := types.NewVar(token.NoPos, nil, "", types.Universe.Lookup("error").Type())
+	sig := types.NewSignature(nil, nil, types.NewTuple(bytes, err), false)
+	return types.NewInterfaceType([]*types.Func{types.NewFunc(token.NoPos, nil, name, sig)}, nil).Complete()
+}
+
+// unmarshalerOf returns the interface with the method name([]byte) error
+func unmarshalerOf(name string) *types.Interface {
+	bytes := types.NewVar(token.NoPos, nil, "", types.NewSlice(types.Typ[types.Byte]))
+	err := types.NewVar(token.NoPos, nil, "", types.Universe.Lookup("error").Type())
+	sig := types.NewSignature(nil, types.NewTuple(bytes), types.NewTuple(err), false)
+	return types.NewInterfaceType([]*types.Func{types.NewFunc(token.NoPos, nil, name, sig)}, nil).Complete()
+}
+
+// hasJSONMethods returns true if t, or a pointer to t, implements any of
+// the jsonMarshalers
+func hasJSONMethods(t types.Type) bool {
+	for _, iface := range jsonMarshalers {
+		if types.Implements(t, iface) || types.Implements(types.NewPointer(t), iface) {
+			return true
+		}
+	}
+	return false
+}
+
+// basicBits returns the bit size of an integer kind, 0 for the int and uint
+// sizes of the platform
+func basicBits(kind types.BasicKind) int {
+	switch kind {
+	case types.Int8, types.Uint8:
+		return 8
+	case types.Int16, types.Uint16:
+		return 16
+	case types.Int32, types.Uint32:
+		return 32
+	case types.Int64, types.Uint64, types.Uintptr:
+		return 64
+	}
+	return 0
+}
+
+// JSONEncode returns the statement of the MarshalJSON method of a message
+// that encodes the parameter, or result, held in the field v
+func (p Param) JSONEncode(v string) string {
+	codec := p.codec
+	if p.Blob {
+		codec = jsonCodec{"String", "string", 0}
+	}
+	if codec.method == "" {
+		return fmt.Sprintf("e.Value(%s)", v)
+	}
+	if p.FieldType() != codec.basic {
+		v = fmt.Sprintf("%s(%s)", codec.basic, v)
+	}
+	switch codec.method {
+	case "Float":
+		return fmt.Sprintf("e.Float(%s, %d)", v, codec.bits)
+	}
+	return fmt.Sprintf("e.%s(%s)", codec.method, v)
+}
+
+// JSONDecode returns the statement of the UnmarshalJSON method of a message
+// that decodes the parameter, or result, into the field v
+func (p Param) JSONDecode(v string) string {
+	codec := p.codec
+	if p.Blob {
+		codec = jsonCodec{"String", "string", 0}
+	}
+	var call string
+	switch codec.method {
+	case "":
+		return fmt.Sprintf("d.Value(&%s)", v)
+	case "Int", "Uint", "Float":
+		call = fmt.Sprintf("d.%s(%d)", codec.method, codec.bits)
+	default:
+		call = fmt.Sprintf("d.%s()", codec.method)
+	}
+	if p.FieldType() != codec.basic {
+		call = fmt.Sprintf("%s(%s)", p.FieldType(), call)
+	}
+	return fmt.Sprintf("%s = %s", v, call)
+}
+
+// JSONKey returns the key of a result in the JSON encoding of a response:
+// its name, or its position if it's not named
+func (p Param) JSONKey(i int) string {
+	if p.Name != "" {
+		return p.Name
+	}
+	return fmt.Sprintf("r%d", i)
+}
@@ -0,0 +1,157 @@
+package compiler
+
+import (
+	"io"
+	"log"
+	"text/template"
+)
+
+// Checkable returns true if the linearizability tests can call the method:
+// testkit.RandomValue supports the types of all its parameters
+func (m *Method) Checkable() bool {
+	for _, p := range m.Params {
+		if _, ok := fuzzTypes[p.Type]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// CheckableMethods returns the methods of the actor called by its
+// linearizability tests
+func (a *Actor) CheckableMethods() []Method {
+	var methods []Method
+	for _, m := range a.Methods {
+		if m.Checkable() {
+			methods = append(methods, m)
+		}
+	}
+	return methods
+}
+
+// LinCheck returns the name of the function that runs the linearizability
+// tests of the actor
+func (a *Actor) LinCheck() string {
+	return "Check" + a.Name + "Linearizable"
+}
+
+// linCheckActors returns the actors with linearizability tests
+func linCheckActors(pkg Package) []*Actor {
+	var actors []*Actor
+	for _, a := range pkg.Actors {
+		if a.Enabled(FeatureLinCheck) && len(a.CheckableMethods()) > 0 {
+			actors = append(actors, a)
+		}
+	}
+	return actors
+}
+
+// GenerateLinCheck generates the linearizability tests of the actors in the
+// package: a function for each actor that calls the methods whose parameters
+// testkit.RandomValue supports from several references at the same time,
+// and checks the results against a sequential model supplied by the user.
+// The output is meant to be a _test.go file
+func GenerateLinCheck(output io.Writer, pkg Package) {
+	linPkg := pkg
+	linPkg.Actors = linCheckActors(pkg)
+
+	funcMap := template.FuncMap{
+		// polls returns true if an asynchronous method with results is
+		// called, whose results are polled
+		"polls": func() bool {
+			for _, a := range linPkg.Actors {
+				for _, m := range a.CheckableMethods() {
+					if m.Async && len(m.RetVals()) > 0 {
+						return true
+					}
+				}
+			}
+			return false
+		},
+	}
+
+	t := template.New("Linearizability template").Funcs(funcMap)
+
+	t, err := t.Parse(linCheckTmpl)
+	if err != nil {
+		log.Fatal("Parse: ", err)
+	}
+
+	err = t.Execute(output, linPkg)
+}
+
+// linCheckTmpl is the template used to generate the linearizability tests
+const linCheckTmpl = `
+{{- range .Header}}{{.}}
+{{end}}{{if .Header}}
+{{end}}
+{{- if .BuildTags}}//go:build {{.BuildTags}}
+
+{{end -}}
+// Code generated by actorc. DO NOT EDIT.
+
+package {{.Name}}
+{{- if .Actors}}
+
+import (
+	"context"
+	"math/rand"
+	"testing"
+{{- if polls}}
+	"runtime"
+{{- end}}
+
+	"github.com/carevaloc/goactors/actor"
+	"github.com/carevaloc/goactors/actor/testkit"
+)
+{{- end}}
+{{range .Actors}}{{$actorName := .ExpName}}{{$methods := .CheckableMethods}}
+// {{.LinCheck}} calls random methods of actors created with
+// newActor from several references at the same time, and fails the test if
+// the results can't be explained by calling them one at a time on the model.
+// The methods called are the ones whose parameters are strings, []byte,
+// booleans or numbers
+func {{.LinCheck}}(t *testing.T, newActor func() {{$actorName}}, model testkit.Model, opts testkit.LinOptions) {
+	t.Helper()
+	testkit.CheckLinearizable(t, model, opts, func() (func() testkit.LinClient, func()) {
+		act := newActor().Start()
+		newClient := func() testkit.LinClient {
+			ref := act.Ref()
+			return func(rnd *rand.Rand) testkit.Operation {
+				switch rnd.Intn({{len $methods}}) {
+{{- range $i, $m := $methods}}
+				case {{$i}}:
+{{- range $j, $p := .Params}}
+					p{{$j}} := testkit.RandomValue(rnd, "{{$p.Type}}").({{$p.Type}})
+{{- end}}
+{{- if .RetVals}}
+{{- if .Async}}
+					poll := ref.{{.Name}}({{range $j, $p := .Params}}{{if $j}}, {{end}}p{{$j}}{{end}})
+					for {
+						{{range $j, $r := .RetVals}}r{{$j}}, {{end}}done := poll()
+						if done {
+							return testkit.Operation{Method: "{{.Name}}"{{if .Params}}, Params: []interface{}{ {{- range $j, $p := .Params}}{{if $j}}, {{end}}p{{$j}}{{end}}}{{end}}, Results: []interface{}{ {{- range $j, $r := .RetVals}}{{if $j}}, {{end}}r{{$j}}{{end}}}}
+						}
+						runtime.Gosched()
+					}
+{{- else}}
+					{{range $j, $r := .RetVals}}{{if $j}}, {{end}}r{{$j}}{{end}} := ref.{{.Name}}({{range $j, $p := .Params}}{{if $j}}, {{end}}p{{$j}}{{end}})
+					return testkit.Operation{Method: "{{.Name}}"{{if .Params}}, Params: []interface{}{ {{- range $j, $p := .Params}}{{if $j}}, {{end}}p{{$j}}{{end}}}{{end}}, Results: []interface{}{ {{- range $j, $r := .RetVals}}{{if $j}}, {{end}}r{{$j}}{{end}}}}
+{{- end}}
+{{- else}}
+					ref.{{.Name}}({{range $j, $p := .Params}}{{if $j}}, {{end}}p{{$j}}{{end}})
+					return testkit.Operation{Method: "{{.Name}}"{{if .Params}}, Params: []interface{}{ {{- range $j, $p := .Params}}{{if $j}}, {{end}}p{{$j}}{{end}}}{{end}}{{if .Async}}, Pending: true{{end}}}
+{{- end}}
+{{- end}}
+				}
+				panic("unreachable")
+			}
+		}
+		return newClient, func() {
+			if err := act.Close(context.Background()); err != nil && err != actor.ErrStopped {
+				t.Error(err)
+			}
+		}
+	})
+}
+{{end}}`
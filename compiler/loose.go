@@ -0,0 +1,102 @@
+package compiler
+
+import (
+	"fmt"
+	"go/ast"
+	"path"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// parseStructsLoose finds the actors in the syntax tree of the input file,
+// without type information: structs with an embedded field named Actor
+func parseStructsLoose(f *ast.File, actors map[string]*Actor) error {
+	for _, decl := range f.Decls {
+		gd, ok := decl.(*ast.GenDecl)
+		if !ok {
+			continue
+		}
+		for _, spec := range gd.Specs {
+			ts, ok := spec.(*ast.TypeSpec)
+			if !ok {
+				continue
+			}
+			st, ok := ts.Type.(*ast.StructType)
+			if !ok {
+				continue
+			}
+
+			var promote = make(map[string]bool)
+			for _, fld := range st.Fields.List {
+				if len(fld.Names) > 0 {
+					continue
+				}
+				var tag reflect.StructTag
+				if fld.Tag != nil {
+					str, _ := strconv.Unquote(fld.Tag.Value)
+					tag = reflect.StructTag(str)
+				}
+				name := embeddedName(fld.Type)
+				if tag.Get("promote") == "true" {
+					promote[name] = true
+				}
+				if name == "Actor" {
+					if err := addActor(ts.Name.Name, fld.Pos(), tag, promote, actors); err != nil {
+						return err
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// embeddedName returns the name of an embedded field: the name of its type
+func embeddedName(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.StarExpr:
+		return embeddedName(t.X)
+	case *ast.SelectorExpr:
+		return t.Sel.Name
+	case *ast.Ident:
+		return t.Name
+	}
+	return ""
+}
+
+// majorVersion matches the major version suffix of a module path
+var majorVersion = regexp.MustCompile(`^v[0-9]+$`)
+
+// guessImportName returns the usual name of the package imported with the
+// given path: its last element, without major version or go prefix and suffix
+func guessImportName(importPath string) string {
+	name := path.Base(importPath)
+	if majorVersion.MatchString(name) && path.Dir(importPath) != "." {
+		name = path.Base(path.Dir(importPath))
+	}
+	name = strings.TrimPrefix(name, "go-")
+	name = strings.TrimSuffix(strings.TrimSuffix(name, "-go"), ".go")
+	return strings.Replace(name, "-", "_", -1)
+}
+
+// guessedImports returns a warning for each import used by the generated code
+// whose package name was guessed
+func guessedImports(fileImports map[string]fileImport, imports map[string]string) []located {
+	var warnings []located
+	for name, imp := range fileImports {
+		// the names of the standard library and goactors packages match their paths
+		if imp.path == "github.com/carevaloc/goactors/actor" {
+			continue
+		}
+		if _, used := imports[imp.path]; used && imp.alias == "" && strings.Contains(imp.path, ".") {
+			warnings = append(warnings, located{pos: imp.pos, code: CodeImportName, msg: fmt.Sprintf("package name of import %q not verified, assumed to be %s", imp.path, name)})
+		}
+	}
+	sort.Slice(warnings, func(i, j int) bool {
+		return warnings[i].msg < warnings[j].msg
+	})
+	return warnings
+}
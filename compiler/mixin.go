@@ -0,0 +1,120 @@
+package compiler
+
+import (
+	"fmt"
+	"go/types"
+	"log"
+	"strings"
+)
+
+// parseMixin adds the methods of a mixin to the actor: the methods declared
+// by the type of an embedded field with the promote tag, declared in
+// another file of the package or in another package, whose unexported
+// methods can't be called. They are read from the type information, as
+// their source isn't parsed
+func parseMixin(mixin *types.Var, tw *typeWriter, actor *Actor, init string) error {
+	ms := types.NewMethodSet(types.NewPointer(deref(mixin.Type())))
+	for i := 0; i < ms.Len(); i++ {
+		sel := ms.At(i)
+		fn := sel.Obj().(*types.Func)
+		// the methods of the types embedded in the mixin are left out
+		if len(sel.Index()) > 1 || !fn.Exported() && fn.Pkg() != tw.pkg {
+			continue
+		}
+		log.Printf("Method %s of mixin %s promoted to actor %s\n", fn.Name(), mixin.Name(), actor.Impl)
+		if err := parseMixinMethod(fn, mixin, tw, actor, init); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseMixinMethod extracts the signature of a method of a mixin and adds it
+// to the actor
+func parseMixinMethod(fn *types.Func, mixin *types.Var, tw *typeWriter, actor *Actor, init string) error {
+	pkgImports := tw.imports
+	tw.imports = make(map[string]string)
+	defer func() {
+		tw.imports = pkgImports
+	}()
+
+	name := fn.Name()
+	sig := fn.Type().(*types.Signature)
+	if actor.exclude[name] || excludedMethods[name] {
+		return nil
+	}
+	if sig.Variadic() {
+		return errorAt(mixin.Pos(), CodeSignature, "actor %s: method %s of mixin %s is variadic, it must be excluded", actor.Name, name, mixin.Name())
+	}
+	method := Method{Name: name, Params: []Param{}, RetValues: []Param{}, Async: actor.Async(name), actor: actor.Impl, promoted: true, exported: actor.ExportMessages, call: name}
+	for i := 0; i < sig.Params().Len(); i++ {
+		v := sig.Params().At(i)
+		ptype, err := tw.typeString(v.Type())
+		if err != nil {
+			return errorAt(mixin.Pos(), CodeSignature, "actor %s: method %s of mixin %s: %v", actor.Name, name, mixin.Name(), err)
+		}
+		pname := v.Name()
+		if pname == "" || pname == "_" {
+			pname = fmt.Sprintf("p%d", i)
+		}
+		par := Param{Name: pname, Type: ptype, Blob: actor.blob[name+"."+pname], codec: jsonCodecOfType(v.Type())}
+		if actor.ExportMessages {
+			par.field = toUpper(pname)
+		}
+		if par.Blob && par.Type != "[]byte" {
+			return errorAt(mixin.Pos(), CodeSignature, "actor %s: blob parameter %s of method %s is not a []byte", actor.Name, pname, name)
+		}
+		method.Params = append(method.Params, par)
+	}
+
+	var named bool
+	for i := 0; i < sig.Results().Len(); i++ {
+		v := sig.Results().At(i)
+		rtype, err := tw.typeString(v.Type())
+		if err != nil {
+			return errorAt(mixin.Pos(), CodeSignature, "actor %s: method %s of mixin %s: %v", actor.Name, name, mixin.Name(), err)
+		}
+		named = named || v.Name() != ""
+		method.RetValues = append(method.RetValues, Param{Name: v.Name(), Type: rtype, codec: jsonCodecOfType(v.Type())})
+	}
+	if method.Async && len(method.RetValues) > 0 {
+		done := Param{Type: "bool"}
+		if named {
+			done.Name = "done"
+		}
+		method.RetValues = append(method.RetValues, done)
+	}
+
+	actor.mixed[name] = true
+	return addMethod(method, mixin.Pos(), false, tw, actor, init, pkgImports, func(typeName string) (*Conversion, error) {
+		return nil, fmt.Errorf("method %s of mixin %s can't be converted from %s: the conversions need the source of the method", name, mixin.Name(), typeName)
+	})
+}
+
+// typeString returns the type t as it should appear in the generated code,
+// qualified with the names of the imports of the input file, and records
+// the imports it needs. The packages not imported by the input file are
+// imported by their name, unless another import uses it
+func (w *typeWriter) typeString(t types.Type) (string, error) {
+	var err error
+	str := types.TypeString(t, func(p *types.Package) string {
+		if p == w.pkg {
+			return ""
+		}
+		for name, imp := range w.fileImports {
+			if imp.path == p.Path() {
+				w.imports[imp.path] = imp.alias
+				return name
+			}
+		}
+		if imp, ok := w.fileImports[p.Name()]; ok && err == nil {
+			err = fmt.Errorf("package %s of type %s is not imported, and its name is used by the import of %s", p.Path(), t, imp.path)
+		}
+		w.imports[p.Path()] = ""
+		return p.Name()
+	})
+	if err == nil && strings.Contains(str, "invalid type") {
+		err = fmt.Errorf("type %s can't be resolved", str)
+	}
+	return str, err
+}
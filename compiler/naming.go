@@ -0,0 +1,174 @@
+package compiler
+
+import (
+	"fmt"
+	"go/types"
+	"sort"
+	"strings"
+)
+
+// The identifiers generated for an actor are named after it:
+//
+//	Actor, ActorRef, NewActor...     the interface, the reference and the constructors
+//	FakeActor, NewFakeActor...       the fakes, in the test helpers file
+//	FuzzActorMethod, implFuzz...     the fuzz targets, in the fuzz targets file
+//	CheckActorLinearizable           the linearizability tests, in their file
+//	implMethodRequest                the request of a method, ImplMethodRequest if exported
+//	implMethodResponse               the response of a method, ImplMethodResponse if exported
+//	ImplMethodResult                 the result struct of a method with several results
+//	implMethodGrant                  the credits granted to a stream producer
+//	implMethodElement                an element sent to a stream consumer
+//	implStop                         the stop request
+//	implSlot                         the element of the arena
+//
+// The names of the messages of the methods always end in Request, Response,
+// Result, Grant or Element, and the ones of the stop request and the slot don't, so an
+// actor's identifiers can't collide among them. They can still collide with
+// the ones of other actors, as the ones of the method bGet of an actor a
+// and the method get of an actor aB, or with identifiers declared in the
+// package, and checkNames reports it. It also reports the methods of the
+// actors named like the methods that the references have for all the actors
+
+// generatedName is an identifier generated for an actor
+type generatedName struct {
+	name  string
+	owner string
+	// fakes is true for the identifiers of the test helpers, fuzz targets
+	// and linearizability tests files, which are optional, so they aren't
+	// checked against the package
+	fakes bool
+}
+
+// generatedNames returns the package level identifiers generated for an
+// actor
+func generatedNames(a *Actor) []generatedName {
+	owner := "actor " + a.Name
+	names := []generatedName{{name: a.Name, owner: owner}, {name: a.Ref(), owner: owner}, {name: a.StopRequest(), owner: owner + " stop request"}}
+	if len(a.Constructors) == 0 {
+		names = append(names, generatedName{name: actorInterface.New + a.Name, owner: owner})
+	}
+	for _, ctor := range a.Constructors {
+		names = append(names, generatedName{name: ctor.Constructor(), owner: owner})
+	}
+	if a.Arena > 0 {
+		names = append(names, generatedName{name: a.Slot(), owner: owner + " arena"})
+	}
+	for _, m := range a.Methods {
+		owner := "method " + a.Name + "." + m.Name
+		names = append(names, generatedName{name: m.Request(), owner: owner}, generatedName{name: m.Response(), owner: owner})
+		if m.MultiResult() {
+			names = append(names, generatedName{name: m.Result(), owner: owner})
+		}
+		if m.Endpoint != "" {
+			names = append(names, generatedName{name: m.EndpointMessage(), owner: owner + " stream " + strings.ToLower(m.Endpoint)})
+		}
+	}
+	if a.Enabled(FeatureFakes) {
+		for _, fake := range []string{"Fake" + a.Name, actorInterface.New + "Fake" + a.Name, a.Name + "Fakes", actorInterface.New + a.Name + "WithFakes"} {
+			names = append(names, generatedName{name: fake, owner: owner + " fakes", fakes: true})
+		}
+	}
+	if a.Enabled(FeatureFuzz) {
+		names = append(names, generatedName{name: a.FuzzHooks(), owner: owner + " fuzz targets", fakes: true}, generatedName{name: "start" + a.Name + "Fuzz", owner: owner + " fuzz targets", fakes: true})
+		for _, m := range a.Methods {
+			if m.Fuzzable() {
+				names = append(names, generatedName{name: a.FuzzTarget(m), owner: "method " + a.Name + "." + m.Name + " fuzz target", fakes: true})
+			}
+		}
+	}
+	if a.Enabled(FeatureLinCheck) {
+		names = append(names, generatedName{name: a.LinCheck(), owner: owner + " linearizability tests", fakes: true})
+	}
+	return names
+}
+
+// refMethods are the methods of the references that don't call an actor
+// method
+var refMethods = []string{"From", "Methods", "Stopped", "ID", "Equals"}
+
+// checkNames checks that the identifiers generated for the actors don't
+// collide with each other or, if the scope of the package is known, with
+// the identifiers declared in it. It also checks that the methods of the
+// references don't collide with refMethods, with the context and timeout
+// variants of the synchronous methods or with the stream endpoints, and that the fields
+// of the exported messages of each method have different names. All the
+// collisions are reported, in the order of the actors and their methods
+func checkNames(actors map[string]*Actor, scope *types.Scope) error {
+	var impls []string
+	for impl := range actors {
+		impls = append(impls, impl)
+	}
+	sort.Strings(impls)
+
+	var collisions []string
+	owners := make(map[string]string)
+	for _, impl := range impls {
+		a := actors[impl]
+		for _, gn := range generatedNames(a) {
+			if owner, ok := owners[gn.name]; ok && owner != gn.owner {
+				collisions = append(collisions, fmt.Sprintf("%s of %s collides with the one of %s", gn.name, gn.owner, owner))
+				continue
+			}
+			owners[gn.name] = gn.owner
+			if scope != nil && !gn.fakes && scope.Lookup(gn.name) != nil {
+				collisions = append(collisions, fmt.Sprintf("%s of %s collides with an identifier declared in the package", gn.name, gn.owner))
+			}
+		}
+		reserved := append([]string(nil), refMethods...)
+		if a.View != nil {
+			reserved = append(reserved, "View")
+		}
+		if a.HasSeq() {
+			reserved = append(reserved, "WaitFor")
+		}
+		for _, m := range a.Methods {
+			for _, name := range reserved {
+				if m.Name == name {
+					collisions = append(collisions, fmt.Sprintf("method %s.%s collides with the method %s of the reference", a.Name, m.Name, name))
+				}
+			}
+		}
+		for _, m := range a.Methods {
+			for _, other := range a.Methods {
+				if !m.Async && (other.Name == m.Name+"Context" || other.Name == m.Name+"Timeout") {
+					collisions = append(collisions, fmt.Sprintf("method %s.%s collides with the %s variant of %s.%s", a.Name, other.Name, strings.ToLower(strings.TrimPrefix(other.Name, m.Name)), a.Name, m.Name))
+				}
+				if m.Endpoint != "" && other.Name == m.Name+m.Endpoint {
+					collisions = append(collisions, fmt.Sprintf("method %s.%s collides with the stream %s of %s.%s", a.Name, other.Name, strings.ToLower(m.Endpoint), a.Name, m.Name))
+				}
+			}
+		}
+		if !a.ExportMessages {
+			continue
+		}
+		for _, m := range a.Methods {
+			if field := duplicateField(m.Params); field != "" {
+				collisions = append(collisions, fmt.Sprintf("parameters of method %s.%s collide in the exported field %s", a.Name, m.Name, field))
+			}
+			if field := duplicateField(m.ResultFields()); field != "" {
+				collisions = append(collisions, fmt.Sprintf("results of method %s.%s collide in the exported field %s", a.Name, m.Name, field))
+			}
+		}
+	}
+	if len(collisions) == 0 {
+		return nil
+	}
+	ds := Diagnostics{{Severity: SeverityError, Code: CodeCollision, Message: "generated identifiers collide, rename the actors, methods or parameters:"}}
+	for _, c := range collisions {
+		ds = append(ds, Diagnostic{Severity: SeverityNote, Code: CodeCollision, Message: c})
+	}
+	return ds
+}
+
+// duplicateField returns the name of a field shared by two parameters, or
+// an empty string
+func duplicateField(params []Param) string {
+	seen := make(map[string]bool)
+	for _, p := range params {
+		if seen[p.Field()] {
+			return p.Field()
+		}
+		seen[p.Field()] = true
+	}
+	return ""
+}
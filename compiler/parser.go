@@ -3,6 +3,7 @@ package compiler
 import (
 	"fmt"
 	"go/ast"
+	"go/build"
 	"go/importer"
 	"go/parser"
 	"go/token"
@@ -10,8 +11,14 @@ import (
 	"io/ioutil"
 	"log"
 	"os"
+	"path/filepath"
 	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
+	"unicode"
 )
 
 // Actor contains an actor specification extracted from a go source file
@@ -20,7 +27,77 @@ type Actor struct {
 	Impl    string
 	Methods []Method
 	Init    *Method
-	async   map[string]bool
+	// Constructors are the init method and the methods named init plus a
+	// suffix, like initFromConfig, in the order they are declared
+	Constructors []*Method
+	// View is the method that returns the projection of the actor's state
+	// published after each message, if the actor has one
+	View    *Method
+	Version int
+	// Arena is the number of requests in the arena of the actor, or zero if
+	// its requests are allocated
+	Arena int
+	// Batched is true if the actor batches the responses of its synchronous
+	// methods, up to Batch of them, or GOMAXPROCS if Batch is zero
+	Batched bool
+	Batch   int
+	// ExportMessages exports the request and response types of the methods
+	// and their fields
+	ExportMessages bool
+	async          map[string]bool
+	seq            map[string]bool
+	stream         map[string]bool
+	producer       map[string]bool
+	consumer       map[string]bool
+	exclude        map[string]bool
+	promote        map[string]bool
+	weights        map[string]int
+	timeout        map[string]time.Duration
+	blob           map[string]bool
+	convert        map[string]string
+	// mixins are the embedded fields with the promote tag whose types are
+	// declared in other files or packages, and mixed the names of the
+	// methods taken from them
+	mixins []*types.Var
+	mixed  map[string]bool
+	// hooks are the hooks declared by the actor in the input file
+	hooks map[string]bool
+	// features are the optional parts of the generated code enabled for
+	// the actor. tagFeatures is true if the actor lists them, in the
+	// features tag or directive
+	features    map[string]bool
+	tagFeatures bool
+	// pos is the position of the embedded Actor field
+	pos token.Pos
+	// contract are the guarantees of the hot path declared in the hotpath
+	// tag
+	contract map[string]bool
+}
+
+// Optional features of the generated code. The actors have the metrics, the
+// fakes, the fuzz targets and the linearizability tests by default, and the
+// JSON methods with the -json flag of actorc, unless they list their
+// features in the features tag or directive
+const (
+	// FeatureMetrics counts the messages of the actor in its metrics and
+	// samples their allocations
+	FeatureMetrics = "metrics"
+	// FeatureJSON generates the JSON methods of the messages
+	FeatureJSON = "json"
+	// FeatureFakes generates the fake of the actor in the test helpers
+	FeatureFakes = "fakes"
+	// FeatureFuzz generates the fuzz targets of the methods of the actor
+	FeatureFuzz = "fuzz"
+	// FeatureLinCheck generates the linearizability tests of the actor
+	FeatureLinCheck = "lincheck"
+)
+
+// features are the known features
+var features = []string{FeatureMetrics, FeatureJSON, FeatureFakes, FeatureFuzz, FeatureLinCheck}
+
+// Enabled returns true if the feature is generated for the actor
+func (a *Actor) Enabled(feature string) bool {
+	return a.features[feature]
 }
 
 // ExpName is the exported (uppercase) actor name
@@ -38,24 +115,176 @@ func (a *Actor) Async(m string) bool {
 	return a.async[m]
 }
 
-// StopRequest returns the name of the stop request method for an actor
+// Weights returns the weights of the methods in the fair queue of the actor,
+// by exported method name, or nil if the actor receives the messages in order
+func (a *Actor) Weights() map[string]int {
+	if a.weights == nil {
+		return nil
+	}
+	var weights = make(map[string]int)
+	for method, weight := range a.weights {
+		weights[toUpper(method)] = weight
+	}
+	return weights
+}
+
+// HasSeq returns true if any of the actor methods returns a sequence token
+func (a *Actor) HasSeq() bool {
+	for _, m := range a.Methods {
+		if m.Seq {
+			return true
+		}
+	}
+	return false
+}
+
+// HasTimeouts returns true if any of the actor methods has a timeout
+func (a *Actor) HasTimeouts() bool {
+	return len(a.timeout) > 0
+}
+
+// HasStages returns true if any of the actor methods is a stream stage
+func (a *Actor) HasStages() bool {
+	return len(a.stream) > 0
+}
+
+// ReplaysParams returns true if any of the replayable methods of the actor
+// has parameters, which the replay passes from the decoded request
+func (a *Actor) ReplaysParams() bool {
+	for _, m := range a.Methods {
+		if m.Replayable() && len(m.Params) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// HasSync returns true if any of the actor methods is synchronous
+func (a *Actor) HasSync() bool {
+	for _, m := range a.Methods {
+		if !m.Async {
+			return true
+		}
+	}
+	return false
+}
+
+// HasEndpoints returns true if any of the actor methods is the endpoint of
+// a credit-based stream
+func (a *Actor) HasEndpoints() bool {
+	return len(a.producer) > 0 || len(a.consumer) > 0
+}
+
+// HasBlobs returns true if any of the actor methods has blob parameters
+func (a *Actor) HasBlobs() bool {
+	return len(a.blob) > 0
+}
+
+// HasConsumers returns true if any of the actor methods is the consumer of
+// a credit-based stream
+func (a *Actor) HasConsumers() bool {
+	return len(a.consumer) > 0
+}
+
+// MessageName returns the stable name of a message of the actor, used to
+// register it in the message registry
+func (a *Actor) MessageName(pkg, name string) string {
+	return pkg + "." + a.Name + "." + name
+}
+
+// Slot returns the name of the struct with a request of each method, the
+// element of the actor's arena
+func (a *Actor) Slot() string {
+	return a.Impl + "Slot"
+}
+
+// StopRequest returns the name of the stop request of an actor. Unlike the
+// names of the messages of the methods it doesn't end in Request, so they
+// can't collide
 func (a *Actor) StopRequest() string {
-	return a.Impl + "StopRequest"
+	return a.Impl + "Stop"
 }
 
 // Package contains the specification of a Package extracted
 // from a go source file
 type Package struct {
 	Name     string
-	Imports  map[string]bool
+	Imports  map[string]string
 	Actors   []*Actor
 	ActorInt *ActorInterface
+	Warnings []string
+	// Header contains the comment lines written at the top of the generated file
+	Header []string
+	// Diagnostics are the warnings, with their positions and codes
+	Diagnostics Diagnostics
+	// BuildTags is the build constraint of the generated file
+	BuildTags string
+	// JSON generates the JSON methods of the messages
+	JSON bool
+	// fset and fileName resolve the positions of the input file
+	fset     *token.FileSet
+	fileName string
+}
+
+// EnableJSON generates the JSON methods of the messages, that encode and
+// decode their fields without reflection
+func (p *Package) EnableJSON() {
+	p.JSON = true
+	for _, a := range p.Actors {
+		if !a.tagFeatures {
+			a.features[FeatureJSON] = true
+		}
+	}
+	p.addFeatureImports()
+}
+
+// addFeatureImports adds the imports used by the features of the actors
+func (p *Package) addFeatureImports() {
+	for _, a := range p.Actors {
+		if a.Enabled(FeatureJSON) {
+			p.Imports[msgjsonImport] = ""
+		}
+	}
+}
+
+// Duration returns the time.Duration type as written in the generated code,
+// qualified with the name of the time package imported by the input file
+func (p Package) Duration() string {
+	switch alias := p.Imports["time"]; alias {
+	case "":
+		return "time.Duration"
+	case ".":
+		return "Duration"
+	default:
+		return alias + ".Duration"
+	}
 }
 
 // Param contains the specification of a method parameter
 type Param struct {
-	Name string
-	Type string
+	Name  string
+	Type  string
+	Blob  bool
+	field string
+	codec jsonCodec
+}
+
+// Field returns the name of the field that holds the parameter in the
+// request
+func (p Param) Field() string {
+	if p.field != "" {
+		return p.field
+	}
+	return p.Name
+}
+
+// FieldType returns the type of the field that holds the parameter in the
+// request
+func (p Param) FieldType() string {
+	if p.Blob {
+		return "actor.BlobRef"
+	}
+	return p.Type
 }
 
 // Method contains an actor method specification extracted from o go
@@ -66,7 +295,23 @@ type Method struct {
 	Async     bool
 	RetValues []Param
 	Comments  []string
-	actor     string
+	Stage     string
+	// Endpoint is the end of a credit-based stream between actors generated
+	// for the method, ProducerEndpoint or ConsumerEndpoint, if any
+	Endpoint string
+	// Seq is true if the method, asynchronous and without results, returns
+	// the sequence token of the write
+	Seq bool
+	// Timeout is the time the method has to finish, or zero
+	Timeout time.Duration
+	// Conversion is the struct type the parameters can be sent from, if any
+	Conversion *Conversion
+	actor      string
+	promoted   bool
+	exported   bool
+	imports    map[string]string
+	// call is the name of a method of a mixin, as declared
+	call string
 }
 
 func toLower(s string) string {
@@ -85,6 +330,77 @@ func toUpper(s string) string {
 	return string(r)
 }
 
+// Stream stage kinds
+const (
+	SourceStage = "Source"
+	FlowStage   = "Flow"
+	SinkStage   = "Sink"
+)
+
+// Credit-based stream endpoints
+const (
+	ProducerEndpoint = "Producer"
+	ConsumerEndpoint = "Consumer"
+)
+
+// endpoint returns the end of a credit-based stream that can be built from
+// the method, as producer when it's a source or as consumer when it's a sink
+func (m *Method) endpoint(producer bool) (string, error) {
+	stage, _ := m.stage()
+	switch {
+	case producer && stage == SourceStage:
+		return ProducerEndpoint, nil
+	case !producer && stage == SinkStage:
+		return ConsumerEndpoint, nil
+	case producer:
+		return "", fmt.Errorf("method %s can't be a stream producer: it must take no parameters and return (T, bool)", m.Name)
+	}
+	return "", fmt.Errorf("method %s can't be a stream consumer: it must take one value and return nothing", m.Name)
+}
+
+// EndpointMessage generates the name of the message of the stream endpoint
+// of a method: the grant of credits to a producer, or an element for a
+// consumer
+func (m *Method) EndpointMessage() string {
+	if m.Endpoint == ProducerEndpoint {
+		return m.actor + m.Name + "Grant"
+	}
+	return m.actor + m.Name + "Element"
+}
+
+// EndpointElem returns the type of the elements of the stream endpoint of a
+// method
+func (m *Method) EndpointElem() string {
+	if m.Endpoint == ProducerEndpoint {
+		return m.RetValues[0].Type
+	}
+	return m.Params[0].Type
+}
+
+// stage returns the kind of stream stage that can be built from the method,
+// based on its signature
+func (m *Method) stage() (string, error) {
+	switch {
+	case len(m.Params) == 0 && len(m.RetValues) == 2 && m.RetValues[1].Type == "bool" && !m.Async:
+		return SourceStage, nil
+	case len(m.Params) == 1 && len(m.RetValues) == 1 && !m.Async:
+		return FlowStage, nil
+	case len(m.Params) == 1 && len(m.RetVals()) == 0:
+		return SinkStage, nil
+	}
+	return "", fmt.Errorf("method %s can't be used as a stream stage: sources take no parameters and return (T, bool), flows take and return one value and sinks take one value and return nothing", m.Name)
+}
+
+// HasBlobs returns true if any of the parameters of the method is a blob
+func (m *Method) HasBlobs() bool {
+	for _, p := range m.Params {
+		if p.Blob {
+			return true
+		}
+	}
+	return false
+}
+
 // HasResponse returns true if the method returns results, false otherwise
 func (m *Method) HasResponse() bool {
 	if m.Async && len(m.RetVals()) == 0 {
@@ -101,21 +417,137 @@ func (m *Method) RetVals() []Param {
 	return m.RetValues
 }
 
-// LName returns the lower case name of the actor
+// Constructor returns the name of the function that creates the actor with
+// the method, if it's a constructor: New plus the actor name plus the suffix
+// of the method name after init
+func (m *Method) Constructor() string {
+	return actorInterface.New + toUpper(m.actor) + strings.TrimPrefix(m.Name, actorInterface.Init)
+}
+
+// Fails returns true if the method, a constructor, returns an error
+func (m *Method) Fails() bool {
+	return len(m.RetValues) == 1
+}
+
+// Context returns true if the method, a constructor, receives a context as
+// its first parameter. The actor is initialized again with a background
+// context when it's restarted
+func (m *Method) Context() bool {
+	return len(m.Params) > 0 && m.Params[0].Type == "context.Context"
+}
+
+// ReturnsError returns true if the last result of the method is an error,
+// which its Context and Timeout variants return in place of their own
+func (m *Method) ReturnsError() bool {
+	rets := m.RetVals()
+	return len(rets) > 0 && rets[len(rets)-1].Type == "error"
+}
+
+// CallRetVals returns the results of the Context and Timeout variants of
+// the method before their error: its results, without the error if it
+// returns one
+func (m *Method) CallRetVals() []Param {
+	rets := m.RetVals()
+	if m.ReturnsError() {
+		return rets[:len(rets)-1]
+	}
+	return rets
+}
+
+// ContextParam returns the name of the context parameter of the Context
+// variant of the method, ctx unless the method has a parameter named so
+func (m *Method) ContextParam() string {
+	return m.unusedName("ctx", "callCtx")
+}
+
+// TimeoutParam returns the name of the timeout parameter of the Timeout
+// variant of the method, timeout unless the method has a parameter named so
+func (m *Method) TimeoutParam() string {
+	return m.unusedName("timeout", "callTimeout")
+}
+
+// unusedName returns name, or alt if the method has a parameter named name
+func (m *Method) unusedName(name, alt string) string {
+	for _, p := range m.Params {
+		if p.Name == name {
+			return alt
+		}
+	}
+	return name
+}
+
+// Replayable returns true if the request of the method can be sent again
+// when it's decoded from a dead letter log: its parameters are decoded as
+// they were sent. The blob parameters are references to the blob store,
+// which released them, and the contexts aren't encoded
+func (m *Method) Replayable() bool {
+	for _, p := range m.Params {
+		if p.Blob || p.Type == "context.Context" {
+			return false
+		}
+	}
+	return true
+}
+
+// LName returns the lower case name of the actor, or the name of the method
+// of a mixin, as declared, called by the generated code
 func (m *Method) LName() string {
+	if m.call != "" {
+		return m.call
+	}
 	return toLower(m.Name)
 }
 
 // Request generates the name of the request structure for a method
 func (m *Method) Request() string {
+	if m.exported {
+		return toUpper(m.actor) + m.Name + "Request"
+	}
 	return m.actor + m.Name + "Request"
 }
 
 // Response generates the name of the response structure for a method
 func (m *Method) Response() string {
+	if m.exported {
+		return toUpper(m.actor) + m.Name + "Response"
+	}
 	return m.actor + m.Name + "Response"
 }
 
+// ResponseField returns the name of the field that holds the result i in
+// the response: the one of the result struct if the messages are exported
+func (m *Method) ResponseField(i int) string {
+	if m.exported {
+		return m.ResultFields()[i].Name
+	}
+	return fmt.Sprintf("r%d", i)
+}
+
+// MultiResult returns true if the method has more than one return value
+func (m *Method) MultiResult() bool {
+	return len(m.RetVals()) > 1
+}
+
+// Result generates the name of the exported struct that holds the return
+// values of a method
+func (m *Method) Result() string {
+	return toUpper(m.actor) + m.Name + "Result"
+}
+
+// ResultFields returns the fields of the result struct: the return values
+// with exported names
+func (m *Method) ResultFields() []Param {
+	var fields []Param
+	for i, ret := range m.RetVals() {
+		name := fmt.Sprintf("R%d", i)
+		if ret.Name != "" {
+			name = toUpper(ret.Name)
+		}
+		fields = append(fields, Param{Name: name, Type: ret.Type})
+	}
+	return fields
+}
+
 // func parseComment(iter *NodeIter, nd *ast.Comment) error {
 // 	text := nd.Text
 // 	return nil
@@ -140,101 +572,567 @@ var actorInterface = ActorInterface{
 	Ref:   "Ref",
 }
 
+// viewMethod is the name of the method returning the projection of an
+// actor's state
+const viewMethod = "view"
+
+// ignoreDirective excludes a method from generation when it appears in the
+// method's doc comment
+const ignoreDirective = "//actor:ignore"
+
+// featuresDirective lists the features of an actor, like the features tag,
+// when it appears in the doc comment of the actor's struct
+const featuresDirective = "//actor:features="
+
+// hasDirective returns true if the comment group contains the directive
+func hasDirective(doc *ast.CommentGroup, directive string) bool {
+	if doc == nil {
+		return false
+	}
+	for _, c := range doc.List {
+		if strings.TrimSpace(c.Text) == directive {
+			return true
+		}
+	}
+	return false
+}
+
+// directiveValue returns the value of the directive in the comment group,
+// the text following it, and false if it doesn't contain it
+func directiveValue(doc *ast.CommentGroup, directive string) (string, bool) {
+	if doc == nil {
+		return "", false
+	}
+	for _, c := range doc.List {
+		if text := strings.TrimSpace(c.Text); strings.HasPrefix(text, directive) {
+			return strings.TrimPrefix(text, directive), true
+		}
+	}
+	return "", false
+}
+
 // excludeMethods contains a list of methods that will be ignored by the generator
-var excludedMethods = map[string]bool{"init": true, "InCapacity": true}
+var excludedMethods = map[string]bool{
+	"init":            true,
+	"InCapacity":      true,
+	"MaxAttempts":     true,
+	"InlineCalls":     true,
+	"Ready":           true,
+	"Snapshot":        true,
+	"StateVersion":    true,
+	"Restore":         true,
+	"Throttle":        true,
+	"ResponseTimeout": true,
+	"PreStart":        true,
+	"PostStop":        true,
+	"Unhandled":       true,
+	"OnUnhandled":     true,
+	"OnPanic":         true,
+	"view":            true,
+}
 
 // parseStruct parses a struct in the input file and checks if it's an actor declariation.
-// If it is it adds the identified actor to the actors map passed as parameter
-func parseStruct(name string, t *types.Struct, actors map[string]*Actor) {
+// If it is it adds the identified actor to the actors map passed as parameter. The
+// promoted fields whose types are not declared in the input file, as inFile says, are
+// the mixins of the actor
+func parseStruct(name string, t *types.Struct, actors map[string]*Actor, inFile func(token.Pos) bool) error {
 	if t.NumFields() == 0 {
-		return
+		return nil
 	}
 
+	var promote = make(map[string]bool)
+	var mixins []*types.Var
 	for i := 0; i < t.NumFields(); i++ {
 		fld := t.Field(i)
 		if !fld.Embedded() {
 			continue
 		}
+		if reflect.StructTag(t.Tag(i)).Get("promote") == "true" {
+			if named, ok := deref(fld.Type()).(*types.Named); ok && !inFile(named.Obj().Pos()) {
+				mixins = append(mixins, fld)
+			} else {
+				promote[fld.Name()] = true
+			}
+		}
 		if fld.Name() == "Actor" {
-			log.Printf("%s is an actor\n", name)
-			act := &Actor{Name: toUpper(name), Impl: name, async: make(map[string]bool)}
-			actors[name] = act
-
-			tag := t.Tag(i)
-			structTag := reflect.StructTag(tag)
-			if str, ok := structTag.Lookup("async"); ok {
-				var async = strings.Split(str, ",")
-				for _, method := range async {
-					act.async[strings.Trim(method, " \t")] = true
-				}
+			if err := addActor(name, fld.Pos(), reflect.StructTag(t.Tag(i)), promote, actors); err != nil {
+				return err
 			}
 		}
 	}
+	if act, ok := actors[name]; ok {
+		act.mixins = mixins
+	}
+	return nil
 }
 
-// checkImport checks if a type used in a declarion in the input file needs to be imported
-// in which case it adds it to the imports map passed as parameter
-func checkImport(imports map[string]bool, typeName string) {
-	idx := strings.Index(typeName, ".")
-	if idx == -1 {
-		return
+// deref returns the type pointed to by t, if it's a pointer
+func deref(t types.Type) types.Type {
+	if ptr, ok := t.(*types.Pointer); ok {
+		return ptr.Elem()
 	}
-	r := []rune(typeName)
-	var path = string(r[:idx])
+	return t
+}
 
-	if !imports[path] {
-		imports[path] = true
+// addActor adds an actor to the actors map, configured by the tag of the
+// embedded Actor field at pos
+func addActor(name string, pos token.Pos, structTag reflect.StructTag, promote map[string]bool, actors map[string]*Actor) error {
+	log.Printf("%s is an actor\n", name)
+	act := &Actor{Name: toUpper(name), Impl: name, Version: 1, async: make(map[string]bool), seq: make(map[string]bool), stream: make(map[string]bool), producer: make(map[string]bool), consumer: make(map[string]bool), exclude: make(map[string]bool), promote: promote, mixed: make(map[string]bool), hooks: make(map[string]bool), blob: make(map[string]bool), convert: make(map[string]string), pos: pos}
+	actors[name] = act
+
+	act.features = map[string]bool{FeatureMetrics: true, FeatureFakes: true, FeatureFuzz: true, FeatureLinCheck: true}
+	if str, ok := structTag.Lookup("features"); ok {
+		act.features = make(map[string]bool)
+		act.tagFeatures = true
+		if err := parseFeatures(name, str, act.features); err != nil {
+			return errorAt(pos, CodeTag, "%v", err)
+		}
+	}
+	if str, ok := structTag.Lookup("hotpath"); ok {
+		act.contract = make(map[string]bool)
+		for _, item := range strings.Split(str, ",") {
+			g := strings.Trim(item, " \t")
+			known := false
+			for _, k := range guarantees {
+				known = known || k == g
+			}
+			if !known {
+				return errorAt(pos, CodeTag, "actor %s: unknown hot path guarantee %q, expected %s", name, g, strings.Join(guarantees, ", "))
+			}
+			act.contract[g] = true
+		}
+	}
+	parseTagList(structTag, "async", act.async)
+	parseTagList(structTag, "seq", act.seq)
+	parseTagList(structTag, "stream", act.stream)
+	parseTagList(structTag, "producer", act.producer)
+	parseTagList(structTag, "consumer", act.consumer)
+	parseTagList(structTag, "exclude", act.exclude)
+	parseTagList(structTag, "blob", act.blob)
+	for param := range act.blob {
+		if !strings.Contains(param, ".") {
+			return errorAt(pos, CodeTag, "actor %s: invalid blob parameter %q, expected method.param", name, param)
+		}
+	}
+	if str, ok := structTag.Lookup("version"); ok {
+		version, err := strconv.Atoi(str)
+		if err != nil || version < 1 {
+			return errorAt(pos, CodeTag, "actor %s: invalid message version %q", name, str)
+		}
+		act.Version = version
+	}
+	if str, ok := structTag.Lookup("arena"); ok {
+		size, err := strconv.Atoi(str)
+		if err != nil || size < 1 {
+			return errorAt(pos, CodeTag, "actor %s: invalid arena capacity %q", name, str)
+		}
+		act.Arena = size
+	}
+	if str, ok := structTag.Lookup("batch"); ok {
+		act.Batched = true
+		if str != "procs" {
+			size, err := strconv.Atoi(str)
+			if err != nil || size < 1 {
+				return errorAt(pos, CodeTag, "actor %s: invalid response batch %q, expected procs or a number", name, str)
+			}
+			act.Batch = size
+		}
 	}
+	if str, ok := structTag.Lookup("messages"); ok {
+		if str != "exported" {
+			return errorAt(pos, CodeTag, "actor %s: invalid messages visibility %q, expected exported", name, str)
+		}
+		act.ExportMessages = true
+	}
+	if str, ok := structTag.Lookup("convert"); ok {
+		if err := parseConvertTag(name, str, act.convert); err != nil {
+			return errorAt(pos, CodeTag, "%v", err)
+		}
+	}
+	if str, ok := structTag.Lookup("timeout"); ok {
+		act.timeout = make(map[string]time.Duration)
+		for _, item := range strings.Split(str, ",") {
+			kv := strings.SplitN(item, "=", 2)
+			method := strings.Trim(kv[0], " \t")
+			var timeout time.Duration
+			if len(kv) == 2 {
+				timeout, _ = time.ParseDuration(strings.Trim(kv[1], " \t"))
+			}
+			if method == "" || timeout <= 0 {
+				return errorAt(pos, CodeTag, "actor %s: invalid method timeout %q", name, item)
+			}
+			act.timeout[method] = timeout
+		}
+	}
+	if str, ok := structTag.Lookup("weights"); ok {
+		act.weights = make(map[string]int)
+		for _, item := range strings.Split(str, ",") {
+			kv := strings.SplitN(item, "=", 2)
+			method := strings.Trim(kv[0], " \t")
+			weight := 0
+			if len(kv) == 2 {
+				weight, _ = strconv.Atoi(strings.Trim(kv[1], " \t"))
+			}
+			if method == "" || weight < 1 {
+				return errorAt(pos, CodeTag, "actor %s: invalid method weight %q", name, item)
+			}
+			act.weights[method] = weight
+		}
+	}
+	return nil
 }
 
-// type name separates the path from the type name
-func typeName(t types.Type) string {
-	s := t.String()
-	idx := strings.LastIndex(s, "/")
-	if idx == -1 {
-		return s
+// parseFeatureDirectives sets the features of the actors whose structs have
+// the features directive in their doc comment. An actor can't list its
+// features in both the directive and the tag
+func parseFeatureDirectives(f *ast.File, actors map[string]*Actor) error {
+	for _, decl := range f.Decls {
+		gd, ok := decl.(*ast.GenDecl)
+		if !ok || gd.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range gd.Specs {
+			ts := spec.(*ast.TypeSpec)
+			act, ok := actors[ts.Name.Name]
+			if !ok {
+				continue
+			}
+			doc := ts.Doc
+			if doc == nil && len(gd.Specs) == 1 {
+				doc = gd.Doc
+			}
+			str, ok := directiveValue(doc, featuresDirective)
+			if !ok {
+				continue
+			}
+			if act.tagFeatures {
+				return errorAt(ts.Name.Pos(), CodeTag, "actor %s: features listed in both the features tag and the %s directive", ts.Name.Name, featuresDirective)
+			}
+			act.features = make(map[string]bool)
+			act.tagFeatures = true
+			if err := parseFeatures(ts.Name.Name, str, act.features); err != nil {
+				return errorAt(ts.Name.Pos(), CodeTag, "%v", err)
+			}
+		}
 	}
-	r := []rune(s)
-	return string(r[idx+1:])
+	return nil
+}
+
+// parseFeatures adds the comma separated features of the value of the
+// features tag or directive to the set passed as parameter
+func parseFeatures(name, str string, set map[string]bool) error {
+	for _, item := range strings.Split(str, ",") {
+		feature := strings.Trim(item, " \t")
+		if feature == "" {
+			continue
+		}
+		known := false
+		for _, f := range features {
+			known = known || f == feature
+		}
+		if !known {
+			return fmt.Errorf("actor %s: unknown feature %q, expected %s", name, feature, strings.Join(features, ", "))
+		}
+		set[feature] = true
+	}
+	return nil
+}
+
+// parseTagList adds the comma separated method names in the value of
+// the tag key to the set passed as parameter
+func parseTagList(tag reflect.StructTag, key string, set map[string]bool) {
+	if str, ok := tag.Lookup(key); ok {
+		for _, method := range strings.Split(str, ",") {
+			set[strings.Trim(method, " \t")] = true
+		}
+	}
+}
+
+// fileImport is an import declared in the input file
+type fileImport struct {
+	path  string
+	alias string
+	pos   token.Pos
+}
+
+// checkImports checks if a type expression used in a declaration in the input file refers to
+// imported packages, in which case it adds them to the imports map passed as parameter
+func checkImports(imports map[string]string, fileImports map[string]fileImport, expr ast.Expr) {
+	ast.Inspect(expr, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok {
+			if id, ok := sel.X.(*ast.Ident); ok {
+				if imp, ok := fileImports[id.Name]; ok {
+					imports[imp.path] = imp.alias
+				}
+			}
+		}
+		return true
+	})
+}
+
+// importsOf returns the imports of the input file by the name used to refer to them.
+// Without type information the names of the packages are guessed from their paths
+func importsOf(f *ast.File, pkg *types.Package) map[string]fileImport {
+	var names = make(map[string]string)
+	if pkg != nil {
+		for _, imp := range pkg.Imports() {
+			names[imp.Path()] = imp.Name()
+		}
+	} else {
+		for _, spec := range f.Imports {
+			path, _ := strconv.Unquote(spec.Path.Value)
+			names[path] = guessImportName(path)
+		}
+	}
+
+	var result = make(map[string]fileImport)
+	for _, spec := range f.Imports {
+		path, _ := strconv.Unquote(spec.Path.Value)
+		if spec.Name == nil {
+			result[names[path]] = fileImport{path: path, pos: spec.Path.Pos()}
+		} else if spec.Name.Name != "_" && spec.Name.Name != "." {
+			result[spec.Name.Name] = fileImport{path: path, alias: spec.Name.Name, pos: spec.Path.Pos()}
+		}
+	}
+	return result
+}
+
+// generatedIdent matches the type checking errors caused by references to code that
+// will be generated by actorc, which are allowed in the input file
+var generatedIdent = regexp.MustCompile(`^undefined: (\w+)$|has no field or method (Start|Ref|Stop)\)$`)
+
+// actorNames returns the exported names of the structs embedding an Actor, found
+// in the syntax tree, before type checking
+func actorNames(f *ast.File) []string {
+	var names []string
+	ast.Inspect(f, func(n ast.Node) bool {
+		ts, ok := n.(*ast.TypeSpec)
+		if !ok {
+			return true
+		}
+		if st, ok := ts.Type.(*ast.StructType); ok {
+			for _, fld := range st.Fields.List {
+				if sel, ok := fld.Type.(*ast.SelectorExpr); ok && len(fld.Names) == 0 && sel.Sel.Name == "Actor" {
+					names = append(names, toUpper(ts.Name.Name))
+				}
+			}
+		}
+		return false
+	})
+	return names
+}
+
+// isGenerated returns true if the type checking error is caused by a reference to an
+// identifier that will be generated for one of the actors
+func isGenerated(err error, actors []string) bool {
+	terr, ok := err.(types.Error)
+	if !ok {
+		return false
+	}
+	m := generatedIdent.FindStringSubmatch(terr.Msg)
+	if m == nil {
+		return false
+	}
+	if m[2] != "" {
+		return true
+	}
+	for _, name := range actors {
+		if strings.HasPrefix(m[1], name) || strings.HasPrefix(m[1], actorInterface.New+name) {
+			return true
+		}
+	}
+	return false
+}
+
+// typeWriter writes the types of the parameters and results of the actor methods
+// as they should appear in the generated code, and records the imports they need
+type typeWriter struct {
+	src         string
+	offset      token.Pos
+	pkg         *types.Package
+	info        *types.Info
+	imports     map[string]string
+	fileImports map[string]fileImport
+}
+
+// typeOf returns the type expression written in the generated code for expr.
+// With type information the type is written by the type checker, qualified
+// with the names of the imports of the input file, so local aliases and named
+// types keep their declared names. Without it, or if the type can't be
+// resolved, the text of the input file is used
+func (w *typeWriter) typeOf(expr ast.Expr) string {
+	text := w.src[expr.Pos()-w.offset : expr.End()-w.offset]
+	if w.info == nil {
+		checkImports(w.imports, w.fileImports, expr)
+		return text
+	}
+
+	var prefix string
+	if ell, ok := expr.(*ast.Ellipsis); ok {
+		prefix, expr = "...", ell.Elt
+	}
+	tv, ok := w.info.Types[expr]
+	if !ok {
+		checkImports(w.imports, w.fileImports, expr)
+		return text
+	}
+
+	var used []fileImport
+	var unknown bool
+	str := types.TypeString(tv.Type, func(p *types.Package) string {
+		if p == w.pkg {
+			return ""
+		}
+		for name, imp := range w.fileImports {
+			if imp.path == p.Path() {
+				used = append(used, imp)
+				return name
+			}
+		}
+		unknown = true
+		return p.Name()
+	})
+	// types from packages that are not imported by the input file, or that
+	// couldn't be resolved, like the generated ones, are written as in the file
+	if unknown || strings.Contains(str, "invalid type") {
+		checkImports(w.imports, w.fileImports, expr)
+		return text
+	}
+	for _, imp := range used {
+		w.imports[imp.path] = imp.alias
+	}
+	return prefix + str
 }
 
 // parse package parses the input file and obtains all the program types using
-// the go/types conf.Check method
-func parsePackage(src string) (Package, error) {
+// the go/types conf.Check method, with the importer imp of the imported
+// packages. In loose mode, if type checking fails, the
+// actors are found in the syntax tree and warnings are added for what couldn't
+// be verified
+func parsePackage(fileName, src string, loose bool, imp types.Importer) (_ Package, err error) {
 	fset := token.NewFileSet()
-	f, err := parser.ParseFile(fset, "src.go", src, 0)
+	defer func() {
+		if err != nil {
+			err = diagnose(fset, fileName, err)
+		}
+	}()
+	f, err := parser.ParseFile(fset, fileName, src, parser.ParseComments)
 	if err != nil {
 		return Package{}, err
 	}
 
-	conf := types.Config{Importer: importer.Default()}
-	pkg, err := conf.Check("", fset, []*ast.File{f}, nil)
+	// the generated code is built for the same platforms as the input file
+	buildTags, err := buildConstraint(f, fileName)
 	if err != nil {
 		return Package{}, err
 	}
 
-	log.Printf("package name: %s\n", pkg.Name())
+	// the other files of the package declare the types of the mixins
+	input := fset.File(f.Pos())
+	inFile := func(pos token.Pos) bool {
+		return fset.File(pos) == input
+	}
+
+	// the input file can use the code that will be generated
+	var typeErr error
+	var names = actorNames(f)
+	conf := types.Config{
+		Importer: imp,
+		// cgo is not run: the C identifiers are not checked
+		FakeImportC: true,
+		Error: func(err error) {
+			// the other files of the package are only checked for their declarations
+			if terr, ok := err.(types.Error); ok && !inFile(terr.Pos) {
+				return
+			}
+			if typeErr == nil && !isGenerated(err, names) {
+				typeErr = err
+			}
+		},
+	}
+	info := &types.Info{Types: make(map[ast.Expr]types.TypeAndValue)}
+	pkg, _ := conf.Check("", fset, append([]*ast.File{f}, siblings(fset, fileName, f.Name.Name)...), info)
+	if typeErr != nil && !loose {
+		return Package{}, typeErr
+	}
+
 	var actors = map[string]*Actor{}
-	var imports = map[string]bool{"github.com/carevaloc/goactors/actor": true}
+	var imports = map[string]string{"context": "", "github.com/carevaloc/goactors/actor": ""}
 	var result = Package{
-		Name:     pkg.Name(),
-		Imports:  imports,
-		ActorInt: &actorInterface,
+		Name:      f.Name.Name,
+		Imports:   imports,
+		ActorInt:  &actorInterface,
+		BuildTags: buildTags,
+		fset:      fset,
+		fileName:  fileName,
+	}
+	log.Printf("package name: %s\n", result.Name)
+	warn := func(w located) {
+		result.Warnings = append(result.Warnings, w.msg)
+		result.Diagnostics = append(result.Diagnostics, diagnostic(fset, fileName, SeverityWarning, w))
 	}
 
-	scope := pkg.Scope()
-	for _, name := range scope.Names() {
-		obj := scope.Lookup(name)
-		var t = obj.Type()
-		log.Printf("Name: %s, type: %s\n", name, t)
-		switch t := t.Underlying().(type) {
-		case *types.Struct:
-			log.Printf("struct: %s\n", obj.Name())
-			parseStruct(name, t, actors)
+	var fileImports map[string]fileImport
+	var typeInfo = info
+	if typeErr != nil {
+		typeInfo = nil
+		w := located{code: CodeUnverified, msg: fmt.Sprintf("type checking failed, types not verified: %v", typeErr)}
+		if terr, ok := typeErr.(types.Error); ok {
+			w.pos = terr.Pos
+		}
+		warn(w)
+		if err := parseStructsLoose(f, actors); err != nil {
+			return Package{}, err
 		}
+		fileImports = importsOf(f, nil)
+	} else {
+		scope := pkg.Scope()
+		for _, name := range scope.Names() {
+			obj := scope.Lookup(name)
+			var t = obj.Type()
+			if !inFile(obj.Pos()) {
+				continue
+			}
+			log.Printf("Name: %s, type: %s\n", name, t)
+			switch t := t.Underlying().(type) {
+			case *types.Struct:
+				log.Printf("struct: %s\n", obj.Name())
+				if err := parseStruct(name, t, actors, inFile); err != nil {
+					return Package{}, err
+				}
+			}
+		}
+		fileImports = importsOf(f, pkg)
+	}
+
+	if err := parseFeatureDirectives(f, actors); err != nil {
+		return Package{}, err
 	}
 
-	parseMethods(f, src, imports, actors, actorInterface.Init)
+	tw := &typeWriter{src: src, offset: f.Pos(), pkg: pkg, info: typeInfo, imports: imports, fileImports: fileImports}
+	if err := parseMethods(f, src, tw, actors, actorInterface.Init); err != nil {
+		return Package{}, err
+	}
+
+	if typeErr != nil {
+		for _, w := range guessedImports(fileImports, imports) {
+			warn(w)
+		}
+	}
+	for _, w := range tagWarnings(f, actors) {
+		warn(w)
+	}
+	var scope *types.Scope
+	if typeErr == nil {
+		scope = pkg.Scope()
+	}
+	if err := checkNames(actors, scope); err != nil {
+		return Package{}, err
+	}
+	for _, w := range result.Warnings {
+		log.Printf("Warning: %s\n", w)
+	}
 
 	log.Print("Imports: ")
 	for imp := range result.Imports {
@@ -244,16 +1142,111 @@ func parsePackage(src string) (Package, error) {
 
 	for _, actor := range actors {
 		result.Actors = append(result.Actors, actor)
+		if actor.HasStages() || actor.HasEndpoints() {
+			imports["github.com/carevaloc/goactors/actor/stream"] = ""
+		}
+		if actor.HasEndpoints() {
+			imports["reflect"] = ""
+		}
+		if _, ok := imports["time"]; !ok && actor.HasSync() {
+			imports["time"] = ""
+		}
 	}
+	sort.Slice(result.Actors, func(i, j int) bool {
+		return result.Actors[i].Name < result.Actors[j].Name
+	})
+	result.addFeatureImports()
 
 	return result, nil
 }
 
+// tagWarnings returns a warning for each method named in the tags of an
+// actor that is not declared in the input file. Usually a typo that makes
+// the method synchronous, or generates it when it should be excluded
+func tagWarnings(f *ast.File, actors map[string]*Actor) []located {
+	var declared = make(map[string]map[string]bool)
+	for _, decl := range f.Decls {
+		fd, ok := decl.(*ast.FuncDecl)
+		if !ok || fd.Recv == nil {
+			continue
+		}
+		recvType := fd.Recv.List[0].Type
+		if star, ok := recvType.(*ast.StarExpr); ok {
+			recvType = star.X
+		}
+		if id, ok := recvType.(*ast.Ident); ok {
+			if declared[id.Name] == nil {
+				declared[id.Name] = make(map[string]bool)
+			}
+			declared[id.Name][fd.Name.Name] = true
+		}
+	}
+
+	var warnings []located
+	for _, actor := range actors {
+		for _, tag := range []struct {
+			key   string
+			names map[string]bool
+		}{{"async", actor.async}, {"seq", actor.seq}, {"stream", actor.stream}, {"producer", actor.producer}, {"consumer", actor.consumer}, {"exclude", actor.exclude}, {"weights", weighted(actor)}, {"blob", blobMethods(actor)}, {"convert", converted(actor)}, {"timeout", timed(actor)}} {
+			for name := range tag.names {
+				found := declared[actor.Impl][name]
+				for typ := range actor.promote {
+					found = found || declared[typ][name]
+				}
+				found = found || actor.mixed[name]
+				if !found {
+					warnings = append(warnings, located{pos: actor.pos, code: CodeUndeclaredMethod, msg: fmt.Sprintf("actor %s: method %s in tag %s is not declared", actor.Name, name, tag.key)})
+				}
+			}
+		}
+	}
+	sort.Slice(warnings, func(i, j int) bool {
+		return warnings[i].msg < warnings[j].msg
+	})
+	return warnings
+}
+
+// weighted returns the set of methods with a weight
+func weighted(a *Actor) map[string]bool {
+	var set = make(map[string]bool)
+	for method := range a.weights {
+		set[method] = true
+	}
+	return set
+}
+
+// blobMethods returns the set of methods with blob parameters
+func blobMethods(a *Actor) map[string]bool {
+	var set = make(map[string]bool)
+	for param := range a.blob {
+		set[strings.SplitN(param, ".", 2)[0]] = true
+	}
+	return set
+}
+
+// converted returns the set of methods with a conversion
+func converted(a *Actor) map[string]bool {
+	var set = make(map[string]bool)
+	for method := range a.convert {
+		set[method] = true
+	}
+	return set
+}
+
+// timed returns the set of methods with a timeout
+func timed(a *Actor) map[string]bool {
+	var set = make(map[string]bool)
+	for method := range a.timeout {
+		set[method] = true
+	}
+	return set
+}
+
 // readSrc reads the source file and returs a string with the file contents
 func readSrc(fileName string) (string, error) {
 	file, err := os.Open(fileName)
 	if err != nil {
-		log.Fatal(err)
+		return "", fmt.Errorf("Unable to open input file %s", fileName)
 	}
 	defer file.Close()
 
@@ -265,28 +1258,69 @@ func readSrc(fileName string) (string, error) {
 	return string(b), nil
 }
 
+// siblings parses the other files of the package of the input file, in its
+// directory, that are built with it. Test files and generated files are
+// left out, as the code generated for the input file may be stale
+func siblings(fset *token.FileSet, fileName, pkgName string) []*ast.File {
+	dir := filepath.Dir(fileName)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	var files []*ast.File
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") || name == filepath.Base(fileName) {
+			continue
+		}
+		if ok, err := build.Default.MatchFile(dir, name); err != nil || !ok {
+			continue
+		}
+		f, err := parser.ParseFile(fset, filepath.Join(dir, name), nil, parser.ParseComments)
+		if err != nil || f.Name.Name != pkgName || ast.IsGenerated(f) {
+			continue
+		}
+		files = append(files, f)
+	}
+	return files
+}
+
 // ParseFile parses a go source file and creates the data structure
-// that will be passed to the generator to generate the actor code
+// that will be passed to the generator to generate the actor code. The
+// error, if any, is a Diagnostics
 func ParseFile(fileName string) (Package, error) {
 	src, err := readSrc(fileName)
 	if err != nil {
-		return Package{}, err
+		return Package{}, Diagnostics{{Severity: SeverityError, Code: CodeIO, File: fileName, Message: err.Error()}}
 	}
 
-	return parsePackage(src)
+	return parsePackage(fileName, src, false, importer.Default())
 }
 
-func stripFirst(s string) string {
-	r := []rune(s)
-	return string(r[1:])
+// ParseFileLoose parses a go source file like ParseFile, but if type checking
+// fails it finds the actors using only the syntax tree instead of returning an
+// error. Package.Warnings and Package.Diagnostics list what couldn't be
+// verified
+func ParseFileLoose(fileName string) (Package, error) {
+	src, err := readSrc(fileName)
+	if err != nil {
+		return Package{}, Diagnostics{{Severity: SeverityError, Code: CodeIO, File: fileName, Message: err.Error()}}
+	}
+
+	return parsePackage(fileName, src, true, importer.Default())
 }
 
-// parseMethod parses the string containeng the source code read from the source file and
-// visits all the function nodes. If the function is an actor method, the function signature
-// is extracted, stored in a Method struct and added to the corresponding actor
-func parseMethods(f *ast.File, src string, imports map[string]bool, actors map[string]*Actor, init string) {
+// parseMethods parses the string containeng the source code read from the source file and
+// visits all the function nodes. If the function is an actor method, or a method of a struct
+// whose methods are promoted by an actor, the function signature is extracted, stored in a
+// Method struct and added to the corresponding actors
+func parseMethods(f *ast.File, src string, tw *typeWriter, actors map[string]*Actor, init string) error {
+	var err error
 	offset := f.Pos()
 	ast.Inspect(f, func(n ast.Node) bool {
+		if err != nil {
+			return false
+		}
 		if fd, ok := n.(*ast.FuncDecl); ok {
 			log.Printf("Function: %s\n", fd.Name)
 
@@ -299,81 +1333,213 @@ func parseMethods(f *ast.File, src string, imports map[string]bool, actors map[s
 			recv := fd.Recv
 			recvType := recv.List[0].Type
 			recvTypeName := src[recvType.Pos()-offset : recvType.End()-offset]
-			actorName := stripFirst(recvTypeName)
+			typeName := strings.TrimPrefix(recvTypeName, "*")
 
 			log.Printf("Receiver type: %s\n", recvTypeName)
-			log.Printf("Actor name: %s\n", actorName)
 
-			actor, ok := actors[actorName]
-			if !ok {
-				log.Printf("%s not found in actors map\n", actorName)
-				return true
+			if actor, ok := actors[typeName]; ok {
+				log.Printf("Actor name: %s\n", typeName)
+				err = parseMethod(fd, tw, actor, init, false)
+				return err == nil
 			}
 
-			log.Println(" parameters:")
+			for _, actor := range actors {
+				if actor.promote[typeName] {
+					log.Printf("Method promoted to actor %s\n", actor.Impl)
+					if err = parseMethod(fd, tw, actor, init, true); err != nil {
+						return false
+					}
+				}
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return err
+	}
 
-			async := actor.Async(fd.Name.Name)
-			method := Method{Name: fd.Name.Name, Params: []Param{}, RetValues: []Param{}, Async: async, actor: actorName}
+	for _, actor := range actors {
+		for _, mixin := range actor.mixins {
+			if err := parseMixin(mixin, tw, actor, init); err != nil {
+				return err
+			}
+		}
+	}
 
-			for _, param := range fd.Type.Params.List {
-				for _, pname := range param.Names {
-					ptype := src[param.Type.Pos()-offset : param.Type.End()-offset]
-					par := Param{Name: pname.Name, Type: ptype}
-					method.Params = append(method.Params, par)
-					checkImport(imports, ptype)
-					log.Printf("  Name: %s, type: %s\n", pname, ptype)
-				}
+	// the methods of an actor shadow the promoted methods with the same name
+	for _, actor := range actors {
+		own := make(map[string]bool)
+		for _, m := range actor.Methods {
+			if !m.promoted {
+				own[m.Name] = true
+			}
+		}
+		var methods []Method
+		for _, m := range actor.Methods {
+			if !m.promoted || !own[m.Name] {
+				methods = append(methods, m)
 			}
+		}
+		actor.Methods = methods
+	}
+	return nil
+}
 
-			if fd.Type.Results != nil {
-				log.Println(" results:")
-				log.Printf("Number of results: %d\n", len(fd.Type.Results.List))
-				var named = false
-				for _, param := range fd.Type.Results.List {
-					ptype := src[param.Type.Pos()-offset : param.Type.End()-offset]
-					if len(param.Names) > 0 {
-						named = true
-						for _, pname := range param.Names {
-							retval := Param{Name: pname.Name, Type: ptype}
-							method.RetValues = append(method.RetValues, retval)
-							checkImport(imports, ptype)
-							log.Printf("  Name: %s, type: %s\n", pname, ptype)
-						}
-					} else {
-						retval := Param{Type: ptype}
-						method.RetValues = append(method.RetValues, retval)
-						checkImport(imports, ptype)
-						log.Printf("  Name: , type: %s\n", ptype)
-					}
-				}
-				if method.Async {
-					if named {
-						method.RetValues = append(method.RetValues, Param{Name: "done", Type: "bool"})
-					} else {
-						method.RetValues = append(method.RetValues, Param{Type: "bool"})
-					}
-				}
+// isConstructor returns true if the method is the init method, or its name
+// is init plus a suffix starting with an upper case letter
+func isConstructor(name, init string) bool {
+	if name == init {
+		return true
+	}
+	suffix := strings.TrimPrefix(name, init)
+	return suffix != name && suffix != "" && unicode.IsUpper([]rune(suffix)[0])
+}
+
+// parseMethod extracts the signature of a method and adds it to the actor
+func parseMethod(fd *ast.FuncDecl, tw *typeWriter, actor *Actor, init string, promoted bool) error {
+	log.Println(" parameters:")
+
+	// the imports used by the method are only added to the package if it's generated
+	pkgImports := tw.imports
+	tw.imports = make(map[string]string)
+	defer func() {
+		tw.imports = pkgImports
+	}()
+
+	async := actor.Async(fd.Name.Name)
+	method := Method{Name: fd.Name.Name, Params: []Param{}, RetValues: []Param{}, Async: async, actor: actor.Impl, promoted: promoted, exported: actor.ExportMessages}
+
+	for _, param := range fd.Type.Params.List {
+		for _, pname := range param.Names {
+			ptype := tw.typeOf(param.Type)
+			par := Param{Name: pname.Name, Type: ptype, Blob: actor.blob[fd.Name.Name+"."+pname.Name], codec: tw.jsonCodecOf(param.Type)}
+			if actor.ExportMessages {
+				par.field = toUpper(pname.Name)
+			}
+			if par.Blob && ptype != "[]byte" {
+				return errorAt(pname.Pos(), CodeSignature, "actor %s: blob parameter %s of method %s is not a []byte", actor.Name, pname.Name, fd.Name.Name)
 			}
+			method.Params = append(method.Params, par)
+			log.Printf("  Name: %s, type: %s\n", pname, ptype)
+		}
+	}
 
-			if fd.Doc != nil {
-				for _, comment := range fd.Doc.List {
-					method.Comments = append(method.Comments, comment.Text)
-					log.Println(comment.Text)
+	if fd.Type.Results != nil {
+		log.Println(" results:")
+		log.Printf("Number of results: %d\n", len(fd.Type.Results.List))
+		var named = false
+		for _, param := range fd.Type.Results.List {
+			ptype := tw.typeOf(param.Type)
+			if len(param.Names) > 0 {
+				named = true
+				for _, pname := range param.Names {
+					retval := Param{Name: pname.Name, Type: ptype, codec: tw.jsonCodecOf(param.Type)}
+					method.RetValues = append(method.RetValues, retval)
+					log.Printf("  Name: %s, type: %s\n", pname, ptype)
 				}
 			} else {
-				log.Printf("Method %s has no comment\n", method.Name)
+				retval := Param{Type: ptype, codec: tw.jsonCodecOf(param.Type)}
+				method.RetValues = append(method.RetValues, retval)
+				log.Printf("  Name: , type: %s\n", ptype)
 			}
-
-			if method.Name == init {
-				actor.Init = &method
+		}
+		if method.Async {
+			if named {
+				method.RetValues = append(method.RetValues, Param{Name: "done", Type: "bool"})
+			} else {
+				method.RetValues = append(method.RetValues, Param{Type: "bool"})
 			}
+		}
+	}
 
-			_, excluded := excludedMethods[method.Name]
-			if !excluded {
-				method.Name = toUpper(method.Name)
-				actor.Methods = append(actor.Methods, method)
-			}
+	if fd.Doc != nil {
+		for _, comment := range fd.Doc.List {
+			method.Comments = append(method.Comments, comment.Text)
+			log.Println(comment.Text)
 		}
-		return true
+	} else {
+		log.Printf("Method %s has no comment\n", method.Name)
+	}
+
+	return addMethod(method, fd.Name.Pos(), hasDirective(fd.Doc, ignoreDirective), tw, actor, init, pkgImports, func(typeName string) (*Conversion, error) {
+		return conversion(fd, tw, method.Params, typeName)
 	})
 }
+
+// addMethod adds a method parsed from the input file or from a mixin to the
+// actor, declared at pos, or ignored with the ignore directive. The imports it
+// uses are added to pkgImports if it's generated, and convert returns its
+// conversion from a struct type
+func addMethod(method Method, pos token.Pos, ignored bool, tw *typeWriter, actor *Actor, init string, pkgImports map[string]string, convert func(typeName string) (*Conversion, error)) error {
+	promoted := method.promoted
+	if excludedMethods[method.Name] && !promoted {
+		actor.hooks[method.Name] = true
+	}
+	constructor := isConstructor(method.Name, init)
+	excluded := constructor || excludedMethods[method.Name] || actor.exclude[method.Name] || ignored
+	if promoted && excluded {
+		return nil
+	}
+
+	method.imports = tw.imports
+	if !excluded || constructor || method.Name == viewMethod {
+		for path, alias := range method.imports {
+			pkgImports[path] = alias
+		}
+	}
+
+	if constructor {
+		if len(method.RetValues) > 1 || len(method.RetValues) == 1 && method.RetValues[0].Type != "error" {
+			return errorAt(pos, CodeSignature, "actor %s: %s must return nothing or an error", actor.Name, method.Name)
+		}
+		actor.Constructors = append(actor.Constructors, &method)
+	}
+	if method.Name == init {
+		actor.Init = &method
+	}
+	if method.Name == viewMethod {
+		if len(method.Params) > 0 || len(method.RetValues) != 1 {
+			return errorAt(pos, CodeSignature, "actor %s: %s must have no parameters and return a single value", actor.Name, method.Name)
+		}
+		actor.View = &method
+	}
+
+	if actor.stream[method.Name] {
+		var err error
+		if method.Stage, err = method.stage(); err != nil {
+			return errorAt(pos, CodeSignature, "%v", err)
+		}
+	}
+
+	if (actor.producer[method.Name] || actor.consumer[method.Name]) && !excluded {
+		if actor.producer[method.Name] && actor.consumer[method.Name] {
+			return errorAt(pos, CodeTag, "actor %s: method %s can't be both a stream producer and a consumer", actor.Name, method.Name)
+		}
+		var err error
+		if method.Endpoint, err = method.endpoint(actor.producer[method.Name]); err != nil {
+			return errorAt(pos, CodeSignature, "%v", err)
+		}
+	}
+
+	if typeName, ok := actor.convert[method.Name]; ok && !excluded {
+		var err error
+		if method.Conversion, err = convert(typeName); err != nil {
+			return errorAt(pos, CodeConversion, "actor %s: %v", actor.Name, err)
+		}
+	}
+
+	method.Timeout = actor.timeout[method.Name]
+
+	if actor.seq[method.Name] && !excluded {
+		if !method.Async || len(method.RetValues) > 0 {
+			return errorAt(pos, CodeSignature, "actor %s: method %s can't return a sequence token: it must be asynchronous and return nothing", actor.Name, method.Name)
+		}
+		method.Seq = true
+	}
+
+	if !excluded {
+		method.Name = toUpper(method.Name)
+		actor.Methods = append(actor.Methods, method)
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package compiler
+
+// Report contains statistics about the code generated for a package. It is
+// meant to be encoded as JSON for build dashboards and audits
+type Report struct {
+	Package  string        `json:"package"`
+	Template string        `json:"template"`
+	Actors   []ActorReport `json:"actors"`
+	Methods  int           `json:"methods"`
+	Sync     int           `json:"sync"`
+	Async    int           `json:"async"`
+	Messages []string      `json:"messages"`
+	Warnings []string      `json:"warnings"`
+}
+
+// ActorReport contains the statistics of an actor
+type ActorReport struct {
+	Name    string         `json:"name"`
+	Methods []MethodReport `json:"methods"`
+	Sync    int            `json:"sync"`
+	Async   int            `json:"async"`
+}
+
+// MethodReport describes a generated actor method
+type MethodReport struct {
+	Name     string   `json:"name"`
+	Async    bool     `json:"async"`
+	Stage    string   `json:"stage,omitempty"`
+	Weight   int      `json:"weight,omitempty"`
+	Messages []string `json:"messages"`
+}
+
+// NewReport creates the generation report of a package
+func NewReport(pkg Package) Report {
+	report := Report{
+		Package:  pkg.Name,
+		Template: TemplateName,
+		Actors:   []ActorReport{},
+		Messages: []string{},
+		Warnings: append([]string{}, pkg.Warnings...),
+	}
+
+	for _, actor := range pkg.Actors {
+		ar := ActorReport{Name: actor.Name, Methods: []MethodReport{}}
+		report.Messages = append(report.Messages, actor.StopRequest())
+		for _, m := range actor.Methods {
+			mr := MethodReport{Name: m.Name, Async: m.Async, Stage: m.Stage, Weight: actor.Weights()[m.Name], Messages: []string{m.Request(), m.Response()}}
+			if m.Async {
+				ar.Async++
+			} else {
+				ar.Sync++
+			}
+			ar.Methods = append(ar.Methods, mr)
+			report.Messages = append(report.Messages, mr.Messages...)
+		}
+		report.Methods += len(ar.Methods)
+		report.Sync += ar.Sync
+		report.Async += ar.Async
+		report.Actors = append(report.Actors, ar)
+	}
+	return report
+}
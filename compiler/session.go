@@ -0,0 +1,84 @@
+package compiler
+
+import (
+	"go/importer"
+	"go/types"
+	"sync"
+)
+
+// Session parses files for long-lived processes, like actorc serve. The
+// type information of the imported packages is loaded once and reused by
+// all the files, and the result of a file is reused while its source
+// doesn't change. A Session can be used by several goroutines, which parse
+// one at a time
+type Session struct {
+	mu       sync.Mutex
+	importer types.Importer
+	files    map[string]parsed
+}
+
+// parsed is the cached result of a file
+type parsed struct {
+	src   string
+	loose bool
+	pkg   Package
+	err   error
+}
+
+// NewSession returns an empty session
+func NewSession() *Session {
+	s := &Session{}
+	s.Reset()
+	return s
+}
+
+// Reset forgets the imported packages and the files parsed, so they are
+// loaded again. It should be called when the imported packages change
+func (s *Session) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.importer = importer.Default()
+	s.files = make(map[string]parsed)
+}
+
+// Parse parses src, the source of the file, like ParseFile or, if loose is
+// true, ParseFileLoose. If src is empty the file is read. The Package
+// returned is a copy, that can be modified without changing the cached one
+func (s *Session) Parse(fileName, src string, loose bool) (Package, error) {
+	if src == "" {
+		var err error
+		if src, err = readSrc(fileName); err != nil {
+			return Package{}, Diagnostics{{Severity: SeverityError, Code: CodeIO, File: fileName, Message: err.Error()}}
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, ok := s.files[fileName]
+	if !ok || p.src != src || p.loose != loose {
+		pkg, err := parsePackage(fileName, src, loose, s.importer)
+		p = parsed{src: src, loose: loose, pkg: pkg, err: err}
+		s.files[fileName] = p
+	}
+	return p.pkg.copy(), p.err
+}
+
+// copy returns a copy of the package whose imports and actors can be
+// modified, as EnableJSON does
+func (p Package) copy() Package {
+	c := p
+	c.Imports = make(map[string]string)
+	for path, alias := range p.Imports {
+		c.Imports[path] = alias
+	}
+	c.Actors = nil
+	for _, a := range p.Actors {
+		ca := *a
+		ca.features = make(map[string]bool)
+		for f, on := range a.features {
+			ca.features[f] = on
+		}
+		c.Actors = append(c.Actors, &ca)
+	}
+	return c
+}
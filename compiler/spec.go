@@ -0,0 +1,106 @@
+package compiler
+
+import (
+	"sort"
+
+	"github.com/carevaloc/goactors/compiler/spec"
+)
+
+// Spec returns the stable view of the package, for external tools
+func (p Package) Spec() spec.Package {
+	return pkgSpec{p}
+}
+
+// ParseSpec parses a go source file and returns the specification of
+// the actors declared in it
+func ParseSpec(fileName string) (spec.Package, error) {
+	pkg, err := ParseFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	return pkg.Spec(), nil
+}
+
+type pkgSpec struct {
+	p Package
+}
+
+func (s pkgSpec) Name() string {
+	return s.p.Name
+}
+
+func (s pkgSpec) Imports() []string {
+	var imports []string
+	for imp := range s.p.Imports {
+		imports = append(imports, imp)
+	}
+	sort.Strings(imports)
+	return imports
+}
+
+func (s pkgSpec) Actors() []spec.Actor {
+	var actors []spec.Actor
+	for _, a := range s.p.Actors {
+		actors = append(actors, actorSpec{a})
+	}
+	return actors
+}
+
+type actorSpec struct {
+	a *Actor
+}
+
+func (s actorSpec) Name() string {
+	return s.a.Name
+}
+
+func (s actorSpec) Impl() string {
+	return s.a.Impl
+}
+
+func (s actorSpec) Methods() []spec.Method {
+	var methods []spec.Method
+	for i := range s.a.Methods {
+		methods = append(methods, methodSpec{&s.a.Methods[i]})
+	}
+	return methods
+}
+
+func (s actorSpec) Init() spec.Method {
+	if s.a.Init == nil {
+		return nil
+	}
+	return methodSpec{s.a.Init}
+}
+
+type methodSpec struct {
+	m *Method
+}
+
+func (s methodSpec) Name() string {
+	return s.m.Name
+}
+
+func (s methodSpec) Params() []spec.Param {
+	return specParams(s.m.Params)
+}
+
+func (s methodSpec) Results() []spec.Param {
+	return specParams(s.m.RetVals())
+}
+
+func (s methodSpec) Async() bool {
+	return s.m.Async
+}
+
+func (s methodSpec) Comments() []string {
+	return append([]string(nil), s.m.Comments...)
+}
+
+func specParams(params []Param) []spec.Param {
+	var result []spec.Param
+	for _, p := range params {
+		result = append(result, spec.Param{Name: p.Name, Type: p.Type})
+	}
+	return result
+}
@@ -0,0 +1,70 @@
+// Package spec is the stable interface to the actor specifications that
+// actorc extracts from Go source files. Tools that consume the parse results
+// should use it instead of the compiler types, which change as the code
+// generator evolves.
+package spec
+
+// Version is the version of this interface. It will only change when the
+// interface changes in an incompatible way
+const Version = 1
+
+// Package contains the actors declared in a source file
+type Package interface {
+	// Name returns the name of the package
+	Name() string
+
+	// Imports returns the sorted import paths needed by the generated code
+	Imports() []string
+
+	// Actors returns the actors declared in the file, sorted by name
+	Actors() []Actor
+}
+
+// Actor is an actor declaration: a struct that embeds actor.Actor
+type Actor interface {
+	// Name returns the exported name of the actor, used by the generated
+	// interface. It is never empty
+	Name() string
+
+	// Impl returns the name of the struct that implements the actor
+	Impl() string
+
+	// Methods returns the methods exposed by the actor reference, in
+	// source order. It doesn't include init and the runtime hooks
+	Methods() []Method
+
+	// Init returns the init method, or nil if the actor doesn't have one
+	Init() Method
+}
+
+// Method is an actor method
+type Method interface {
+	// Name returns the exported name of the method, used by the actor
+	// reference. For init it is the name of the method
+	Name() string
+
+	// Params returns the method parameters
+	Params() []Param
+
+	// Results returns the values returned by the method. For asynchronous
+	// methods it doesn't include the flag returned by the generated code
+	// to signal that the method has finished
+	Results() []Param
+
+	// Async returns true if the method is asynchronous
+	Async() bool
+
+	// Comments returns the lines of the method's doc comment, including the
+	// comment markers
+	Comments() []string
+}
+
+// Param is a parameter or a result of a method. Name is empty for unnamed
+// results. Type is the type as the type checker writes it, qualified with
+// the names the input file gives to its imports, as in the generated code.
+// It can differ from the source file, as in the spacing of struct and
+// interface types
+type Param struct {
+	Name string
+	Type string
+}
@@ -0,0 +1,20 @@
+package archive
+
+import "github.com/carevaloc/goactors/actor"
+
+type archive struct {
+	actor.Actor `blob:"store.content"`
+	files       map[string]int
+}
+
+func (a *archive) store(name string, content []byte) int {
+	if a.files == nil {
+		a.files = make(map[string]int)
+	}
+	a.files[name] = len(content)
+	return len(content)
+}
+
+func (a *archive) size(name string) int {
+	return a.files[name]
+}
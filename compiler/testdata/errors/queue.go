@@ -0,0 +1,40 @@
+package queue
+
+import (
+	"errors"
+
+	"github.com/carevaloc/goactors/actor"
+)
+
+var errEmpty = errors.New("empty queue")
+
+type queue struct {
+	actor.Actor
+	items []int
+}
+
+func (q *queue) push(v int) {
+	q.items = append(q.items, v)
+}
+
+func (q *queue) peek() (int, error) {
+	if len(q.items) == 0 {
+		return 0, errEmpty
+	}
+	return q.items[0], nil
+}
+
+func (q *queue) check() error {
+	if len(q.items) == 0 {
+		return errEmpty
+	}
+	return nil
+}
+
+func (q *queue) pop() (v int, ok bool) {
+	if len(q.items) == 0 {
+		return 0, false
+	}
+	v, q.items = q.items[0], q.items[1:]
+	return v, true
+}
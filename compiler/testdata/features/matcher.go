@@ -0,0 +1,25 @@
+package matcher
+
+import "github.com/carevaloc/goactors/actor"
+
+// matcher is on the hot path: it has no metrics nor test helpers
+//
+//actor:features=json
+type matcher struct {
+	actor.Actor
+	bids []int
+}
+
+func (m *matcher) bid(price int) {
+	m.bids = append(m.bids, price)
+}
+
+func (m *matcher) best() int {
+	best := 0
+	for _, b := range m.bids {
+		if b > best {
+			best = b
+		}
+	}
+	return best
+}
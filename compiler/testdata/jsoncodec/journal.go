@@ -0,0 +1,47 @@
+package journal
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/carevaloc/goactors/actor"
+)
+
+type level int
+
+func (l level) MarshalText() ([]byte, error) {
+	switch l {
+	case 0:
+		return []byte("info"), nil
+	case 1:
+		return []byte("error"), nil
+	}
+	return nil, errors.New("unknown level")
+}
+
+func (l *level) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "info":
+		*l = 0
+	case "error":
+		*l = 1
+	default:
+		return errors.New("unknown level")
+	}
+	return nil
+}
+
+type journal struct {
+	actor.Actor `features:"json"`
+	entries     []json.RawMessage
+}
+
+func (j *journal) write(lvl level, at time.Time, seq int32, entry json.RawMessage) int {
+	j.entries = append(j.entries, entry)
+	return len(j.entries)
+}
+
+func (j *journal) read(i int) json.RawMessage {
+	return j.entries[i]
+}
@@ -0,0 +1,18 @@
+package replay
+
+import "github.com/carevaloc/goactors/actor"
+
+// counter has only methods without parameters, so its replay doesn't use
+// the decoded requests
+type counter struct {
+	actor.Actor `features:"json"`
+	n           int
+}
+
+func (c *counter) incr() {
+	c.n++
+}
+
+func (c *counter) value() int {
+	return c.n
+}
@@ -0,0 +1,17 @@
+package index
+
+import "github.com/carevaloc/goactors/actor"
+
+type index struct {
+	actor.Actor `timeout:"query=2s,reindex=1m" async:"reindex"`
+	docs        map[string]string
+}
+
+func (i *index) query(key string) (string, bool) {
+	doc, ok := i.docs[key]
+	return doc, ok
+}
+
+func (i *index) reindex() int {
+	return len(i.docs)
+}